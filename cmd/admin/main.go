@@ -0,0 +1,11 @@
+package main
+
+import (
+	"os"
+	"user-auth-go/internal/admin"
+)
+
+// admin runs operational tasks against the configured database, see `admin help`
+func main() {
+	os.Exit(admin.New().Run(os.Args[1:]))
+}
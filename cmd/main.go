@@ -1,49 +1,199 @@
 package main
 
 import (
+	"context"
 	"fmt"
-	"log"
+	"io"
+	"log/slog"
 	"net/http"
 	"os"
+	"os/signal"
+	"syscall"
+	"time"
+	"user-auth-go/internal/admin"
 	"user-auth-go/internal/api"
 	"user-auth-go/internal/config"
+	"user-auth-go/internal/health"
+	"user-auth-go/internal/metrics"
+	"user-auth-go/internal/middleware"
+	"user-auth-go/internal/outbox"
+	"user-auth-go/internal/storage/sqlstore"
+	"user-auth-go/internal/tracing"
+	"user-auth-go/internal/webhooks"
 	"user-auth-go/web/handlers"
 )
 
 func main() {
-	// initialize config
-	config.Init()
+	// `main migrate up|down|status` manages the schema and exits, same as `admin migrate`
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		os.Exit(admin.New().Run(os.Args[1:]))
+	}
+
+	// initialize config, flags override the config file and environment, see `admin config print`
+	config.Init(os.Args[1:]...)
+
+	// traces go nowhere unless TRACING_EXPORTER is stdout or otlp
+	shutdownTracing, err := tracing.Setup(context.Background(), tracing.Options{
+		Exporter:    config.Current.Tracing.Exporter,
+		Endpoint:    config.Current.Tracing.Endpoint,
+		ServiceName: config.Current.Tracing.ServiceName,
+		SampleRatio: config.Current.Tracing.SampleRatio,
+	})
+	if err != nil {
+		slog.Error("Failed to set up tracing", "error", err)
+		os.Exit(1)
+	}
+
+	dialect := sqlstore.Dialect(config.DBDriver)
+
+	if config.DBAutoMigrate {
+		migrateOnStartup(dialect)
+	}
+
 	handlers.Init()
 
+	store := sqlstore.New(config.DB, dialect)
+	mux := http.NewServeMux()
+
 	// static files
 	fs := http.FileServer(http.Dir("web/static"))
-	http.Handle("/static/", http.StripPrefix("/static/", fs))
+	mux.Handle("/static/", http.StripPrefix("/static/", fs))
 
-	// register web pages routes
-	http.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
-		http.Redirect(w, r, "/login", http.StatusSeeOther)
-	})
-	http.HandleFunc("/login", handlers.LoginPage)
-	http.HandleFunc("/signup", handlers.SignupPage)
-	http.HandleFunc("/profile", handlers.ProfilePage)
-	http.HandleFunc("/profile/edit", handlers.ProfileEditPage)
+	// liveness and readiness probes
+	migrator, err := sqlstore.NewMigrator(config.DB, dialect)
+	if err != nil {
+		slog.Error("Failed to load migrations", "error", err)
+		os.Exit(1)
+	}
+	checker := health.New(config.DB, migrator)
+	checker.Routes(mux)
+
+	// register web pages and API routes
+	handlers.NewServer(store).Routes(mux)
+	api.NewServer(store).Routes(mux)
 
-	// register public and protected API routes
-	http.HandleFunc("/api/signup", api.Signup)
-	http.HandleFunc("/api/login", api.Login)
-	http.HandleFunc("/api/auth/google", api.GoogleLogin)
-	http.HandleFunc("/api/auth/google/callback", api.GoogleCallback)
+	// background workers, stopped in this order on shutdown: the outbox dispatcher
+	// queues webhook deliveries, so it stops before the webhook worker
+	sinks := outbox.ConfiguredSinks(store)
+	workers := []*worker{
+		startWorker("secrets", config.WatchSecrets),
+		startWorker("outbox", outbox.NewDispatcher(store.Outbox, sinks...).Run),
+		startWorker("webhooks", webhooks.NewWorker(store.Webhooks).Run),
+	}
 
-	// protected handlers
-	http.HandleFunc("/api/logout", api.AuthGuard(api.Logout))
-	http.HandleFunc("/api/profile", api.AuthGuard(api.Profile))
+	// metrics on their own admin listener, or on the app port behind the token
+	metrics.RegisterDBStats(metrics.Default, config.DB)
+	metrics.RegisterActiveSessions(metrics.Default, store.Sessions.CountActive)
+	metricsHandler := metrics.Handler(metrics.Default, config.Current.Metrics.Token)
 
-	port := os.Getenv("PORT")
-	if port == "" {
-		port = "8080"
+	var metricsServer *http.Server
+	if addr := config.Current.Metrics.Addr; addr != "" {
+		metricsServer = serveMetrics(addr, metricsHandler)
+	} else if config.Current.Metrics.Token != "" {
+		mux.Handle("/metrics", metricsHandler)
 	}
 
+	port := config.Current.Server.Port
+
+	// global middlewares, applied to web pages and API alike
+	// Route wraps the mux directly so access logs know the matched pattern
+	handler := middleware.Chain(middleware.Route(mux),
+		tracing.Middleware(),
+		middleware.RequestID(),
+		middleware.AccessLog(slog.Default()),
+		middleware.Metrics(),
+		middleware.SecurityHeaders(config.SecurityHeaders),
+		middleware.CORS(config.CORS),
+	)
+
 	// initialize server
-	fmt.Printf("Server running at http://localhost:%s\n", port)
-	log.Fatal(http.ListenAndServe(fmt.Sprintf(":%s", port), nil))
+	server := newServer(fmt.Sprintf(":%s", port), handler)
+
+	reloader, err := configureTLS(server)
+	if err != nil {
+		slog.Error("Failed to set up TLS", "error", err)
+		os.Exit(1)
+	}
+
+	serveErr := make(chan error, 1)
+
+	if reloader != nil {
+		workers = append(workers, startWorker("certificates", reloader.Run))
+
+		go func() {
+			slog.Info("Server running", "url", fmt.Sprintf("https://localhost:%s", port), "client_auth", config.Current.TLS.ClientAuth)
+			serveErr <- server.ListenAndServeTLS("", "")
+		}()
+	} else {
+		go func() {
+			slog.Info("Server running", "url", fmt.Sprintf("http://localhost:%s", port))
+			serveErr <- server.ListenAndServe()
+		}()
+	}
+
+	signals, stopSignals := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+
+	select {
+	case err := <-serveErr:
+		slog.Error("Server stopped", "error", err)
+		os.Exit(1)
+	case <-signals.Done():
+	}
+	// a second signal kills the process right away
+	stopSignals()
+
+	// fail readiness and keep serving for the drain delay, so load balancers stop
+	// sending requests before the listener closes
+	delay := seconds(config.Current.Server.DrainDelay)
+	slog.Info("Draining before shutdown", "delay", delay.String())
+	checker.Drain()
+	time.Sleep(delay)
+
+	ctx, cancel := context.WithTimeout(context.Background(), seconds(config.Current.Server.ShutdownTimeout))
+	defer cancel()
+
+	shutdownServer(ctx, "app", server)
+	if metricsServer != nil {
+		shutdownServer(ctx, "metrics", metricsServer)
+	}
+
+	for _, w := range workers {
+		w.stop(ctx)
+	}
+
+	// the dispatcher is stopped, flush and close sinks holding a connection like NATS
+	for _, sink := range sinks {
+		if closer, ok := sink.(io.Closer); ok {
+			if err := closer.Close(); err != nil {
+				slog.Error("Failed to close outbox sink", "sink", sink.Name(), "error", err)
+			}
+		}
+	}
+
+	if err := shutdownTracing(ctx); err != nil {
+		slog.Error("Failed to flush traces", "error", err)
+	}
+
+	if err := config.DB.Close(); err != nil {
+		slog.Error("Failed to close database", "error", err)
+	}
+
+	slog.Info("Server stopped")
+}
+
+// handle serve only `/metrics` on the admin listener
+func serveMetrics(addr string, handler http.Handler) *http.Server {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", handler)
+	server := newServer(addr, mux)
+
+	go func() {
+		slog.Info("Metrics listening", "addr", addr)
+		if err := server.ListenAndServe(); err != http.ErrServerClosed {
+			slog.Error("Metrics listener stopped", "error", err)
+			os.Exit(1)
+		}
+	}()
+
+	return server
 }
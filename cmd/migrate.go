@@ -0,0 +1,28 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"user-auth-go/internal/config"
+	"user-auth-go/internal/storage/sqlstore"
+)
+
+// handle apply pending migrations before serving, DB_AUTO_MIGRATE turns it on
+func migrateOnStartup(dialect sqlstore.Dialect) {
+	migrator, err := sqlstore.NewMigrator(config.DB, dialect)
+	if err != nil {
+		slog.Error("Failed to load migrations", "error", err)
+		os.Exit(1)
+	}
+
+	applied, err := migrator.Up(context.Background())
+	if err != nil {
+		slog.Error("Failed to migrate", "error", err)
+		os.Exit(1)
+	}
+
+	for _, migration := range applied {
+		slog.Info("Applied migration", "version", migration.Version, "name", migration.Name)
+	}
+}
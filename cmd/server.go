@@ -0,0 +1,96 @@
+package main
+
+import (
+	"context"
+	"crypto/x509"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+	"user-auth-go/internal/certs"
+	"user-auth-go/internal/config"
+)
+
+// handle http.Server with the configured timeouts, so slow clients cannot hold
+// connections open by trickling headers or bodies
+func newServer(addr string, handler http.Handler) *http.Server {
+	cfg := config.Current.Server
+
+	return &http.Server{
+		Addr:              addr,
+		Handler:           handler,
+		ReadHeaderTimeout: seconds(cfg.ReadHeaderTimeout),
+		ReadTimeout:       seconds(cfg.ReadTimeout),
+		WriteTimeout:      seconds(cfg.WriteTimeout),
+		IdleTimeout:       seconds(cfg.IdleTimeout),
+		MaxHeaderBytes:    cfg.MaxHeaderBytes,
+		ErrorLog:          slog.NewLogLogger(slog.Default().Handler(), slog.LevelWarn),
+	}
+}
+
+func seconds(n int) time.Duration {
+	return time.Duration(n) * time.Second
+}
+
+// handle serve HTTPS from the configured cert and key, verifying client certificates
+// when mutual TLS is on. Returns nil when TLS is not configured
+func configureTLS(server *http.Server) (*certs.Reloader, error) {
+	cfg := config.Current.TLS
+	if cfg.CertFile == "" {
+		return nil, nil
+	}
+
+	reloader, err := certs.NewReloader(cfg.CertFile, cfg.KeyFile)
+	if err != nil {
+		return nil, err
+	}
+
+	var clientCAs *x509.CertPool
+	if cfg.ClientAuth != certs.ClientAuthNone {
+		if clientCAs, err = certs.LoadCertPool(cfg.ClientCAFile); err != nil {
+			return nil, fmt.Errorf("client CA file: %w", err)
+		}
+	}
+
+	server.TLSConfig = certs.ServerConfig(reloader, clientCAs, cfg.ClientAuth)
+	return reloader, nil
+}
+
+// worker is a background loop that runs until its context is cancelled
+type worker struct {
+	name   string
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// handle run fn in its own goroutine with a context stop cancels
+func startWorker(name string, fn func(ctx context.Context)) *worker {
+	ctx, cancel := context.WithCancel(context.Background())
+	w := &worker{name: name, cancel: cancel, done: make(chan struct{})}
+
+	go func() {
+		defer close(w.done)
+		fn(ctx)
+	}()
+
+	return w
+}
+
+// handle cancel the worker and wait for its current batch, giving up at the deadline of ctx
+func (w *worker) stop(ctx context.Context) {
+	w.cancel()
+
+	select {
+	case <-w.done:
+	case <-ctx.Done():
+		slog.Warn("Worker did not stop before the shutdown deadline", "worker", w.name)
+	}
+}
+
+// handle finish in-flight requests until the deadline of ctx, then close what is left
+func shutdownServer(ctx context.Context, name string, server *http.Server) {
+	if err := server.Shutdown(ctx); err != nil {
+		slog.Warn("Closing connections still open at the shutdown deadline", "server", name, "error", err)
+		server.Close()
+	}
+}
@@ -0,0 +1,28 @@
+package constants
+
+// audit event types, stored as is in `audit_events.event_type`
+const (
+	EventSignup             = "auth.signup"
+	EventLogin              = "auth.login"
+	EventLogout             = "auth.logout"
+	EventGoogleLogin        = "auth.google_login"
+	EventAuthGuardDenied    = "auth.guard_denied"
+	EventProfileUpdate      = "account.profile_update"
+	EventPasswordChange     = "account.password_change"
+	EventEmailChangeRequest = "account.email_change_request"
+	EventEmailChangeConfirm = "account.email_change_confirm"
+	EventEmailChangeCancel  = "account.email_change_cancel"
+	EventUserCreate         = "admin.user_create"
+	EventUserDelete         = "admin.user_delete"
+	EventUserDisable        = "admin.user_disable"
+	EventUserEnable         = "admin.user_enable"
+	EventUserPromote        = "admin.user_promote"
+	EventPasswordReset      = "admin.password_reset"
+	EventSessionsRevoke     = "admin.sessions_revoke"
+	EventWebhookChange      = "admin.webhook_change"
+)
+
+const (
+	OutcomeSuccess = "success"
+	OutcomeFailure = "failure"
+)
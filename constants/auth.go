@@ -4,3 +4,8 @@ const (
 	AuthProviderLocal  = "local"
 	AuthProviderGoogle = "google"
 )
+
+const (
+	RoleUser  = "user"
+	RoleAdmin = "admin"
+)
@@ -0,0 +1,73 @@
+package constants
+
+// error codes of failed requests, sent as `error.code` by the API and as `/login?error=` by
+// redirects. They never change once released, clients match on them instead of the message
+const (
+	ErrorUnauthorized             = "UNAUTHORIZED"
+	ErrorSessionExpired           = "SESSION_EXPIRED"
+	ErrorNoSession                = "NO_SESSION"
+	ErrorForbidden                = "FORBIDDEN"
+	ErrorAccountDisabled          = "ACCOUNT_DISABLED"
+	ErrorUserNotFound             = "USER_NOT_FOUND"
+	ErrorInvalidCredentials       = "AUTH_INVALID_CREDENTIALS"
+	ErrorProviderMismatch         = "PROVIDER_MISMATCH"
+	ErrorEmailExists              = "EMAIL_EXISTS"
+	ErrorCurrentPasswordRequired  = "CURRENT_PASSWORD_REQUIRED"
+	ErrorCurrentPasswordIncorrect = "CURRENT_PASSWORD_INCORRECT"
+	ErrorPasswordUnchanged        = "PASSWORD_UNCHANGED"
+	ErrorPasswordPolicy           = "PASSWORD_POLICY"
+	ErrorValidation               = "VALIDATION_FAILED"
+	ErrorInvalidRequest           = "INVALID_REQUEST"
+	ErrorBodyTooLarge             = "BODY_TOO_LARGE"
+	ErrorNotFound                 = "NOT_FOUND"
+	ErrorMethodNotAllowed         = "METHOD_NOT_ALLOWED"
+	ErrorWebhookNotFound          = "WEBHOOK_NOT_FOUND"
+	ErrorDeliveryNotFound         = "DELIVERY_NOT_FOUND"
+	ErrorInternal                 = "INTERNAL_ERROR"
+
+	// only sent by the Google login and email link redirects
+	ErrorGoogleDisabled      = "GOOGLE_LOGIN_DISABLED"
+	ErrorOAuthCodeMissing    = "OAUTH_CODE_MISSING"
+	ErrorOAuthExchangeFailed = "OAUTH_EXCHANGE_FAILED"
+	ErrorOAuthUserInfoFailed = "OAUTH_USERINFO_FAILED"
+	ErrorLinkInvalid         = "LINK_INVALID"
+)
+
+// text the web pages show for an error code
+var ErrorMessages = map[string]string{
+	ErrorUnauthorized:             "Please login to continue",
+	ErrorSessionExpired:           "Your session expired, please login again",
+	ErrorNoSession:                "No session found",
+	ErrorForbidden:                "You are not allowed to do that",
+	ErrorAccountDisabled:          "Account is disabled",
+	ErrorUserNotFound:             "User not found",
+	ErrorInvalidCredentials:       "Username or password is incorrect",
+	ErrorProviderMismatch:         "This account signs in with another provider",
+	ErrorEmailExists:              "Email already registered",
+	ErrorCurrentPasswordRequired:  "Current password is required",
+	ErrorCurrentPasswordIncorrect: "Current password is incorrect",
+	ErrorPasswordUnchanged:        "New password must be different from the current password",
+	ErrorPasswordPolicy:           "Password does not meet requirements",
+	ErrorValidation:               "Validation failed",
+	ErrorInvalidRequest:           "Invalid request",
+	ErrorBodyTooLarge:             "Request is too large",
+	ErrorNotFound:                 "Not found",
+	ErrorMethodNotAllowed:         "Method not allowed",
+	ErrorWebhookNotFound:          "Webhook not found",
+	ErrorDeliveryNotFound:         "Delivery not found",
+	ErrorInternal:                 "Something went wrong, please try again",
+	ErrorGoogleDisabled:           "Google login is disabled",
+	ErrorOAuthCodeMissing:         "Google login was cancelled",
+	ErrorOAuthExchangeFailed:      "Google login failed, please try again",
+	ErrorOAuthUserInfoFailed:      "Could not read your Google profile, please try again",
+	ErrorLinkInvalid:              "This link is invalid or expired",
+}
+
+// notices sent as `/login?notice=`
+const (
+	NoticeEmailChangeCancelled = "EMAIL_CHANGE_CANCELLED"
+)
+
+var NoticeMessages = map[string]string{
+	NoticeEmailChangeCancelled: "Email change cancelled and all devices signed out. Please login and change your password",
+}
@@ -0,0 +1,28 @@
+package constants
+
+// user lifecycle events written to the outbox and delivered to webhook subscribers
+const (
+	WebhookUserSignedUp      = "user.signed_up"
+	WebhookUserEmailVerified = "user.email_verified"
+	WebhookUserUpdated       = "user.profile_updated"
+	WebhookUserDeleted       = "user.deleted"
+	WebhookUserDisabled      = "user.disabled"
+	WebhookUserEnabled       = "user.enabled"
+	WebhookSessionCreated    = "session.created"
+	WebhookSessionRevoked    = "session.revoked"
+
+	// subscribes to every event type
+	WebhookAllEvents = "*"
+)
+
+const (
+	DeliveryPending   = "pending"
+	DeliverySucceeded = "succeeded"
+	DeliveryFailed    = "failed"
+)
+
+const (
+	OutboxPending = "pending"
+	OutboxSent    = "sent"
+	OutboxFailed  = "failed"
+)
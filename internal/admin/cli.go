@@ -0,0 +1,266 @@
+package admin
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"user-auth-go/internal/config"
+	"user-auth-go/internal/models"
+	"user-auth-go/internal/storage/sqlstore"
+)
+
+// exit codes, scripts can tell failures apart without parsing the output
+const (
+	ExitOK       = 0
+	ExitFailure  = 1 // database or operation failed
+	ExitUsage    = 2 // unknown command, bad flags or invalid input
+	ExitNotFound = 3 // user does not exist
+	ExitConflict = 4 // user already exists
+)
+
+const usage = `usage: admin [--json] [--config FILE] <command> [flags]
+
+commands:
+  user create --email EMAIL (--password PASSWORD | --password-stdin) [--admin]
+  user set-password --user ID|EMAIL (--password PASSWORD | --password-stdin)
+  user disable --user ID|EMAIL
+  user enable --user ID|EMAIL
+  user promote --user ID|EMAIL [--role admin|user]
+  sessions purge-expired
+  sessions revoke --user ID|EMAIL
+  migrate up | down [n] | status
+  config check
+  config print [--format yaml|env]
+  secrets keygen
+  secrets list [--file FILE]
+  secrets set NAME [--file FILE] [--value VALUE]   value is read from stdin by default
+  secrets unset NAME [--file FILE]
+
+--json prints one JSON object per command, exit codes:
+  0 ok, 1 failure, 2 usage, 3 not found, 4 conflict`
+
+// CLI runs operational commands against the configured database
+type CLI struct {
+	Stdout io.Writer
+	Stderr io.Writer
+	Stdin  io.Reader
+	// Configure loads the config before any command runs, nil keeps the current one
+	Configure func(args []string) error
+	// Open connects the store, tests swap in their own
+	Open func() (*models.Store, error)
+	// Migrator builds the schema migrator of the configured database
+	Migrator func() (*sqlstore.Migrator, error)
+
+	json bool
+	// flags passed to Configure, only --config is accepted on the admin command line
+	configArgs []string
+}
+
+// handle CLI wired to the process streams and the database from config
+func New() *CLI {
+	return &CLI{
+		Stdout:    os.Stdout,
+		Stderr:    os.Stderr,
+		Stdin:     os.Stdin,
+		Configure: config.Setup,
+		Open: func() (*models.Store, error) {
+			if err := connect(); err != nil {
+				return nil, err
+			}
+			return sqlstore.New(config.DB, sqlstore.Dialect(config.DBDriver)), nil
+		},
+		Migrator: func() (*sqlstore.Migrator, error) {
+			if err := connect(); err != nil {
+				return nil, err
+			}
+			return sqlstore.NewMigrator(config.DB, sqlstore.Dialect(config.DBDriver))
+		},
+	}
+}
+
+func connect() error {
+	if config.DB != nil {
+		return nil
+	}
+	return config.Connect()
+}
+
+// handle dispatch command, returns the process exit code
+func (c *CLI) Run(args []string) int {
+	args, err := c.takeGlobalFlags(args)
+	if err != nil {
+		return c.usageError("%s", err)
+	}
+
+	if len(args) == 0 || args[0] == "help" || args[0] == "-h" || args[0] == "--help" {
+		fmt.Fprintln(c.Stderr, usage)
+		if len(args) == 0 {
+			return ExitUsage
+		}
+		return ExitOK
+	}
+
+	ctx := context.Background()
+	group, rest := args[0], args[1:]
+
+	// config print shows invalid settings too and secrets are edited before the config
+	// is complete, both read what they need themselves
+	standalone := group == "secrets" || (group == "config" && len(rest) > 0 && rest[0] == "print")
+
+	if c.Configure != nil && !standalone {
+		if err := c.Configure(c.configArgs); err != nil {
+			return c.failure(ExitFailure, "%s", err)
+		}
+	}
+
+	switch group {
+	case "user":
+		return c.runUser(ctx, rest)
+	case "sessions":
+		return c.runSessions(ctx, rest)
+	case "migrate":
+		return c.runMigrate(ctx, rest)
+	case "config":
+		return c.runConfig(ctx, rest)
+	case "secrets":
+		return c.runSecrets(rest)
+	}
+
+	return c.usageError("unknown command %q", group)
+}
+
+// handle accept --json anywhere so it can follow the subcommand, --config only before it
+func (c *CLI) takeGlobalFlags(args []string) ([]string, error) {
+	var rest []string
+
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+
+		switch {
+		case arg == "--json" || arg == "-json":
+			c.json = true
+		case len(rest) == 0 && (arg == "--config" || arg == "-config"):
+			if i+1 == len(args) {
+				return nil, errors.New("--config needs a file")
+			}
+			i++
+			c.configArgs = append(c.configArgs, "--config", args[i])
+		case len(rest) == 0 && strings.HasPrefix(arg, "--config="):
+			c.configArgs = append(c.configArgs, arg)
+		default:
+			rest = append(rest, arg)
+		}
+	}
+
+	return rest, nil
+}
+
+func (c *CLI) flagSet(name string) *flag.FlagSet {
+	flags := flag.NewFlagSet(name, flag.ContinueOnError)
+	flags.SetOutput(io.Discard)
+	return flags
+}
+
+type result struct {
+	OK       bool        `json:"ok"`
+	Message  string      `json:"message,omitempty"`
+	Data     interface{} `json:"data,omitempty"`
+	Error    string      `json:"error,omitempty"`
+	ExitCode int         `json:"exit_code"`
+}
+
+// handle print success, data is only part of the JSON output
+func (c *CLI) success(message string, data interface{}) int {
+	if c.json {
+		c.writeJSON(result{OK: true, Message: message, Data: data, ExitCode: ExitOK})
+		return ExitOK
+	}
+
+	fmt.Fprintln(c.Stdout, message)
+	return ExitOK
+}
+
+func (c *CLI) failure(code int, format string, args ...interface{}) int {
+	message := fmt.Sprintf(format, args...)
+
+	if c.json {
+		c.writeJSON(result{OK: false, Error: message, ExitCode: code})
+		return code
+	}
+
+	fmt.Fprintln(c.Stderr, "error: "+message)
+	return code
+}
+
+func (c *CLI) usageError(format string, args ...interface{}) int {
+	code := c.failure(ExitUsage, format, args...)
+	if !c.json {
+		fmt.Fprintln(c.Stderr, usage)
+	}
+	return code
+}
+
+func (c *CLI) writeJSON(value interface{}) {
+	encoder := json.NewEncoder(c.Stdout)
+	encoder.SetIndent("", "  ")
+	encoder.Encode(value)
+}
+
+// handle open store or report the connection failure
+func (c *CLI) store() (*models.Store, int) {
+	store, err := c.Open()
+	if err != nil {
+		return nil, c.failure(ExitFailure, "failed to connect database: %s", err)
+	}
+	return store, ExitOK
+}
+
+// handle resolve --user by numeric id or email
+func (c *CLI) findUser(ctx context.Context, store *models.Store, identifier string) (*models.User, int) {
+	if identifier == "" {
+		return nil, c.usageError("--user is required")
+	}
+
+	var user *models.User
+	var err error
+
+	if id, convErr := strconv.Atoi(identifier); convErr == nil {
+		user, err = store.Users.GetByID(ctx, id)
+	} else {
+		user, err = store.Users.GetByIdentifier(ctx, strings.TrimSpace(identifier), "")
+	}
+
+	if err != nil {
+		return nil, c.failure(ExitFailure, "failed to get user: %s", err)
+	}
+
+	if user == nil {
+		return nil, c.failure(ExitNotFound, "user %s not found", identifier)
+	}
+
+	return user, ExitOK
+}
+
+// handle audit operator actions, the CLI has no request so it marks itself as user agent
+func recordAudit(ctx context.Context, store *models.Store, event models.AuditEvent) {
+	event.UserAgent = "admin-cli"
+
+	if event.Details == nil {
+		event.Details = map[string]interface{}{}
+	}
+	event.Details["via"] = "cli"
+
+	store.Audit.Record(ctx, &event)
+}
+
+func intPtr(value int) *int {
+	return &value
+}
+
+var errNoPassword = errors.New("--password or --password-stdin is required")
@@ -0,0 +1,111 @@
+package admin
+
+import (
+	"context"
+	"fmt"
+	"user-auth-go/internal/config"
+)
+
+// ConfigCheck is the result of `config check`
+type ConfigCheck struct {
+	Driver            string `json:"driver"`
+	Database          string `json:"database"`
+	PendingMigrations int    `json:"pending_migrations"`
+	AutoMigrate       bool   `json:"auto_migrate"`
+}
+
+func (c *CLI) runConfig(ctx context.Context, args []string) int {
+	if len(args) == 0 {
+		return c.usageError("missing config command")
+	}
+
+	switch args[0] {
+	case "check":
+		return c.configCheck(ctx, args[1:])
+	case "print":
+		return c.configPrint(args[1:])
+	}
+
+	return c.usageError("unknown config command %q", args[0])
+}
+
+// handle `config check`, settings are already validated by Configure, this fails when
+// the database is unreachable or the schema is behind and auto migrate would not fix it
+func (c *CLI) configCheck(ctx context.Context, args []string) int {
+	if len(args) > 0 {
+		return c.usageError("config check takes no arguments")
+	}
+
+	check := ConfigCheck{Driver: config.DBDriver, AutoMigrate: config.DBAutoMigrate}
+
+	migrator, err := c.Migrator()
+	if err != nil {
+		return c.failure(ExitFailure, "database: %s", err)
+	}
+
+	statuses, err := migrator.Status(ctx)
+	if err != nil {
+		return c.failure(ExitFailure, "database unreachable: %s", err)
+	}
+	check.Database = "ok"
+
+	for _, status := range statuses {
+		if status.AppliedAt == nil {
+			check.PendingMigrations++
+		}
+	}
+
+	if check.PendingMigrations > 0 && !check.AutoMigrate {
+		return c.failure(ExitFailure, "%d pending migrations, run `migrate up` or set DB_AUTO_MIGRATE=true", check.PendingMigrations)
+	}
+
+	message := fmt.Sprintf("Configuration ok: driver %s, database reachable, %d pending migrations", check.Driver, check.PendingMigrations)
+	return c.success(message, check)
+}
+
+// handle `config print [--format yaml|env]`, the resolved config with secrets redacted.
+// An invalid config is still printed so the problems can be tracked down, the exit code reports them
+func (c *CLI) configPrint(args []string) int {
+	flags := c.flagSet("config print")
+	format := flags.String("format", "yaml", "yaml or env")
+
+	if err := flags.Parse(args); err != nil {
+		return c.usageError("%s", err)
+	}
+
+	if *format != "yaml" && *format != "env" {
+		return c.usageError("--format must be yaml or env")
+	}
+
+	cfg, resolveErr := config.Inspect(c.configArgs)
+	if cfg == nil {
+		return c.failure(ExitUsage, "%s", resolveErr)
+	}
+	cfg = cfg.Redacted()
+
+	if c.json {
+		out := result{OK: resolveErr == nil, Data: cfg, ExitCode: ExitOK}
+		if resolveErr != nil {
+			out.Error = resolveErr.Error()
+			out.ExitCode = ExitFailure
+		}
+		c.writeJSON(out)
+		return out.ExitCode
+	}
+
+	if *format == "env" {
+		fmt.Fprint(c.Stdout, cfg.Env())
+	} else {
+		content, err := cfg.YAML()
+		if err != nil {
+			return c.failure(ExitFailure, "failed to render config: %s", err)
+		}
+		fmt.Fprint(c.Stdout, content)
+	}
+
+	if resolveErr != nil {
+		return c.failure(ExitFailure, "%s", resolveErr)
+	}
+
+	return ExitOK
+}
@@ -0,0 +1,108 @@
+package admin
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"text/tabwriter"
+	"time"
+	"user-auth-go/internal/storage/sqlstore"
+)
+
+// MigrationOutput is the JSON shape of a migration, AppliedAt is nil while pending
+type MigrationOutput struct {
+	Version   int64      `json:"version"`
+	Name      string     `json:"name"`
+	AppliedAt *time.Time `json:"applied_at,omitempty"`
+}
+
+func migrationOutputs(migrations []sqlstore.Migration) []MigrationOutput {
+	outputs := []MigrationOutput{}
+	for _, migration := range migrations {
+		outputs = append(outputs, MigrationOutput{Version: migration.Version, Name: migration.Name})
+	}
+	return outputs
+}
+
+// handle `migrate up | down [n] | status`
+func (c *CLI) runMigrate(ctx context.Context, args []string) int {
+	if len(args) == 0 {
+		return c.usageError("missing migrate command")
+	}
+
+	command := args[0]
+	steps := 1
+
+	switch command {
+	case "up", "status":
+		if len(args) > 1 {
+			return c.usageError("migrate %s takes no arguments", command)
+		}
+	case "down":
+		if len(args) > 2 {
+			return c.usageError("migrate down takes at most one argument")
+		}
+		if len(args) == 2 {
+			n, err := strconv.Atoi(args[1])
+			if err != nil || n < 1 {
+				return c.usageError("migrate down expects a positive number of steps")
+			}
+			steps = n
+		}
+	default:
+		return c.usageError("unknown migrate command %q", command)
+	}
+
+	migrator, err := c.Migrator()
+	if err != nil {
+		return c.failure(ExitFailure, "failed to load migrations: %s", err)
+	}
+
+	switch command {
+	case "up":
+		applied, err := migrator.Up(ctx)
+		if err != nil {
+			return c.failure(ExitFailure, "failed to migrate after applying %d migrations: %s", len(applied), err)
+		}
+
+		message := fmt.Sprintf("Applied %d migrations", len(applied))
+		if len(applied) == 0 {
+			message = "Schema is up to date"
+		}
+		return c.success(message, map[string]interface{}{"applied": migrationOutputs(applied)})
+
+	case "down":
+		reverted, err := migrator.Down(ctx, steps)
+		if err != nil {
+			return c.failure(ExitFailure, "failed to revert after reverting %d migrations: %s", len(reverted), err)
+		}
+		return c.success(fmt.Sprintf("Reverted %d migrations", len(reverted)), map[string]interface{}{"reverted": migrationOutputs(reverted)})
+	}
+
+	statuses, err := migrator.Status(ctx)
+	if err != nil {
+		return c.failure(ExitFailure, "failed to read status: %s", err)
+	}
+
+	outputs := []MigrationOutput{}
+	for _, status := range statuses {
+		outputs = append(outputs, MigrationOutput{Version: status.Version, Name: status.Name, AppliedAt: status.AppliedAt})
+	}
+
+	if c.json {
+		return c.success("", map[string]interface{}{"migrations": outputs})
+	}
+
+	w := tabwriter.NewWriter(c.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "VERSION\tNAME\tAPPLIED AT")
+	for _, output := range outputs {
+		appliedAt := "pending"
+		if output.AppliedAt != nil {
+			appliedAt = output.AppliedAt.Format(time.RFC3339)
+		}
+		fmt.Fprintf(w, "%d\t%s\t%s\n", output.Version, output.Name, appliedAt)
+	}
+	w.Flush()
+
+	return ExitOK
+}
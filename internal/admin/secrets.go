@@ -0,0 +1,131 @@
+package admin
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"user-auth-go/internal/config"
+	"user-auth-go/internal/secrets"
+)
+
+// handle `secrets keygen | list | set NAME | unset NAME`, works without a valid
+// config so secrets can be added before the app starts for the first time
+func (c *CLI) runSecrets(args []string) int {
+	if len(args) == 0 {
+		return c.usageError("missing secrets command")
+	}
+
+	if args[0] == "keygen" {
+		if len(args) > 1 {
+			return c.usageError("secrets keygen takes no arguments")
+		}
+
+		key, err := secrets.GenerateKey()
+		if err != nil {
+			return c.failure(ExitFailure, "failed to generate key: %s", err)
+		}
+
+		if c.json {
+			return c.success("Generated master key", map[string]string{"key": key})
+		}
+		fmt.Fprintln(c.Stdout, key)
+		return ExitOK
+	}
+
+	flags := c.flagSet("secrets " + args[0])
+	file := flags.String("file", "", "secrets file, defaults to SECRETS_FILE")
+	value := flags.String("value", "", "secret value, read from stdin when omitted")
+
+	if err := flags.Parse(args[1:]); err != nil {
+		return c.usageError("%s", err)
+	}
+
+	if err := config.LoadEnvFiles(); err != nil {
+		return c.failure(ExitFailure, "%s", err)
+	}
+
+	if *file == "" {
+		*file = os.Getenv("SECRETS_FILE")
+	}
+	if *file == "" {
+		return c.usageError("--file or SECRETS_FILE is required")
+	}
+
+	key, err := config.SecretsMasterKey()
+	if err != nil {
+		return c.failure(ExitFailure, "%s", err)
+	}
+
+	values, err := secrets.ReadFile(*file, key)
+	if err != nil {
+		return c.failure(ExitFailure, "%s", err)
+	}
+
+	switch args[0] {
+	case "list":
+		if flags.NArg() > 0 {
+			return c.usageError("secrets list takes no arguments")
+		}
+
+		names := []string{}
+		for name := range values {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		if c.json {
+			return c.success("", map[string][]string{"names": names})
+		}
+		for _, name := range names {
+			fmt.Fprintln(c.Stdout, name)
+		}
+		return ExitOK
+
+	case "set", "unset":
+		if flags.NArg() != 1 {
+			return c.usageError("secrets %s takes one secret name", args[0])
+		}
+
+		name := flags.Arg(0)
+		if !isSecretName(name) {
+			return c.usageError("%s is not a secret setting, expected one of %s", name, strings.Join(config.SecretNames(), ", "))
+		}
+
+		if args[0] == "unset" {
+			delete(values, name)
+		} else {
+			secret := *value
+			if secret == "" {
+				line, err := bufio.NewReader(c.Stdin).ReadString('\n')
+				if err != nil && line == "" {
+					return c.usageError("failed to read secret from stdin: %s", err)
+				}
+				secret = strings.TrimRight(line, "\r\n")
+			}
+
+			if secret == "" {
+				return c.usageError("secret value is empty")
+			}
+			values[name] = secret
+		}
+
+		if err := secrets.WriteFile(*file, key, values); err != nil {
+			return c.failure(ExitFailure, "failed to write secrets: %s", err)
+		}
+
+		return c.success(fmt.Sprintf("Updated %s in %s, send SIGHUP to reload a running server", name, *file), map[string]string{"name": name})
+	}
+
+	return c.usageError("unknown secrets command %q", args[0])
+}
+
+func isSecretName(name string) bool {
+	for _, secret := range config.SecretNames() {
+		if secret == name {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,79 @@
+package admin
+
+import (
+	"context"
+	"fmt"
+	"user-auth-go/constants"
+	"user-auth-go/internal/models"
+)
+
+func (c *CLI) runSessions(ctx context.Context, args []string) int {
+	if len(args) == 0 {
+		return c.usageError("missing sessions command")
+	}
+
+	switch args[0] {
+	case "purge-expired":
+		return c.sessionsPurgeExpired(ctx, args[1:])
+	case "revoke":
+		return c.sessionsRevoke(ctx, args[1:])
+	}
+
+	return c.usageError("unknown sessions command %q", args[0])
+}
+
+// handle `sessions purge-expired`, safe to run from cron
+func (c *CLI) sessionsPurgeExpired(ctx context.Context, args []string) int {
+	flags := c.flagSet("sessions purge-expired")
+	if err := flags.Parse(args); err != nil {
+		return c.usageError("%s", err)
+	}
+
+	store, code := c.store()
+	if code != ExitOK {
+		return code
+	}
+
+	deleted, err := store.Sessions.DeleteExpired(ctx)
+	if err != nil {
+		return c.failure(ExitFailure, "failed to purge sessions: %s", err)
+	}
+
+	return c.success(fmt.Sprintf("Purged %d expired sessions", deleted), map[string]int64{"deleted": deleted})
+}
+
+// handle `sessions revoke --user`, signs the user out of every device
+func (c *CLI) sessionsRevoke(ctx context.Context, args []string) int {
+	flags := c.flagSet("sessions revoke")
+	identifier := flags.String("user", "", "id or email of the user")
+
+	if err := flags.Parse(args); err != nil {
+		return c.usageError("%s", err)
+	}
+
+	if *identifier == "" {
+		return c.usageError("--user is required")
+	}
+
+	store, code := c.store()
+	if code != ExitOK {
+		return code
+	}
+
+	user, code := c.findUser(ctx, store, *identifier)
+	if code != ExitOK {
+		return code
+	}
+
+	if err := store.Sessions.DeleteForUser(ctx, user.ID); err != nil {
+		return c.failure(ExitFailure, "failed to revoke sessions: %s", err)
+	}
+
+	recordAudit(ctx, store, models.AuditEvent{
+		EventType:    constants.EventSessionsRevoke,
+		Outcome:      constants.OutcomeSuccess,
+		TargetUserID: intPtr(user.ID),
+	})
+
+	return c.success("Revoked sessions of "+user.Email, userOutput(user))
+}
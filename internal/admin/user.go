@@ -0,0 +1,286 @@
+package admin
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"strings"
+	"time"
+	"user-auth-go/constants"
+	"user-auth-go/internal/config"
+	"user-auth-go/internal/models"
+)
+
+// UserOutput is the JSON shape of a user, the password hash is never printed
+type UserOutput struct {
+	ID         int        `json:"id"`
+	Email      string     `json:"email"`
+	Role       string     `json:"role"`
+	Disabled   bool       `json:"disabled"`
+	DisabledAt *time.Time `json:"disabled_at,omitempty"`
+}
+
+func userOutput(user *models.User) UserOutput {
+	return UserOutput{
+		ID:         user.ID,
+		Email:      user.Email,
+		Role:       user.Role,
+		Disabled:   user.IsDisabled(),
+		DisabledAt: user.DisabledAt,
+	}
+}
+
+func (c *CLI) runUser(ctx context.Context, args []string) int {
+	if len(args) == 0 {
+		return c.usageError("missing user command")
+	}
+
+	switch args[0] {
+	case "create":
+		return c.userCreate(ctx, args[1:])
+	case "set-password":
+		return c.userSetPassword(ctx, args[1:])
+	case "disable":
+		return c.userSetDisabled(ctx, args[1:], true)
+	case "enable":
+		return c.userSetDisabled(ctx, args[1:], false)
+	case "promote":
+		return c.userPromote(ctx, args[1:])
+	}
+
+	return c.usageError("unknown user command %q", args[0])
+}
+
+// handle `user create --email --password [--admin]`
+func (c *CLI) userCreate(ctx context.Context, args []string) int {
+	flags := c.flagSet("user create")
+	email := flags.String("email", "", "email of the new user")
+	password := flags.String("password", "", "password of the new user")
+	passwordStdin := flags.Bool("password-stdin", false, "read the password from stdin")
+	admin := flags.Bool("admin", false, "give the user the admin role")
+
+	if err := flags.Parse(args); err != nil {
+		return c.usageError("%s", err)
+	}
+
+	*email = strings.TrimSpace(*email)
+	if *email == "" {
+		return c.usageError("--email is required")
+	}
+
+	plain, code := c.readPassword(*password, *passwordStdin, *email)
+	if code != ExitOK {
+		return code
+	}
+
+	hashed, err := config.PasswordHasher.Hash(plain)
+	if err != nil {
+		return c.failure(ExitFailure, "failed to hash password: %s", err)
+	}
+
+	store, code := c.store()
+	if code != ExitOK {
+		return code
+	}
+
+	user, err := store.Users.Create(ctx, *email, hashed)
+	if errors.Is(err, models.ErrEmailExists) {
+		return c.failure(ExitConflict, "user %s already exists", *email)
+	}
+	if err != nil {
+		return c.failure(ExitFailure, "failed to create user: %s", err)
+	}
+
+	if *admin {
+		if err := store.Users.UpdateRole(ctx, user.ID, constants.RoleAdmin); err != nil {
+			return c.failure(ExitFailure, "user %d created but failed to set admin role: %s", user.ID, err)
+		}
+		user.Role = constants.RoleAdmin
+	}
+
+	recordAudit(ctx, store, models.AuditEvent{
+		EventType:    constants.EventUserCreate,
+		Outcome:      constants.OutcomeSuccess,
+		TargetUserID: intPtr(user.ID),
+		Details:      map[string]interface{}{"role": user.Role},
+	})
+
+	return c.success("Created user "+user.Email, userOutput(user))
+}
+
+// handle `user set-password --user (--password | --password-stdin)`, other sessions are revoked
+func (c *CLI) userSetPassword(ctx context.Context, args []string) int {
+	flags := c.flagSet("user set-password")
+	identifier := flags.String("user", "", "id or email of the user")
+	password := flags.String("password", "", "new password")
+	passwordStdin := flags.Bool("password-stdin", false, "read the new password from stdin")
+
+	if err := flags.Parse(args); err != nil {
+		return c.usageError("%s", err)
+	}
+
+	if *identifier == "" {
+		return c.usageError("--user is required")
+	}
+
+	store, code := c.store()
+	if code != ExitOK {
+		return code
+	}
+
+	user, code := c.findUser(ctx, store, *identifier)
+	if code != ExitOK {
+		return code
+	}
+
+	plain, code := c.readPassword(*password, *passwordStdin, user.Email, user.FullName)
+	if code != ExitOK {
+		return code
+	}
+
+	hashed, err := config.PasswordHasher.Hash(plain)
+	if err != nil {
+		return c.failure(ExitFailure, "failed to hash password: %s", err)
+	}
+
+	if err := store.Users.UpdatePassword(ctx, user.ID, hashed); err != nil {
+		return c.failure(ExitFailure, "failed to update password: %s", err)
+	}
+
+	if err := store.Sessions.DeleteForUser(ctx, user.ID); err != nil {
+		return c.failure(ExitFailure, "password updated but failed to revoke sessions: %s", err)
+	}
+
+	recordAudit(ctx, store, models.AuditEvent{
+		EventType:    constants.EventPasswordReset,
+		Outcome:      constants.OutcomeSuccess,
+		TargetUserID: intPtr(user.ID),
+	})
+
+	return c.success("Password updated for "+user.Email, userOutput(user))
+}
+
+// handle `user disable|enable --user`, disabling also signs the user out everywhere
+func (c *CLI) userSetDisabled(ctx context.Context, args []string, disabled bool) int {
+	name, eventType, verb := "user enable", constants.EventUserEnable, "Enabled"
+	if disabled {
+		name, eventType, verb = "user disable", constants.EventUserDisable, "Disabled"
+	}
+
+	flags := c.flagSet(name)
+	identifier := flags.String("user", "", "id or email of the user")
+
+	if err := flags.Parse(args); err != nil {
+		return c.usageError("%s", err)
+	}
+
+	if *identifier == "" {
+		return c.usageError("--user is required")
+	}
+
+	store, code := c.store()
+	if code != ExitOK {
+		return code
+	}
+
+	user, code := c.findUser(ctx, store, *identifier)
+	if code != ExitOK {
+		return code
+	}
+
+	if err := store.Users.SetDisabled(ctx, user.ID, disabled); err != nil {
+		return c.failure(ExitFailure, "failed to update user: %s", err)
+	}
+
+	if disabled {
+		if err := store.Sessions.DeleteForUser(ctx, user.ID); err != nil {
+			return c.failure(ExitFailure, "user disabled but failed to revoke sessions: %s", err)
+		}
+	}
+
+	recordAudit(ctx, store, models.AuditEvent{
+		EventType:    eventType,
+		Outcome:      constants.OutcomeSuccess,
+		TargetUserID: intPtr(user.ID),
+	})
+
+	user, err := store.Users.GetByID(ctx, user.ID)
+	if err != nil || user == nil {
+		return c.failure(ExitFailure, "failed to reload user: %v", err)
+	}
+
+	return c.success(verb+" user "+user.Email, userOutput(user))
+}
+
+// handle `user promote --user [--role]`
+func (c *CLI) userPromote(ctx context.Context, args []string) int {
+	flags := c.flagSet("user promote")
+	identifier := flags.String("user", "", "id or email of the user")
+	role := flags.String("role", constants.RoleAdmin, "role to give the user")
+
+	if err := flags.Parse(args); err != nil {
+		return c.usageError("%s", err)
+	}
+
+	if *identifier == "" {
+		return c.usageError("--user is required")
+	}
+
+	if *role != constants.RoleAdmin && *role != constants.RoleUser {
+		return c.usageError("--role must be %s or %s", constants.RoleAdmin, constants.RoleUser)
+	}
+
+	store, code := c.store()
+	if code != ExitOK {
+		return code
+	}
+
+	user, code := c.findUser(ctx, store, *identifier)
+	if code != ExitOK {
+		return code
+	}
+
+	previous := user.Role
+
+	if err := store.Users.UpdateRole(ctx, user.ID, *role); err != nil {
+		return c.failure(ExitFailure, "failed to update role: %s", err)
+	}
+	user.Role = *role
+
+	recordAudit(ctx, store, models.AuditEvent{
+		EventType:    constants.EventUserPromote,
+		Outcome:      constants.OutcomeSuccess,
+		TargetUserID: intPtr(user.ID),
+		Details:      map[string]interface{}{"from": previous, "to": *role},
+	})
+
+	return c.success("Set role of "+user.Email+" to "+*role, userOutput(user))
+}
+
+// handle take password from the flag or the first line of stdin and apply the policy
+func (c *CLI) readPassword(flagValue string, fromStdin bool, personal ...string) (string, int) {
+	password := flagValue
+
+	if fromStdin {
+		line, err := bufio.NewReader(c.Stdin).ReadString('\n')
+		if err != nil && line == "" {
+			return "", c.usageError("failed to read password from stdin: %s", err)
+		}
+		password = strings.TrimRight(line, "\r\n")
+	}
+
+	if password == "" {
+		return "", c.usageError("%s", errNoPassword)
+	}
+
+	violations := config.PasswordPolicy.Validate(password, personal...)
+	if len(violations) > 0 {
+		messages := make([]string, len(violations))
+		for i, violation := range violations {
+			messages[i] = violation.Message
+		}
+		return "", c.failure(ExitUsage, "password rejected: %s", strings.Join(messages, "; "))
+	}
+
+	return password, ExitOK
+}
@@ -0,0 +1,46 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+	"user-auth-go/constants"
+	"user-auth-go/internal/models"
+)
+
+// handler delete user `DELETE /api/v1/admin/users/{id}`
+func (s *Server) AdminDeleteUser(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(idParam(r))
+	if err != nil {
+		respondError(w, r, http.StatusBadRequest, constants.ErrorInvalidRequest, "Invalid id")
+		return
+	}
+
+	user, err := s.Users.GetByID(r.Context(), id)
+	if err != nil {
+		logError(r, "Failed to get user", err)
+		respondError(w, r, http.StatusInternalServerError, constants.ErrorInternal, "Failed to get user")
+		return
+	}
+
+	if user == nil {
+		respondError(w, r, http.StatusNotFound, constants.ErrorUserNotFound, "User not found")
+		return
+	}
+
+	if err := s.Users.Delete(r.Context(), user.ID); err != nil {
+		logError(r, "Failed to delete user", err)
+		respondError(w, r, http.StatusInternalServerError, constants.ErrorInternal, "Failed to delete user")
+		return
+	}
+
+	actor := GetUserFromCtx(r)
+	s.recordAudit(r, models.AuditEvent{
+		EventType:    constants.EventUserDelete,
+		Outcome:      constants.OutcomeSuccess,
+		ActorUserID:  intPtr(actor.ID),
+		TargetUserID: intPtr(user.ID),
+		Details:      map[string]interface{}{"email": user.Email},
+	})
+
+	respondSuccess(w, "User deleted", nil)
+}
@@ -0,0 +1,212 @@
+package api
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+	"user-auth-go/constants"
+	"user-auth-go/internal/config"
+	"user-auth-go/internal/metrics"
+	"user-auth-go/internal/models"
+)
+
+type AuditEventResponse struct {
+	ID           int64                  `json:"id"`
+	EventType    string                 `json:"event_type"`
+	Outcome      string                 `json:"outcome"`
+	ActorUserID  *int                   `json:"actor_user_id,omitempty"`
+	TargetUserID *int                   `json:"target_user_id,omitempty"`
+	IP           string                 `json:"ip"`
+	UserAgent    string                 `json:"user_agent"`
+	Details      map[string]interface{} `json:"details,omitempty"`
+	CreatedAt    time.Time              `json:"created_at"`
+}
+
+// handle append audit event with request metadata
+// failing to record must never fail the request itself. Auth events are also counted
+// in auth_events_total here, so handlers feed the metric by auditing their outcome
+func (s *Server) recordAudit(r *http.Request, event models.AuditEvent) {
+	event.IP = clientIP(r)
+	event.UserAgent = r.UserAgent()
+
+	// the column holds 512 characters, cut on a rune so the stored value stays valid UTF-8
+	if utf8.RuneCountInString(event.UserAgent) > 512 {
+		event.UserAgent = string([]rune(event.UserAgent)[:512])
+	}
+
+	countAuthEvent(event)
+
+	if err := s.Audit.Record(r.Context(), &event); err != nil {
+		logError(r, "Failed to record audit event", err, "event_type", event.EventType)
+	}
+}
+
+// auth events counted in auth_events_total, by their metric label
+var authMetricEvents = map[string]string{
+	constants.EventSignup:      "signup",
+	constants.EventLogin:       "login",
+	constants.EventLogout:      "logout",
+	constants.EventGoogleLogin: "google_callback",
+}
+
+// handle count signup, login, logout and Google callback outcomes by provider and reason,
+// an outcome a handler does not audit is not counted either
+func countAuthEvent(event models.AuditEvent) {
+	name, ok := authMetricEvents[event.EventType]
+	if !ok {
+		return
+	}
+
+	provider := constants.AuthProviderLocal
+	if event.EventType == constants.EventGoogleLogin {
+		provider = constants.AuthProviderGoogle
+	}
+	if value, ok := event.Details["provider"].(string); ok {
+		provider = value
+	}
+
+	reason, _ := event.Details["reason"].(string)
+	metrics.AuthEvents.WithLabelValues(name, event.Outcome, provider, reason).Inc()
+}
+
+// handle resolve client IP, proxy headers are only trusted when configured
+func clientIP(r *http.Request) string {
+	if config.TrustProxyHeaders {
+		if forwarded := r.Header.Get("X-Forwarded-For"); forwarded != "" {
+			first, _, _ := strings.Cut(forwarded, ",")
+			return strings.TrimSpace(first)
+		}
+
+		if realIP := r.Header.Get("X-Real-IP"); realIP != "" {
+			return realIP
+		}
+	}
+
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+func intPtr(value int) *int {
+	return &value
+}
+
+func toAuditEventResponses(events []models.AuditEvent) []AuditEventResponse {
+	responses := make([]AuditEventResponse, 0, len(events))
+
+	for _, event := range events {
+		responses = append(responses, AuditEventResponse{
+			ID:           event.ID,
+			EventType:    event.EventType,
+			Outcome:      event.Outcome,
+			ActorUserID:  event.ActorUserID,
+			TargetUserID: event.TargetUserID,
+			IP:           event.IP,
+			UserAgent:    event.UserAgent,
+			Details:      event.Details,
+			CreatedAt:    event.CreatedAt,
+		})
+	}
+
+	return responses
+}
+
+// handler recent security activity of logged user `GET /api/v1/profile/activity`
+func (s *Server) SecurityActivity(w http.ResponseWriter, r *http.Request) {
+	user := GetUserFromCtx(r)
+
+	if user == nil {
+		respondError(w, r, http.StatusUnauthorized, constants.ErrorUnauthorized, "Unauthorized")
+		return
+	}
+
+	events, err := s.Audit.ListForUser(r.Context(), user.ID, 50)
+	if err != nil {
+		logError(r, "Failed to get security activity", err)
+		respondError(w, r, http.StatusInternalServerError, constants.ErrorInternal, "Failed to get security activity")
+		return
+	}
+
+	respondSuccess(w, "Security activity retrieved", toAuditEventResponses(events))
+}
+
+// handler query audit log `GET /api/v1/admin/audit-events`
+// filters: user_id, event_type, outcome, ip, since, until (RFC 3339), limit, offset
+func (s *Server) AdminAuditEvents(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	filter := models.AuditFilter{
+		EventType: query.Get("event_type"),
+		Outcome:   query.Get("outcome"),
+		IP:        query.Get("ip"),
+	}
+
+	var err error
+
+	if value := query.Get("user_id"); value != "" {
+		userID, convErr := strconv.Atoi(value)
+		if convErr != nil {
+			respondError(w, r, http.StatusBadRequest, constants.ErrorInvalidRequest, "Invalid user_id")
+			return
+		}
+		filter.UserID = &userID
+	}
+
+	if filter.Since, err = parseTimeParam(query.Get("since")); err != nil {
+		respondError(w, r, http.StatusBadRequest, constants.ErrorInvalidRequest, "Invalid since, expected RFC 3339 time")
+		return
+	}
+
+	if filter.Until, err = parseTimeParam(query.Get("until")); err != nil {
+		respondError(w, r, http.StatusBadRequest, constants.ErrorInvalidRequest, "Invalid until, expected RFC 3339 time")
+		return
+	}
+
+	if filter.Limit, err = parseIntParam(query.Get("limit"), 100); err != nil || filter.Limit > models.MaxAuditLimit {
+		respondError(w, r, http.StatusBadRequest, constants.ErrorInvalidRequest, fmt.Sprintf("Invalid limit, expected at most %d", models.MaxAuditLimit))
+		return
+	}
+
+	if filter.Offset, err = parseIntParam(query.Get("offset"), 0); err != nil {
+		respondError(w, r, http.StatusBadRequest, constants.ErrorInvalidRequest, "Invalid offset")
+		return
+	}
+
+	events, err := s.Audit.Query(r.Context(), filter)
+	if err != nil {
+		logError(r, "Failed to query audit events", err)
+		respondError(w, r, http.StatusInternalServerError, constants.ErrorInternal, "Failed to query audit events")
+		return
+	}
+
+	respondSuccess(w, "Audit events retrieved", toAuditEventResponses(events))
+}
+
+func parseTimeParam(value string) (*time.Time, error) {
+	if value == "" {
+		return nil, nil
+	}
+
+	parsed, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return nil, err
+	}
+	return &parsed, nil
+}
+
+func parseIntParam(value string, defaultVal int) (int, error) {
+	if value == "" {
+		return defaultVal, nil
+	}
+
+	parsed, err := strconv.Atoi(value)
+	if err != nil || parsed < 0 {
+		return 0, strconv.ErrSyntax
+	}
+	return parsed, nil
+}
@@ -9,18 +9,32 @@ import (
 	"user-auth-go/constants"
 	"user-auth-go/internal/config"
 	"user-auth-go/internal/models"
+	"user-auth-go/internal/tracing"
+
+	"golang.org/x/oauth2"
 )
 
 type LoginRequest struct {
-	Email    string `json:"email"`
-	Password string `json:"password"`
+	Email    string `json:"email" validate:"required"`
+	Password string `json:"password" validate:"required"`
+}
+
+type SignupRequest struct {
+	Email    string `json:"email" validate:"required,email,max=254"`
+	Password string `json:"password" validate:"required"`
 }
 
-type SignupRequest = LoginRequest
+// handle password policy, checked once the email is known so it can be refused as a password
+func (req *SignupRequest) Validate() []FieldError {
+	if req.Password == "" {
+		return nil
+	}
+	return passwordPolicyErrors(req.Password, req.Email)
+}
 
+// AuthResponse carries no token, the session travels in the cookie only
 type AuthResponse struct {
-	Token string       `json:"token"`
-	User  UserResponse `json:"user"`
+	User UserResponse `json:"user"`
 }
 
 type UserResponse struct {
@@ -31,58 +45,61 @@ type UserResponse struct {
 	AuthProvider string `json:"auth_provider"`
 }
 
-func Signup(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodPost {
-		respondError(w, http.StatusMethodNotAllowed, "Method not allowed")
-	}
-
+// handler signup `POST /api/v1/signup`
+func (s *Server) Signup(w http.ResponseWriter, r *http.Request) {
 	var req SignupRequest
 
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		respondError(w, http.StatusBadRequest, "Invalid request body")
-		return
-	}
-
-	// TODO: add email validation
-	if req.Email == "" || req.Password == "" {
-		respondError(w, http.StatusBadRequest, "Email and password are required")
+	if !decodeRequest(w, r, &req) {
 		return
 	}
 
-	// TODO: add another various checking for better password validation
-	if len(req.Password) < 6 {
-		respondError(w, http.StatusBadRequest, "Password must be at least 6 characters")
+	hashedPassword, err := config.PasswordHasher.HashContext(r.Context(), req.Password)
+	if err != nil {
+		logError(r, "Failed to create user", err)
+		respondError(w, r, http.StatusInternalServerError, constants.ErrorInternal, "Failed to create user")
 		return
 	}
 
-	user, err := models.CreateUser(req.Email, req.Password)
+	user, err := s.Users.Create(r.Context(), req.Email, hashedPassword)
 
 	if err != nil {
 		if errors.Is(err, models.ErrEmailExists) {
-			respondError(w, http.StatusConflict, "Email already exists")
+			s.recordAudit(r, models.AuditEvent{
+				EventType: constants.EventSignup,
+				Outcome:   constants.OutcomeFailure,
+				Details:   map[string]interface{}{"reason": "email_exists", "email": req.Email},
+			})
+			respondError(w, r, http.StatusConflict, constants.ErrorEmailExists, "Email already exists")
 			return
 		}
-		respondError(w, http.StatusInternalServerError, "Failed to create user")
+		logError(r, "Failed to create user", err)
+		respondError(w, r, http.StatusInternalServerError, constants.ErrorInternal, "Failed to create user")
 		return
 	}
 
-	session, err := models.CreateSession(user.ID)
+	s.recordAudit(r, models.AuditEvent{
+		EventType:    constants.EventSignup,
+		Outcome:      constants.OutcomeSuccess,
+		ActorUserID:  intPtr(user.ID),
+		TargetUserID: intPtr(user.ID),
+		Details:      map[string]interface{}{"provider": user.AuthProvider},
+	})
+
+	session, err := s.Sessions.Create(r.Context(), user.ID)
 
 	if err != nil {
-		respondError(w, http.StatusInternalServerError, "Failed to create user")
+		logError(r, "Failed to create user", err)
+		respondError(w, r, http.StatusInternalServerError, constants.ErrorInternal, "Failed to create user")
 		return
 	}
 
-	http.SetCookie(w, &http.Cookie{
-		Name:     "session_token",
-		Value:    session.Token,
-		Path:     "/",
-		HttpOnly: true,
-		MaxAge:   86400,
-	})
+	if err := config.SessionCookie.Set(w, session.Token); err != nil {
+		logError(r, "Failed to create session", err)
+		respondError(w, r, http.StatusInternalServerError, constants.ErrorInternal, "Failed to create session")
+		return
+	}
 
 	respondSuccess(w, "Signup Successfull", AuthResponse{
-		Token: session.Token,
 		User: UserResponse{
 			ID:           user.ID,
 			Email:        user.Email,
@@ -91,61 +108,90 @@ func Signup(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
-// handler login `POST /api/login`
-func Login(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodPost {
-		respondError(w, http.StatusMethodNotAllowed, "Method not allowed")
-		return
+// handle apply password policy, each failed rule becomes a field error
+func passwordPolicyErrors(password string, personal ...string) []FieldError {
+	var fieldErrors []FieldError
+
+	for _, violation := range config.PasswordPolicy.Validate(password, personal...) {
+		fieldErrors = append(fieldErrors, FieldError{
+			Field:   "password",
+			Code:    violation.Rule,
+			Message: violation.Message,
+		})
 	}
 
+	return fieldErrors
+}
+
+// handler login `POST /api/v1/login`
+func (s *Server) Login(w http.ResponseWriter, r *http.Request) {
 	var req LoginRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		respondError(w, http.StatusBadRequest, "Invalid request body")
+	if !decodeRequest(w, r, &req) {
 		return
 	}
 
-	if req.Email == "" || req.Password == "" {
-		respondError(w, http.StatusBadRequest, "Email and password are required")
+	user, err := s.Users.GetByIdentifier(r.Context(), req.Email, "")
+	if err != nil {
+		logError(r, "Failed to get user", err)
+		respondError(w, r, http.StatusInternalServerError, constants.ErrorInternal, "Failed to get user")
 		return
 	}
 
-	user, err := models.GetUserByIdentifier(req.Email, "")
-	if err != nil {
-		respondError(w, http.StatusInternalServerError, "Failed to get user")
+	if user == nil {
+		s.recordLoginFailure(r, nil, "unknown_email", req.Email)
+		respondError(w, r, http.StatusUnauthorized, constants.ErrorInvalidCredentials, "Username or password is incorrect")
 		return
 	}
 
-	if user == nil {
-		respondError(w, http.StatusUnauthorized, "Username or password is incorrect")
+	// google accounts can only use password login after setting one
+	if user.AuthProvider == constants.AuthProviderGoogle && user.Password == "" {
+		s.recordLoginFailure(r, intPtr(user.ID), "provider_mismatch", req.Email)
+		respondError(w, r, http.StatusBadRequest, constants.ErrorProviderMismatch, "Please login with Google")
 		return
 	}
 
-	if user.AuthProvider == constants.AuthProviderGoogle {
-		respondError(w, http.StatusBadRequest, "Please login with Google")
+	if !user.CheckPasswordContext(r.Context(), req.Password) {
+		s.recordLoginFailure(r, intPtr(user.ID), "wrong_password", req.Email)
+		respondError(w, r, http.StatusUnauthorized, constants.ErrorInvalidCredentials, "Username or password is incorrect")
 		return
 	}
 
-	if !user.CheckPassword(req.Password) {
-		respondError(w, http.StatusUnauthorized, "Username or password is incorrect")
+	// only tell a disabled account apart after the password matched
+	if user.IsDisabled() {
+		s.recordLoginFailure(r, intPtr(user.ID), "account_disabled", req.Email)
+		respondError(w, r, http.StatusForbidden, constants.ErrorAccountDisabled, "Account is disabled")
 		return
 	}
 
-	session, err := models.CreateSession(user.ID)
+	// upgrade outdated hashes while we still have the plain password
+	if user.PasswordNeedsRehash() {
+		if err := s.setPassword(r.Context(), user.ID, req.Password); err != nil {
+			logError(r, "Failed to rehash password", err, "user_id", user.ID)
+		}
+	}
+
+	session, err := s.Sessions.Create(r.Context(), user.ID)
 	if err != nil {
-		respondError(w, http.StatusInternalServerError, "Failed to create session")
+		logError(r, "Failed to create session", err)
+		respondError(w, r, http.StatusInternalServerError, constants.ErrorInternal, "Failed to create session")
+		return
+	}
+
+	if err := config.SessionCookie.Set(w, session.Token); err != nil {
+		logError(r, "Failed to create session", err)
+		respondError(w, r, http.StatusInternalServerError, constants.ErrorInternal, "Failed to create session")
 		return
 	}
 
-	http.SetCookie(w, &http.Cookie{
-		Name:     "session_token",
-		Value:    session.Token,
-		Path:     "/",
-		HttpOnly: true,
-		MaxAge:   86400,
+	s.recordAudit(r, models.AuditEvent{
+		EventType:    constants.EventLogin,
+		Outcome:      constants.OutcomeSuccess,
+		ActorUserID:  intPtr(user.ID),
+		TargetUserID: intPtr(user.ID),
+		Details:      map[string]interface{}{"provider": constants.AuthProviderLocal},
 	})
 
 	respondSuccess(w, "Login successful", AuthResponse{
-		Token: session.Token,
 		User: UserResponse{
 			ID:           user.ID,
 			Email:        user.Email,
@@ -156,100 +202,194 @@ func Login(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
-// handler login `POST /api/logout`
-func Logout(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodPost {
-		respondError(w, http.StatusMethodNotAllowed, "Method not allowed")
+func (s *Server) recordLoginFailure(r *http.Request, userID *int, reason, email string) {
+	s.recordAudit(r, models.AuditEvent{
+		EventType:    constants.EventLogin,
+		Outcome:      constants.OutcomeFailure,
+		TargetUserID: userID,
+		Details:      map[string]interface{}{"reason": reason, "email": email},
+	})
+}
+
+// handler logout `POST /api/v1/logout`
+func (s *Server) Logout(w http.ResponseWriter, r *http.Request) {
+	token, err := config.SessionCookie.Read(r)
+	if err != nil {
+		respondError(w, r, http.StatusBadRequest, constants.ErrorNoSession, "No session found")
 		return
 	}
 
-	cookie, err := r.Cookie("session_token")
-	if err != nil {
-		respondError(w, http.StatusBadRequest, "No session found")
+	// keep the cookie while the session is still valid server side, the client can retry
+	if err := s.Sessions.Delete(r.Context(), token); err != nil {
+		logError(r, "Failed to delete session", err)
+		respondError(w, r, http.StatusInternalServerError, constants.ErrorInternal, "Failed to logout")
 		return
 	}
 
-	models.DeleteSession(cookie.Value)
+	config.SessionCookie.Clear(w)
 
-	http.SetCookie(w, &http.Cookie{
-		Name:     "session_token",
-		Value:    "",
-		Path:     "/",
-		HttpOnly: true,
-		MaxAge:   -1,
-	})
+	if user := GetUserFromCtx(r); user != nil {
+		s.recordAudit(r, models.AuditEvent{
+			EventType:    constants.EventLogout,
+			Outcome:      constants.OutcomeSuccess,
+			ActorUserID:  intPtr(user.ID),
+			TargetUserID: intPtr(user.ID),
+		})
+	}
 
 	respondSuccess(w, "Logout successful", nil)
 }
 
-// handler login `GET /api/auth/google`
-func GoogleLogin(w http.ResponseWriter, r *http.Request) {
-	url := config.GoogleOAuthConfig.AuthCodeURL("state-token")
+// handler login `GET /api/v1/auth/google`
+func (s *Server) GoogleLogin(w http.ResponseWriter, r *http.Request) {
+	oauthConfig := config.GoogleOAuth()
+	if oauthConfig == nil {
+		redirectError(w, r, http.StatusTemporaryRedirect, constants.ErrorGoogleDisabled)
+		return
+	}
+
+	url := oauthConfig.AuthCodeURL("state-token")
 	http.Redirect(w, r, url, http.StatusTemporaryRedirect)
 }
 
-// handler login `GET /api/auth/google/callback`
-func GoogleCallback(w http.ResponseWriter, r *http.Request) {
+// handler login `GET /api/v1/auth/google/callback`
+func (s *Server) GoogleCallback(w http.ResponseWriter, r *http.Request) {
+	// read once so a secret reload mid request cannot mix two configs
+	oauthConfig := config.GoogleOAuth()
+	if oauthConfig == nil {
+		redirectError(w, r, http.StatusTemporaryRedirect, constants.ErrorGoogleDisabled)
+		return
+	}
+
 	code := r.URL.Query().Get("code")
 	if code == "" {
-		http.Redirect(w, r, "/login?error=Code not found", http.StatusTemporaryRedirect)
+		s.recordGoogleFailure(r, nil, "missing_code")
+		redirectError(w, r, http.StatusTemporaryRedirect, constants.ErrorOAuthCodeMissing)
 		return
 	}
 
-	token, err := config.GoogleOAuthConfig.Exchange(context.Background(), code)
+	// the oauth2 package makes its requests with the client in ctx, traced like every outgoing call
+	oauthCtx := context.WithValue(r.Context(), oauth2.HTTPClient, tracing.HTTPClient())
+
+	exchangeCtx, span := tracing.Start(oauthCtx, "google.token_exchange")
+	token, err := oauthConfig.Exchange(exchangeCtx, code)
+	tracing.RecordError(span, err)
+	span.End()
+
 	if err != nil {
-		http.Redirect(w, r, "/login?error=Failed to exchange token", http.StatusTemporaryRedirect)
+		logError(r, "Failed to exchange Google code", err)
+		s.recordGoogleFailure(r, nil, "token_exchange_failed")
+		redirectError(w, r, http.StatusTemporaryRedirect, constants.ErrorOAuthExchangeFailed)
 		return
 	}
 
-	client := config.GoogleOAuthConfig.Client(context.Background(), token)
-	resp, err := client.Get("https://www.googleapis.com/oauth2/v2/userinfo")
+	googleUser, err := fetchGoogleUser(oauthCtx, oauthConfig.Client(oauthCtx, token))
 	if err != nil {
-		http.Redirect(w, r, "/login?error=Failed to get user info", http.StatusTemporaryRedirect)
+		logError(r, "Failed to get Google user info", err)
+		s.recordGoogleFailure(r, nil, "userinfo_failed")
+		redirectError(w, r, http.StatusTemporaryRedirect, constants.ErrorOAuthUserInfoFailed)
 		return
 	}
-	defer resp.Body.Close()
-
-	body, _ := io.ReadAll(resp.Body)
-
-	var googleUser struct {
-		ID    string `json:"id"`
-		Email string `json:"email"`
-		Name  string `json:"name"`
-	}
-	json.Unmarshal(body, &googleUser)
 
-	user, err := models.GetUserByIdentifier("", googleUser.ID)
+	user, err := s.Users.GetByIdentifier(r.Context(), "", googleUser.ID)
 	if err != nil {
-		http.Redirect(w, r, "/login?error=Failed to get user", http.StatusTemporaryRedirect)
+		logError(r, "Failed to get user", err)
+		redirectError(w, r, http.StatusTemporaryRedirect, constants.ErrorInternal)
 		return
 	}
 
 	if user == nil {
-		user, err = models.CreateUserWithGoogle(googleUser.Email, googleUser.ID, googleUser.Name)
+		user, err = s.Users.CreateWithGoogle(r.Context(), googleUser.Email, googleUser.ID, googleUser.Name)
 		if err != nil {
 			if errors.Is(err, models.ErrEmailExists) {
-				http.Redirect(w, r, "/login?error=Email already registered", http.StatusTemporaryRedirect)
+				s.recordGoogleFailure(r, map[string]interface{}{"email": googleUser.Email}, "email_exists")
+				redirectError(w, r, http.StatusTemporaryRedirect, constants.ErrorEmailExists)
 				return
 			}
-			http.Redirect(w, r, "/login?error=Failed to create user", http.StatusTemporaryRedirect)
+			logError(r, "Failed to create user", err)
+			redirectError(w, r, http.StatusTemporaryRedirect, constants.ErrorInternal)
 			return
 		}
+
+		s.recordAudit(r, models.AuditEvent{
+			EventType:    constants.EventSignup,
+			Outcome:      constants.OutcomeSuccess,
+			ActorUserID:  intPtr(user.ID),
+			TargetUserID: intPtr(user.ID),
+			Details:      map[string]interface{}{"provider": constants.AuthProviderGoogle},
+		})
+	}
+
+	if user.IsDisabled() {
+		s.recordGoogleFailure(r, map[string]interface{}{"email": user.Email}, "account_disabled")
+		redirectError(w, r, http.StatusTemporaryRedirect, constants.ErrorAccountDisabled)
+		return
 	}
 
-	session, err := models.CreateSession(user.ID)
+	session, err := s.Sessions.Create(r.Context(), user.ID)
 	if err != nil {
-		http.Redirect(w, r, "/login?error=Failed to create session", http.StatusTemporaryRedirect)
+		logError(r, "Failed to create session", err)
+		redirectError(w, r, http.StatusTemporaryRedirect, constants.ErrorInternal)
 		return
 	}
 
-	http.SetCookie(w, &http.Cookie{
-		Name:     "session_token",
-		Value:    session.Token,
-		Path:     "/",
-		HttpOnly: true,
-		MaxAge:   86400,
+	if err := config.SessionCookie.Set(w, session.Token); err != nil {
+		logError(r, "Failed to create session", err)
+		redirectError(w, r, http.StatusTemporaryRedirect, constants.ErrorInternal)
+		return
+	}
+
+	s.recordAudit(r, models.AuditEvent{
+		EventType:    constants.EventGoogleLogin,
+		Outcome:      constants.OutcomeSuccess,
+		ActorUserID:  intPtr(user.ID),
+		TargetUserID: intPtr(user.ID),
+		Details:      map[string]interface{}{"provider": constants.AuthProviderGoogle},
 	})
 
 	http.Redirect(w, r, "/profile", http.StatusTemporaryRedirect)
 }
+
+type googleUserInfo struct {
+	ID    string `json:"id"`
+	Email string `json:"email"`
+	Name  string `json:"name"`
+}
+
+// handle read the signed in Google account, in a `google.userinfo` span
+func fetchGoogleUser(ctx context.Context, client *http.Client) (*googleUserInfo, error) {
+	ctx, span := tracing.Start(ctx, "google.userinfo")
+	defer span.End()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, "https://www.googleapis.com/oauth2/v2/userinfo", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		tracing.RecordError(span, err)
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(resp.Body)
+
+	var googleUser googleUserInfo
+	json.Unmarshal(body, &googleUser)
+
+	return &googleUser, nil
+}
+
+func (s *Server) recordGoogleFailure(r *http.Request, details map[string]interface{}, reason string) {
+	if details == nil {
+		details = map[string]interface{}{}
+	}
+	details["reason"] = reason
+
+	s.recordAudit(r, models.AuditEvent{
+		EventType: constants.EventGoogleLogin,
+		Outcome:   constants.OutcomeFailure,
+		Details:   details,
+	})
+}
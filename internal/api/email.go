@@ -0,0 +1,112 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"user-auth-go/constants"
+	"user-auth-go/internal/config"
+	"user-auth-go/internal/mail"
+	"user-auth-go/internal/models"
+)
+
+// handle send confirmation link to the new address and a notice with cancel link to the old one
+func sendEmailChangeMails(user *models.User, change *models.EmailChange) error {
+	confirmURL := fmt.Sprintf("%s/api/v1/profile/email/confirm?token=%s", config.AppBaseURL, url.QueryEscape(change.ConfirmToken))
+	cancelURL := fmt.Sprintf("%s/api/v1/profile/email/cancel?token=%s", config.AppBaseURL, url.QueryEscape(change.CancelToken))
+
+	err := config.Mailer.Send(mail.Message{
+		To:      change.NewEmail,
+		Subject: "Confirm your new email address",
+		Body: fmt.Sprintf("We received a request to change the email of your account to this address.\n\n"+
+			"Confirm the change by opening this link:\n%s\n\n"+
+			"The link expires at %s. If you didn't request this, you can ignore this email.",
+			confirmURL, change.ExpiresAt.UTC().Format("2006-01-02 15:04 MST")),
+	})
+	if err != nil {
+		return err
+	}
+
+	return config.Mailer.Send(mail.Message{
+		To:      user.Email,
+		Subject: "Your email address is about to change",
+		Body: fmt.Sprintf("We received a request to change the email of your account from %s to %s.\n\n"+
+			"If this wasn't you, cancel the change and sign out every device by opening this link:\n%s",
+			user.Email, change.NewEmail, cancelURL),
+	})
+}
+
+// handler confirm email change `GET /api/v1/profile/email/confirm?token=`
+func (s *Server) ConfirmEmailChange(w http.ResponseWriter, r *http.Request) {
+	change, err := s.EmailChanges.GetByConfirmToken(r.Context(), r.URL.Query().Get("token"))
+	if err != nil {
+		logError(r, "Failed to get email change", err)
+		redirectError(w, r, http.StatusSeeOther, constants.ErrorInternal)
+		return
+	}
+
+	if change == nil {
+		redirectError(w, r, http.StatusSeeOther, constants.ErrorLinkInvalid)
+		return
+	}
+
+	if err := s.EmailChanges.Confirm(r.Context(), change); err != nil {
+		if errors.Is(err, models.ErrEmailExists) {
+			s.EmailChanges.Delete(r.Context(), change.ID)
+			redirectError(w, r, http.StatusSeeOther, constants.ErrorEmailExists)
+			return
+		}
+		logError(r, "Failed to confirm email change", err, "user_id", change.UserID)
+		redirectError(w, r, http.StatusSeeOther, constants.ErrorInternal)
+		return
+	}
+
+	s.recordAudit(r, models.AuditEvent{
+		EventType:    constants.EventEmailChangeConfirm,
+		Outcome:      constants.OutcomeSuccess,
+		TargetUserID: intPtr(change.UserID),
+		Details:      map[string]interface{}{"new_email": change.NewEmail},
+	})
+
+	http.Redirect(w, r, "/profile", http.StatusSeeOther)
+}
+
+// handler cancel email change `GET /api/v1/profile/email/cancel?token=`
+// cancelling means the request wasn't made by the owner, so every session is revoked
+func (s *Server) CancelEmailChange(w http.ResponseWriter, r *http.Request) {
+	change, err := s.EmailChanges.GetByCancelToken(r.Context(), r.URL.Query().Get("token"))
+	if err != nil {
+		logError(r, "Failed to get email change", err)
+		redirectError(w, r, http.StatusSeeOther, constants.ErrorInternal)
+		return
+	}
+
+	if change == nil {
+		redirectError(w, r, http.StatusSeeOther, constants.ErrorLinkInvalid)
+		return
+	}
+
+	if err := s.EmailChanges.Delete(r.Context(), change.ID); err != nil {
+		logError(r, "Failed to cancel email change", err, "user_id", change.UserID)
+		redirectError(w, r, http.StatusSeeOther, constants.ErrorInternal)
+		return
+	}
+
+	if err := s.Sessions.DeleteForUser(r.Context(), change.UserID); err != nil {
+		logError(r, "Failed to revoke sessions", err, "user_id", change.UserID)
+		redirectError(w, r, http.StatusSeeOther, constants.ErrorInternal)
+		return
+	}
+
+	config.SessionCookie.Clear(w)
+
+	s.recordAudit(r, models.AuditEvent{
+		EventType:    constants.EventEmailChangeCancel,
+		Outcome:      constants.OutcomeSuccess,
+		TargetUserID: intPtr(change.UserID),
+		Details:      map[string]interface{}{"new_email": change.NewEmail, "sessions_revoked": true},
+	})
+
+	http.Redirect(w, r, "/login?notice="+constants.NoticeEmailChangeCancelled, http.StatusSeeOther)
+}
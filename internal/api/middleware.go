@@ -2,56 +2,144 @@ package api
 
 import (
 	"context"
+	"crypto/x509"
 	"net/http"
+	"user-auth-go/constants"
+	"user-auth-go/internal/certs"
+	"user-auth-go/internal/config"
+	"user-auth-go/internal/logging"
 	"user-auth-go/internal/models"
 )
 
 type ContextKey  string
 
 const UserCtxKey ContextKey = "user"
+const SessionCtxKey ContextKey = "session"
 
-func AuthGuard(next http.HandlerFunc) http.HandlerFunc {
+func (s *Server) AuthGuard(next http.HandlerFunc) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		cookie, err := r.Cookie("session_token")
+		token, err := config.SessionCookie.Read(r)
 
 		if err != nil {
-			respondError(w, http.StatusUnauthorized, "Unauthorized")
+			// machine clients without a session authenticate with their client certificate
+			if cert := certs.ClientCertificate(r); cert != nil && config.ClientIdentities != nil {
+				s.certificateAuth(w, r, cert, next)
+				return
+			}
+
+			// anonymous requests are not audited, every crawler would fill the log
+			respondError(w, r, http.StatusUnauthorized, constants.ErrorUnauthorized, "Unauthorized")
 			return
 		}
 
-		session, err := models.GetSessionByToken(cookie.Value)
+		session, err := s.Sessions.GetByToken(r.Context(), token)
 
 		if err != nil {
-			respondError(w, http.StatusInternalServerError, "Failed to validate session")
+			logError(r, "Failed to validate session", err)
+			respondError(w, r, http.StatusInternalServerError, constants.ErrorInternal, "Failed to validate session")
 			return
 		}
 
 		if session == nil {
 			// invalidate session token
-			http.SetCookie(w, &http.Cookie{
-				Name:     "session_token",
-				Value:    "",
-				Path:     "/",
-				HttpOnly: true,
-				MaxAge:   -1,
-			})
-
-			respondError(w, http.StatusUnauthorized, "Session Expired")
+			config.SessionCookie.Clear(w)
+
+			s.recordGuardDenied(r, nil, "session_expired")
+			respondError(w, r, http.StatusUnauthorized, constants.ErrorSessionExpired, "Session Expired")
 			return
 		}
 
-		user, err := models.GetUserByID(session.UserID)
+		user, err := s.Users.GetByID(r.Context(), session.UserID)
 
 		if err != nil || user == nil {
-			respondError(w, http.StatusUnauthorized, "User not found")
+			s.recordGuardDenied(r, intPtr(session.UserID), "user_not_found")
+			respondError(w, r, http.StatusUnauthorized, constants.ErrorUserNotFound, "User not found")
+			return
+		}
+
+		if user.IsDisabled() {
+			config.SessionCookie.Clear(w)
+
+			s.recordGuardDenied(r, intPtr(user.ID), "account_disabled")
+			respondError(w, r, http.StatusForbidden, constants.ErrorAccountDisabled, "Account is disabled")
+			return
 		}
 
-		// handle to save user to it's context
+		logging.SetUserID(r.Context(), user.ID)
+
+		// handle to save user and current session to it's context
 		ctx := context.WithValue(r.Context(), UserCtxKey, user)
+		ctx = context.WithValue(ctx, SessionCtxKey, session)
 		next.ServeHTTP(w, r.WithContext(ctx))
 	}
 }
 
+// handle authenticate as the user mapped to a verified client certificate, requests
+// carry no session so session bound actions like changing the password stay unavailable
+func (s *Server) certificateAuth(w http.ResponseWriter, r *http.Request, cert *x509.Certificate, next http.HandlerFunc) {
+	email, ok := config.ClientIdentities.Lookup(cert)
+	if !ok {
+		s.recordGuardDenied(r, nil, "unknown_certificate")
+		respondError(w, r, http.StatusUnauthorized, constants.ErrorUnauthorized, "Unauthorized")
+		return
+	}
+
+	user, err := s.Users.GetByIdentifier(r.Context(), email, "")
+
+	if err != nil {
+		logError(r, "Failed to find certificate user", err)
+		respondError(w, r, http.StatusInternalServerError, constants.ErrorInternal, "Failed to validate certificate")
+		return
+	}
+
+	if user == nil {
+		s.recordGuardDenied(r, nil, "user_not_found")
+		respondError(w, r, http.StatusUnauthorized, constants.ErrorUserNotFound, "User not found")
+		return
+	}
+
+	if user.IsDisabled() {
+		s.recordGuardDenied(r, intPtr(user.ID), "account_disabled")
+		respondError(w, r, http.StatusForbidden, constants.ErrorAccountDisabled, "Account is disabled")
+		return
+	}
+
+	logging.SetUserID(r.Context(), user.ID)
+
+	ctx := context.WithValue(r.Context(), UserCtxKey, user)
+	next.ServeHTTP(w, r.WithContext(ctx))
+}
+
+// only admins can pass, must be used inside AuthGuard
+func (s *Server) AdminGuard(next http.HandlerFunc) http.HandlerFunc {
+	return s.AuthGuard(func(w http.ResponseWriter, r *http.Request) {
+		user := GetUserFromCtx(r)
+
+		if user == nil {
+			respondError(w, r, http.StatusUnauthorized, constants.ErrorUnauthorized, "Unauthorized")
+			return
+		}
+
+		if !user.IsAdmin() {
+			s.recordGuardDenied(r, intPtr(user.ID), "not_admin")
+			respondError(w, r, http.StatusForbidden, constants.ErrorForbidden, "Forbidden")
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// handle audit a request refused although it presented a session or a client certificate
+func (s *Server) recordGuardDenied(r *http.Request, userID *int, reason string) {
+	s.recordAudit(r, models.AuditEvent{
+		EventType:   constants.EventAuthGuardDenied,
+		Outcome:     constants.OutcomeFailure,
+		ActorUserID: userID,
+		Details:     map[string]interface{}{"reason": reason, "path": r.URL.Path},
+	})
+}
+
 func GetUserFromCtx(r *http.Request) *models.User {
 	user, ok := r.Context().Value(UserCtxKey).(*models.User)
 	if !ok {
@@ -59,3 +147,11 @@ func GetUserFromCtx(r *http.Request) *models.User {
 	}
 	return user
 }
+
+func GetSessionFromCtx(r *http.Request) *models.Session {
+	session, ok := r.Context().Value(SessionCtxKey).(*models.Session)
+	if !ok {
+		return nil
+	}
+	return session
+}
@@ -1,7 +1,6 @@
 package api
 
 import (
-	"encoding/json"
 	"errors"
 	"net/http"
 	"user-auth-go/constants"
@@ -9,9 +8,17 @@ import (
 )
 
 type UpdateProfileRequest struct {
-	FullName  string `json:"full_name"`
-	Telephone string `json:"telephone"`
-	Email     string `json:"email"`
+	FullName  string `json:"full_name" validate:"required,max=100"`
+	Telephone string `json:"telephone" validate:"max=20"`
+	Email     string `json:"email" validate:"required,email,max=254"`
+
+	// required when a local account changes its email
+	CurrentPassword string `json:"current_password"`
+}
+
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"current_password"`
+	NewPassword     string `json:"new_password" validate:"required"`
 }
 
 type ProfileResponse struct {
@@ -20,102 +27,248 @@ type ProfileResponse struct {
 	FullName     string `json:"full_name"`
 	Telephone    string `json:"telephone"`
 	AuthProvider string `json:"auth_provider"`
+	PendingEmail string `json:"pending_email,omitempty"`
 }
 
-func GetProfile(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodGet {
-		respondError(w, http.StatusMethodNotAllowed, "Method not allowed")
-		return
-	}
-
+// handler profile of logged user `GET /api/v1/profile`
+func (s *Server) GetProfile(w http.ResponseWriter, r *http.Request) {
 	user := GetUserFromCtx(r)
 
 	if user == nil {
-		respondError(w, http.StatusUnauthorized, "Unauthorized")
+		respondError(w, r, http.StatusUnauthorized, constants.ErrorUnauthorized, "Unauthorized")
 		return
 	}
 
-	respondSuccess(w, "Profile Retrieved", ProfileResponse{
+	response := ProfileResponse{
 		ID:           user.ID,
 		Email:        user.Email,
 		FullName:     user.FullName,
 		Telephone:    user.Telephone,
 		AuthProvider: user.AuthProvider,
-	})
-}
+	}
 
-func UpdateProfile(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodPut {
-		respondError(w, http.StatusMethodNotAllowed, "Method not allowed")
+	change, err := s.EmailChanges.GetPending(r.Context(), user.ID)
+	if err != nil {
+		logError(r, "Failed to get profile", err)
+		respondError(w, r, http.StatusInternalServerError, constants.ErrorInternal, "Failed to get profile")
 		return
 	}
 
+	if change != nil {
+		response.PendingEmail = change.NewEmail
+	}
+
+	respondSuccess(w, "Profile Retrieved", response)
+}
+
+// handler update profile `PUT /api/v1/profile`
+func (s *Server) UpdateProfile(w http.ResponseWriter, r *http.Request) {
 	user := GetUserFromCtx(r)
 
 	if user == nil {
-		respondError(w, http.StatusUnauthorized, "Unauthorized")
+		respondError(w, r, http.StatusUnauthorized, constants.ErrorUnauthorized, "Unauthorized")
+		return
 	}
 
 	var req UpdateProfileRequest
 
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		respondError(w, http.StatusBadRequest, "Invalid request body")
+	if !decodeRequest(w, r, &req) {
 		return
 	}
 
-	// TODO: move validation to struct users
-	if req.FullName == "" {
-		respondError(w, http.StatusBadRequest, "Full name is required")
-		return
-	}
+	emailChanged := req.Email != user.Email
 
-	if req.Email == "" {
-		respondError(w, http.StatusBadRequest, "Email is required")
+	if user.AuthProvider == constants.AuthProviderGoogle && emailChanged {
+		respondError(w, r, http.StatusBadRequest, constants.ErrorProviderMismatch, "Cannot change email for Google account")
 		return
 	}
 
-	if user.AuthProvider == constants.AuthProviderGoogle && req.Email != user.Email {
-		respondError(w, http.StatusBadRequest, "Cannot change email for Google account")
-		return
+	// a stolen session alone must not be enough to take over the account
+	if emailChanged {
+		if req.CurrentPassword == "" {
+			respondError(w, r, http.StatusBadRequest, constants.ErrorCurrentPasswordRequired, "Current password is required to change email")
+			return
+		}
+
+		if !user.CheckPasswordContext(r.Context(), req.CurrentPassword) {
+			s.recordAudit(r, models.AuditEvent{
+				EventType:    constants.EventEmailChangeRequest,
+				Outcome:      constants.OutcomeFailure,
+				ActorUserID:  intPtr(user.ID),
+				TargetUserID: intPtr(user.ID),
+				Details:      map[string]interface{}{"reason": "wrong_password"},
+			})
+			respondError(w, r, http.StatusUnauthorized, constants.ErrorCurrentPasswordIncorrect, "Current password is incorrect")
+			return
+		}
+
+		existing, err := s.Users.GetByIdentifier(r.Context(), req.Email, "")
+		if err != nil {
+			logError(r, "Failed to update profile", err)
+			respondError(w, r, http.StatusInternalServerError, constants.ErrorInternal, "Failed to update profile")
+			return
+		}
+
+		if existing != nil {
+			respondError(w, r, http.StatusConflict, constants.ErrorEmailExists, "Email already exists")
+			return
+		}
 	}
 
-	// handle update user profile
-	err := models.UpdateUserProfile(user.ID, req.FullName, req.Telephone, req.Email)
+	// handle update user profile, email is only changed after confirmation
+	err := s.Users.UpdateProfile(r.Context(), user.ID, req.FullName, req.Telephone, user.Email)
 
 	if err != nil {
 		if errors.Is(err, models.ErrEmailExists) {
-			respondError(w, http.StatusConflict, "Email already exists")
+			respondError(w, r, http.StatusConflict, constants.ErrorEmailExists, "Email already exists")
 			return
 		}
-		respondError(w, http.StatusInternalServerError, "Failed to update profile")
+		logError(r, "Failed to update profile", err)
+		respondError(w, r, http.StatusInternalServerError, constants.ErrorInternal, "Failed to update profile")
 		return
 	}
 
-	// handle get newest user id
-	// TODO: make sure is my sql support returning value?
-	updatedUser, err := models.GetUserByID(user.ID)
+	message := "Profile updated"
+	pendingEmail := ""
+
+	if emailChanged {
+		change, err := s.EmailChanges.Create(r.Context(), user.ID, req.Email)
+		if err != nil {
+			logError(r, "Failed to request email change", err)
+			respondError(w, r, http.StatusInternalServerError, constants.ErrorInternal, "Failed to request email change")
+			return
+		}
+
+		if err := sendEmailChangeMails(user, change); err != nil {
+			logError(r, "Failed to send confirmation email", err)
+			s.EmailChanges.Delete(r.Context(), change.ID)
+			respondError(w, r, http.StatusInternalServerError, constants.ErrorInternal, "Failed to send confirmation email")
+			return
+		}
+
+		s.recordAudit(r, models.AuditEvent{
+			EventType:    constants.EventEmailChangeRequest,
+			Outcome:      constants.OutcomeSuccess,
+			ActorUserID:  intPtr(user.ID),
+			TargetUserID: intPtr(user.ID),
+			Details:      map[string]interface{}{"old_email": user.Email, "new_email": change.NewEmail},
+		})
+
+		message = "Profile updated. Check your new email address to confirm the change"
+		pendingEmail = change.NewEmail
+	}
+
+	s.recordAudit(r, models.AuditEvent{
+		EventType:    constants.EventProfileUpdate,
+		Outcome:      constants.OutcomeSuccess,
+		ActorUserID:  intPtr(user.ID),
+		TargetUserID: intPtr(user.ID),
+		Details:      map[string]interface{}{"changed_fields": changedProfileFields(user, req)},
+	})
+
+	// handle read the updated user back for the response
+	updatedUser, err := s.Users.GetByID(r.Context(), user.ID)
 	if err != nil {
-		respondError(w, http.StatusInternalServerError, "Failed to get updated profile")
+		logError(r, "Failed to get updated profile", err)
+		respondError(w, r, http.StatusInternalServerError, constants.ErrorInternal, "Failed to get updated profile")
 		return
 	}
 
-	respondSuccess(w, "Profile updated", ProfileResponse{
+	respondSuccess(w, message, ProfileResponse{
 		ID:           updatedUser.ID,
 		Email:        updatedUser.Email,
 		FullName:     updatedUser.FullName,
 		Telephone:    updatedUser.Telephone,
 		AuthProvider: updatedUser.AuthProvider,
+		PendingEmail: pendingEmail,
 	})
 
 }
 
-func Profile(w http.ResponseWriter, r *http.Request) {
-	switch r.Method {
-	case http.MethodGet:
-		GetProfile(w, r)
-	case http.MethodPut:
-		UpdateProfile(w, r)
-	default:
-		respondError(w, http.StatusMethodNotAllowed, "Method not allowed")
+// handler change password `PUT /api/v1/profile/password`
+func (s *Server) ChangePassword(w http.ResponseWriter, r *http.Request) {
+	user := GetUserFromCtx(r)
+	session := GetSessionFromCtx(r)
+
+	if user == nil || session == nil {
+		respondError(w, r, http.StatusUnauthorized, constants.ErrorUnauthorized, "Unauthorized")
+		return
+	}
+
+	var req ChangePasswordRequest
+
+	if !decodeRequest(w, r, &req) {
+		return
+	}
+
+	// google accounts without password can set their first one without re-authentication
+	if user.Password != "" {
+		if req.CurrentPassword == "" {
+			respondError(w, r, http.StatusBadRequest, constants.ErrorCurrentPasswordRequired, "Current password is required")
+			return
+		}
+
+		if !user.CheckPasswordContext(r.Context(), req.CurrentPassword) {
+			s.recordAudit(r, models.AuditEvent{
+				EventType:    constants.EventPasswordChange,
+				Outcome:      constants.OutcomeFailure,
+				ActorUserID:  intPtr(user.ID),
+				TargetUserID: intPtr(user.ID),
+				Details:      map[string]interface{}{"reason": "wrong_password"},
+			})
+			respondError(w, r, http.StatusUnauthorized, constants.ErrorCurrentPasswordIncorrect, "Current password is incorrect")
+			return
+		}
+
+		if user.CheckPasswordContext(r.Context(), req.NewPassword) {
+			respondError(w, r, http.StatusBadRequest, constants.ErrorPasswordUnchanged, "New password must be different from the current password")
+			return
+		}
 	}
+
+	if fieldErrors := passwordPolicyErrors(req.NewPassword, user.Email, user.FullName); len(fieldErrors) > 0 {
+		for i := range fieldErrors {
+			fieldErrors[i].Field = "new_password"
+		}
+		respondValidationError(w, r, constants.ErrorPasswordPolicy, "Password does not meet requirements", fieldErrors)
+		return
+	}
+
+	if err := s.setPassword(r.Context(), user.ID, req.NewPassword); err != nil {
+		logError(r, "Failed to update password", err)
+		respondError(w, r, http.StatusInternalServerError, constants.ErrorInternal, "Failed to update password")
+		return
+	}
+
+	// sign out every other device, the current one stays logged in
+	if err := s.Sessions.DeleteForUserExcept(r.Context(), user.ID, session.Token); err != nil {
+		logError(r, "Failed to revoke other sessions", err)
+		respondError(w, r, http.StatusInternalServerError, constants.ErrorInternal, "Failed to revoke other sessions")
+		return
+	}
+
+	s.recordAudit(r, models.AuditEvent{
+		EventType:    constants.EventPasswordChange,
+		Outcome:      constants.OutcomeSuccess,
+		ActorUserID:  intPtr(user.ID),
+		TargetUserID: intPtr(user.ID),
+		Details:      map[string]interface{}{"first_password": user.Password == ""},
+	})
+
+	respondSuccess(w, "Password updated", nil)
+}
+
+// handle list which profile fields differ from the request, email is handled separately
+func changedProfileFields(user *models.User, req UpdateProfileRequest) []string {
+	fields := []string{}
+
+	if user.FullName != req.FullName {
+		fields = append(fields, "full_name")
+	}
+
+	if user.Telephone != req.Telephone {
+		fields = append(fields, "telephone")
+	}
+
+	return fields
 }
@@ -2,28 +2,120 @@ package api
 
 import (
 	"encoding/json"
+	"mime"
 	"net/http"
+	"strconv"
+	"strings"
+	"user-auth-go/internal/logging"
 )
 
 type APIResponse struct {
-	Success bool        `json:"success"`
-	Message string      `json:"message,omitempty"`
-	Data    interface{} `json:"data,omitempty"`
+	Success bool         `json:"success"`
+	Message string       `json:"message,omitempty"`
+	Data    interface{}  `json:"data,omitempty"`
+	Error   *ErrorBody   `json:"error,omitempty"`
+	Errors  []FieldError `json:"errors,omitempty"`
 }
 
+// ErrorBody identifies why a request failed, Code is one of the constants.Error* codes
+type ErrorBody struct {
+	Code string `json:"code"`
+}
+
+type FieldError struct {
+	Field   string `json:"field"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// ProblemDetails is the RFC 7807 form of an error, sent to clients preferring `application/problem+json`
+type ProblemDetails struct {
+	Type     string       `json:"type"`
+	Title    string       `json:"title"`
+	Status   int          `json:"status"`
+	Detail   string       `json:"detail,omitempty"`
+	Instance string       `json:"instance,omitempty"`
+	Code     string       `json:"code"`
+	Errors   []FieldError `json:"errors,omitempty"`
+}
+
+const problemContentType = "application/problem+json"
+
 func respondJSON(w http.ResponseWriter, status int, payload APIResponse) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(status)
 	json.NewEncoder(w).Encode(payload)
 }
 
-func respondError(w http.ResponseWriter, status int, message string) {
+// handle log the cause of a failed request, clients only get the generic message
+func logError(r *http.Request, message string, err error, args ...any) {
+	logging.FromContext(r.Context()).Error(message, append(args, "error", err)...)
+}
+
+func respondError(w http.ResponseWriter, r *http.Request, status int, code string, message string) {
+	respondFailure(w, r, status, code, message, nil)
+}
+
+func respondValidationError(w http.ResponseWriter, r *http.Request, code string, message string, errors []FieldError) {
+	respondFailure(w, r, http.StatusBadRequest, code, message, errors)
+}
+
+// handle error as the API envelope, or as problem details when the client asks for them
+func respondFailure(w http.ResponseWriter, r *http.Request, status int, code string, message string, errors []FieldError) {
+	if prefersProblem(r) {
+		w.Header().Set("Content-Type", problemContentType)
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(ProblemDetails{
+			Type:     "about:blank",
+			Title:    http.StatusText(status),
+			Status:   status,
+			Detail:   message,
+			Instance: r.URL.Path,
+			Code:     code,
+			Errors:   errors,
+		})
+		return
+	}
+
 	respondJSON(w, status, APIResponse{
 		Success: false,
 		Message: message,
+		Error:   &ErrorBody{Code: code},
+		Errors:  errors,
 	})
 }
 
+// handle `Accept` ranks application/problem+json above application/json, a tie keeps the envelope
+func prefersProblem(r *http.Request) bool {
+	problem, envelope := 0.0, 0.0
+
+	for _, accepted := range strings.Split(r.Header.Get("Accept"), ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(accepted))
+		if err != nil {
+			continue
+		}
+
+		quality := 1.0
+		if q, err := strconv.ParseFloat(params["q"], 64); err == nil {
+			quality = q
+		}
+
+		switch mediaType {
+		case problemContentType:
+			problem = max(problem, quality)
+		case "application/json":
+			envelope = max(envelope, quality)
+		}
+	}
+
+	return problem > envelope
+}
+
+// handle send a browser back to the login page, which shows the message of code
+func redirectError(w http.ResponseWriter, r *http.Request, status int, code string) {
+	http.Redirect(w, r, "/login?error="+code, status)
+}
+
 func respondSuccess(w http.ResponseWriter, message string, data interface{}) {
 	respondJSON(w, http.StatusOK, APIResponse{
 		Success: true,
@@ -0,0 +1,118 @@
+package api
+
+import (
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+	"user-auth-go/constants"
+)
+
+// APIPrefix is where the current version of every endpoint is mounted
+const APIPrefix = "/api/v1"
+
+// RFC 9745 `Deprecation` value of the unversioned `/api/...` aliases
+var deprecatedSince = "@" + strconv.FormatInt(time.Date(2026, time.October, 19, 0, 0, 0, 0, time.UTC).Unix(), 10)
+
+// route is one endpoint, path is relative to APIPrefix
+type route struct {
+	method  string
+	path    string
+	handler http.HandlerFunc
+	// unversioned path the endpoint was served at before, kept as a deprecated alias.
+	// Aliases of `{id}` routes take the id as `?id=`
+	legacy string
+}
+
+// handle every endpoint with the guard it needs
+func (s *Server) routes() []route {
+	return []route{
+		{method: http.MethodPost, path: "/signup", handler: s.Signup, legacy: "/api/signup"},
+		{method: http.MethodPost, path: "/login", handler: s.Login, legacy: "/api/login"},
+		{method: http.MethodGet, path: "/auth/google", handler: s.GoogleLogin, legacy: "/api/auth/google"},
+		{method: http.MethodGet, path: "/auth/google/callback", handler: s.GoogleCallback, legacy: "/api/auth/google/callback"},
+		{method: http.MethodGet, path: "/profile/email/confirm", handler: s.ConfirmEmailChange, legacy: "/api/profile/email/confirm"},
+		{method: http.MethodGet, path: "/profile/email/cancel", handler: s.CancelEmailChange, legacy: "/api/profile/email/cancel"},
+
+		// protected handlers
+		{method: http.MethodPost, path: "/logout", handler: s.AuthGuard(s.Logout), legacy: "/api/logout"},
+		{method: http.MethodGet, path: "/profile", handler: s.AuthGuard(s.GetProfile), legacy: "/api/profile"},
+		{method: http.MethodPut, path: "/profile", handler: s.AuthGuard(s.UpdateProfile), legacy: "/api/profile"},
+		{method: http.MethodPut, path: "/profile/password", handler: s.AuthGuard(s.ChangePassword), legacy: "/api/profile/password"},
+		{method: http.MethodGet, path: "/profile/activity", handler: s.AuthGuard(s.SecurityActivity), legacy: "/api/profile/activity"},
+
+		// admin handlers
+		{method: http.MethodGet, path: "/admin/audit-events", handler: s.AdminGuard(s.AdminAuditEvents), legacy: "/api/admin/audit-events"},
+		{method: http.MethodDelete, path: "/admin/users/{id}", handler: s.AdminGuard(s.AdminDeleteUser), legacy: "/api/admin/users"},
+		{method: http.MethodGet, path: "/admin/webhooks", handler: s.AdminGuard(s.AdminListWebhooks), legacy: "/api/admin/webhooks"},
+		{method: http.MethodPost, path: "/admin/webhooks", handler: s.AdminGuard(s.AdminCreateWebhook), legacy: "/api/admin/webhooks"},
+		{method: http.MethodPut, path: "/admin/webhooks/{id}", handler: s.AdminGuard(s.AdminUpdateWebhook), legacy: "/api/admin/webhooks"},
+		{method: http.MethodDelete, path: "/admin/webhooks/{id}", handler: s.AdminGuard(s.AdminDeleteWebhook), legacy: "/api/admin/webhooks"},
+		{method: http.MethodGet, path: "/admin/webhooks/deliveries", handler: s.AdminGuard(s.AdminWebhookDeliveries), legacy: "/api/admin/webhooks/deliveries"},
+		{method: http.MethodGet, path: "/admin/webhooks/deliveries/{id}", handler: s.AdminGuard(s.AdminWebhookDeliveries)},
+		{method: http.MethodPost, path: "/admin/webhooks/deliveries/{id}/redeliver", handler: s.AdminGuard(s.AdminRedeliverWebhook), legacy: "/api/admin/webhooks/deliveries/redeliver"},
+	}
+}
+
+// handle register every endpoint under APIPrefix and at its deprecated alias
+func (s *Server) Routes(mux *http.ServeMux) {
+	for _, rt := range s.routes() {
+		mux.HandleFunc(rt.method+" "+APIPrefix+rt.path, rt.handler)
+
+		if rt.legacy != "" {
+			mux.HandleFunc(rt.method+" "+rt.legacy, deprecated(APIPrefix+rt.path, rt.handler))
+		}
+	}
+
+	mux.HandleFunc(fallbackPattern, fallback(mux))
+}
+
+// matches every API request no endpoint pattern matched
+const fallbackPattern = "/api/"
+
+// methods fallback asks the mux about to fill `Allow`
+var routeMethods = []string{http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete}
+
+// handle 405 with the methods the path does serve in `Allow`, or 404 for unknown API paths
+func fallback(mux *http.ServeMux) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var allowed []string
+
+		for _, method := range routeMethods {
+			probe := r.WithContext(r.Context())
+			probe.Method = method
+
+			if _, pattern := mux.Handler(probe); pattern != fallbackPattern {
+				allowed = append(allowed, method)
+			}
+		}
+
+		if len(allowed) == 0 {
+			respondError(w, r, http.StatusNotFound, constants.ErrorNotFound, "Not found")
+			return
+		}
+
+		w.Header().Set("Allow", strings.Join(allowed, ", "))
+		respondError(w, r, http.StatusMethodNotAllowed, constants.ErrorMethodNotAllowed, "Method not allowed")
+	}
+}
+
+// handle point clients of an unversioned alias at its successor, `{id}` is filled from `?id=`
+func deprecated(successor string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		link := strings.Replace(successor, "{id}", url.PathEscape(r.URL.Query().Get("id")), 1)
+
+		w.Header().Set("Deprecation", deprecatedSince)
+		w.Header().Set("Link", "<"+link+`>; rel="successor-version"`)
+		next(w, r)
+	}
+}
+
+// handle `{id}` path value of versioned routes, `?id=` of their deprecated aliases
+func idParam(r *http.Request) string {
+	if id := r.PathValue("id"); id != "" {
+		return id
+	}
+	return r.URL.Query().Get("id")
+}
@@ -0,0 +1,26 @@
+package api
+
+import (
+	"context"
+	"user-auth-go/internal/config"
+	"user-auth-go/internal/models"
+)
+
+// Server holds what the API handlers need, handlers are its methods
+type Server struct {
+	*models.Store
+}
+
+func NewServer(store *models.Store) *Server {
+	return &Server{Store: store}
+}
+
+// handle hash and store new password for user
+func (s *Server) setPassword(ctx context.Context, userID int, password string) error {
+	hashedPassword, err := config.PasswordHasher.HashContext(ctx, password)
+	if err != nil {
+		return err
+	}
+
+	return s.Users.UpdatePassword(ctx, userID, hashedPassword)
+}
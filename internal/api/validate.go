@@ -0,0 +1,167 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/mail"
+	"reflect"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+	"user-auth-go/constants"
+	"user-auth-go/internal/config"
+)
+
+// validator is implemented by requests with rules struct tags cannot express,
+// its errors are reported together with the tag errors
+type validator interface {
+	Validate() []FieldError
+}
+
+// handle decode the JSON body into req and validate it, responding with the field errors
+// when it fails. Bodies over config.MaxBodyBytes, unknown fields and trailing data are rejected
+func decodeRequest(w http.ResponseWriter, r *http.Request, req interface{}) bool {
+	if !decodeJSON(w, r, req) {
+		return false
+	}
+
+	if fieldErrors := validateRequest(req); len(fieldErrors) > 0 {
+		respondValidationError(w, r, constants.ErrorValidation, "Validation failed", fieldErrors)
+		return false
+	}
+
+	return true
+}
+
+func decodeJSON(w http.ResponseWriter, r *http.Request, req interface{}) bool {
+	decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, config.MaxBodyBytes))
+	decoder.DisallowUnknownFields()
+
+	err := decoder.Decode(req)
+	if err == nil && decoder.More() {
+		err = errors.New("unexpected data after the JSON object")
+	}
+
+	if err == nil {
+		return true
+	}
+
+	var tooLarge *http.MaxBytesError
+	var typeErr *json.UnmarshalTypeError
+
+	switch {
+	case errors.As(err, &tooLarge):
+		respondError(w, r, http.StatusRequestEntityTooLarge, constants.ErrorBodyTooLarge, fmt.Sprintf("Request body must be at most %d bytes", tooLarge.Limit))
+
+	case errors.As(err, &typeErr):
+		respondValidationError(w, r, constants.ErrorInvalidRequest, "Invalid request body", []FieldError{{
+			Field:   typeErr.Field,
+			Code:    "invalid_type",
+			Message: "Must be a " + jsonType(typeErr.Type),
+		}})
+
+	// encoding/json has no error type for unknown fields
+	case strings.HasPrefix(err.Error(), "json: unknown field "):
+		field, _ := strconv.Unquote(strings.TrimPrefix(err.Error(), "json: unknown field "))
+		respondValidationError(w, r, constants.ErrorInvalidRequest, "Invalid request body", []FieldError{{
+			Field:   field,
+			Code:    "unknown_field",
+			Message: "Unknown field",
+		}})
+
+	default:
+		respondError(w, r, http.StatusBadRequest, constants.ErrorInvalidRequest, "Invalid request body")
+	}
+
+	return false
+}
+
+func jsonType(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.String:
+		return "string"
+	case reflect.Bool:
+		return "boolean"
+	case reflect.Slice, reflect.Array:
+		return "list"
+	case reflect.Map, reflect.Struct:
+		return "object"
+	}
+	return "number"
+}
+
+// handle check the `validate` tags of req, then its Validate method. Supported rules are
+// required, email (RFC 5322 address without display name), min=N and max=N characters or items
+func validateRequest(req interface{}) []FieldError {
+	var fieldErrors []FieldError
+
+	value := reflect.Indirect(reflect.ValueOf(req))
+
+	for i := 0; i < value.NumField(); i++ {
+		field := value.Type().Field(i)
+
+		rules := field.Tag.Get("validate")
+		if rules == "" {
+			continue
+		}
+
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if fieldError, ok := checkRules(name, value.Field(i), rules); !ok {
+			fieldErrors = append(fieldErrors, fieldError)
+		}
+	}
+
+	if v, ok := req.(validator); ok {
+		fieldErrors = append(fieldErrors, v.Validate()...)
+	}
+
+	return fieldErrors
+}
+
+// handle first rule the field breaks, later rules are skipped
+func checkRules(name string, value reflect.Value, rules string) (FieldError, bool) {
+	length := value.Len()
+	if value.Kind() == reflect.String {
+		length = utf8.RuneCountInString(value.String())
+	}
+
+	for _, rule := range strings.Split(rules, ",") {
+		rule, arg, _ := strings.Cut(rule, "=")
+		limit, _ := strconv.Atoi(arg)
+
+		switch rule {
+		case "required":
+			if length == 0 || value.Kind() == reflect.String && strings.TrimSpace(value.String()) == "" {
+				return FieldError{Field: name, Code: "required", Message: "Is required"}, false
+			}
+
+		case "email":
+			if length > 0 && !validEmail(value.String()) {
+				return FieldError{Field: name, Code: "invalid_email", Message: "Must be a valid email address"}, false
+			}
+
+		case "min":
+			if length > 0 && length < limit {
+				return FieldError{Field: name, Code: "too_short", Message: fmt.Sprintf("Must be at least %d characters", limit)}, false
+			}
+
+		case "max":
+			if length > limit {
+				return FieldError{Field: name, Code: "too_long", Message: fmt.Sprintf("Must be at most %d characters", limit)}, false
+			}
+
+		default:
+			panic(fmt.Sprintf("unknown validate rule %q on %s", rule, name))
+		}
+	}
+
+	return FieldError{}, true
+}
+
+// handle bare RFC 5322 address like `jane@example.com`, display names and comments are refused
+func validEmail(email string) bool {
+	address, err := mail.ParseAddress(email)
+	return err == nil && address.Address == email && address.Name == ""
+}
@@ -0,0 +1,361 @@
+package api
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+	"user-auth-go/constants"
+	"user-auth-go/internal/models"
+)
+
+type WebhookSubscriptionRequest struct {
+	URL         string   `json:"url" validate:"required,max=2048"`
+	Events      []string `json:"events" validate:"required"`
+	Description string   `json:"description" validate:"max=500"`
+	Active      *bool    `json:"active"`
+}
+
+type WebhookSubscriptionResponse struct {
+	ID          int       `json:"id"`
+	URL         string    `json:"url"`
+	Events      []string  `json:"events"`
+	Description string    `json:"description"`
+	Active      bool      `json:"active"`
+	Secret      string    `json:"secret,omitempty"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+type WebhookDeliveryResponse struct {
+	ID             int64                            `json:"id"`
+	SubscriptionID int                              `json:"subscription_id"`
+	EventID        string                           `json:"event_id"`
+	EventType      string                           `json:"event_type"`
+	Payload        json.RawMessage                  `json:"payload"`
+	Status         string                           `json:"status"`
+	Attempts       int                              `json:"attempts"`
+	NextAttemptAt  time.Time                        `json:"next_attempt_at"`
+	LastStatusCode *int                             `json:"last_status_code,omitempty"`
+	LastError      string                           `json:"last_error,omitempty"`
+	CreatedAt      time.Time                        `json:"created_at"`
+	AttemptLog     []WebhookDeliveryAttemptResponse `json:"attempt_log,omitempty"`
+}
+
+type WebhookDeliveryAttemptResponse struct {
+	StatusCode *int      `json:"status_code,omitempty"`
+	Error      string    `json:"error,omitempty"`
+	DurationMs int64     `json:"duration_ms"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+var webhookEventTypes = map[string]bool{
+	constants.WebhookUserSignedUp:      true,
+	constants.WebhookUserEmailVerified: true,
+	constants.WebhookUserUpdated:       true,
+	constants.WebhookUserDeleted:       true,
+	constants.WebhookUserDisabled:      true,
+	constants.WebhookUserEnabled:       true,
+	constants.WebhookSessionCreated:    true,
+	constants.WebhookSessionRevoked:    true,
+	constants.WebhookAllEvents:         true,
+}
+
+// handler list webhook subscriptions `GET /api/v1/admin/webhooks`
+func (s *Server) AdminListWebhooks(w http.ResponseWriter, r *http.Request) {
+	subscriptions, err := s.Webhooks.ListSubscriptions(r.Context())
+	if err != nil {
+		logError(r, "Failed to get webhooks", err)
+		respondError(w, r, http.StatusInternalServerError, constants.ErrorInternal, "Failed to get webhooks")
+		return
+	}
+
+	responses := make([]WebhookSubscriptionResponse, 0, len(subscriptions))
+	for i := range subscriptions {
+		responses = append(responses, toWebhookSubscriptionResponse(&subscriptions[i], false))
+	}
+
+	respondSuccess(w, "Webhooks retrieved", responses)
+}
+
+// handler create webhook subscription `POST /api/v1/admin/webhooks`
+func (s *Server) AdminCreateWebhook(w http.ResponseWriter, r *http.Request) {
+	var req WebhookSubscriptionRequest
+
+	if !decodeRequest(w, r, &req) {
+		return
+	}
+
+	secret, err := generateWebhookSecret()
+	if err != nil {
+		logError(r, "Failed to create webhook", err)
+		respondError(w, r, http.StatusInternalServerError, constants.ErrorInternal, "Failed to create webhook")
+		return
+	}
+
+	sub, err := s.Webhooks.CreateSubscription(r.Context(), req.URL, secret, req.Events, req.Description)
+	if err != nil {
+		logError(r, "Failed to create webhook", err)
+		respondError(w, r, http.StatusInternalServerError, constants.ErrorInternal, "Failed to create webhook")
+		return
+	}
+
+	s.recordWebhookChange(r, "create", sub.ID)
+
+	// secret is only shown once, right after creation
+	respondSuccess(w, "Webhook created", toWebhookSubscriptionResponse(sub, true))
+}
+
+// handler update webhook subscription `PUT /api/v1/admin/webhooks/{id}`
+func (s *Server) AdminUpdateWebhook(w http.ResponseWriter, r *http.Request) {
+	sub, ok := s.webhookSubscriptionFromRequest(w, r)
+	if !ok {
+		return
+	}
+
+	var req WebhookSubscriptionRequest
+
+	if !decodeRequest(w, r, &req) {
+		return
+	}
+
+	sub.URL = req.URL
+	sub.Events = req.Events
+	sub.Description = req.Description
+
+	if req.Active != nil {
+		sub.Active = *req.Active
+	}
+
+	if err := s.Webhooks.UpdateSubscription(r.Context(), sub); err != nil {
+		logError(r, "Failed to update webhook", err)
+		respondError(w, r, http.StatusInternalServerError, constants.ErrorInternal, "Failed to update webhook")
+		return
+	}
+
+	s.recordWebhookChange(r, "update", sub.ID)
+
+	respondSuccess(w, "Webhook updated", toWebhookSubscriptionResponse(sub, false))
+}
+
+// handler delete webhook subscription `DELETE /api/v1/admin/webhooks/{id}`
+func (s *Server) AdminDeleteWebhook(w http.ResponseWriter, r *http.Request) {
+	sub, ok := s.webhookSubscriptionFromRequest(w, r)
+	if !ok {
+		return
+	}
+
+	if err := s.Webhooks.DeleteSubscription(r.Context(), sub.ID); err != nil {
+		logError(r, "Failed to delete webhook", err)
+		respondError(w, r, http.StatusInternalServerError, constants.ErrorInternal, "Failed to delete webhook")
+		return
+	}
+
+	s.recordWebhookChange(r, "delete", sub.ID)
+
+	respondSuccess(w, "Webhook deleted", nil)
+}
+
+// handler delivery log `GET /api/v1/admin/webhooks/deliveries`, filtered by subscription_id, status
+// and limit. `GET /api/v1/admin/webhooks/deliveries/{id}` returns one delivery with every attempt
+func (s *Server) AdminWebhookDeliveries(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	if idParam(r) != "" {
+		delivery, ok := s.webhookDeliveryFromRequest(w, r)
+		if !ok {
+			return
+		}
+
+		attempts, err := s.Webhooks.ListAttempts(r.Context(), delivery.ID)
+		if err != nil {
+			logError(r, "Failed to get delivery attempts", err)
+			respondError(w, r, http.StatusInternalServerError, constants.ErrorInternal, "Failed to get delivery attempts")
+			return
+		}
+
+		response := toWebhookDeliveryResponse(delivery)
+		for _, attempt := range attempts {
+			response.AttemptLog = append(response.AttemptLog, WebhookDeliveryAttemptResponse{
+				StatusCode: attempt.StatusCode,
+				Error:      attempt.Error,
+				DurationMs: attempt.Duration.Milliseconds(),
+				CreatedAt:  attempt.CreatedAt,
+			})
+		}
+
+		respondSuccess(w, "Delivery retrieved", response)
+		return
+	}
+
+	filter := models.WebhookDeliveryFilter{Status: query.Get("status")}
+
+	var err error
+
+	if filter.SubscriptionID, err = parseIntParam(query.Get("subscription_id"), 0); err != nil {
+		respondError(w, r, http.StatusBadRequest, constants.ErrorInvalidRequest, "Invalid subscription_id")
+		return
+	}
+
+	if filter.Limit, err = parseIntParam(query.Get("limit"), 100); err != nil {
+		respondError(w, r, http.StatusBadRequest, constants.ErrorInvalidRequest, "Invalid limit")
+		return
+	}
+
+	deliveries, err := s.Webhooks.ListDeliveries(r.Context(), filter)
+	if err != nil {
+		logError(r, "Failed to get deliveries", err)
+		respondError(w, r, http.StatusInternalServerError, constants.ErrorInternal, "Failed to get deliveries")
+		return
+	}
+
+	responses := make([]WebhookDeliveryResponse, 0, len(deliveries))
+	for i := range deliveries {
+		responses = append(responses, toWebhookDeliveryResponse(&deliveries[i]))
+	}
+
+	respondSuccess(w, "Deliveries retrieved", responses)
+}
+
+// handler manual redelivery `POST /api/v1/admin/webhooks/deliveries/{id}/redeliver`
+func (s *Server) AdminRedeliverWebhook(w http.ResponseWriter, r *http.Request) {
+	delivery, ok := s.webhookDeliveryFromRequest(w, r)
+	if !ok {
+		return
+	}
+
+	if err := s.Webhooks.Redeliver(r.Context(), delivery.ID); err != nil {
+		logError(r, "Failed to schedule redelivery", err)
+		respondError(w, r, http.StatusInternalServerError, constants.ErrorInternal, "Failed to schedule redelivery")
+		return
+	}
+
+	actor := GetUserFromCtx(r)
+	s.recordAudit(r, models.AuditEvent{
+		EventType:   constants.EventWebhookChange,
+		Outcome:     constants.OutcomeSuccess,
+		ActorUserID: intPtr(actor.ID),
+		Details:     map[string]interface{}{"action": "redeliver", "subscription_id": delivery.SubscriptionID, "delivery_id": delivery.ID},
+	})
+
+	respondSuccess(w, "Redelivery scheduled", nil)
+}
+
+// handle url must be absolute http(s) and every event known
+func (req *WebhookSubscriptionRequest) Validate() []FieldError {
+	var fieldErrors []FieldError
+
+	parsed, err := url.Parse(req.URL)
+	if req.URL != "" && (err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "") {
+		fieldErrors = append(fieldErrors, FieldError{Field: "url", Code: "invalid_url", Message: "URL must be an absolute http or https URL"})
+	}
+
+	for _, event := range req.Events {
+		if !webhookEventTypes[event] {
+			fieldErrors = append(fieldErrors, FieldError{Field: "events", Code: "unknown_event", Message: "Unknown event " + event})
+		}
+	}
+
+	return fieldErrors
+}
+
+func (s *Server) webhookSubscriptionFromRequest(w http.ResponseWriter, r *http.Request) (*models.WebhookSubscription, bool) {
+	id, err := strconv.Atoi(idParam(r))
+	if err != nil {
+		respondError(w, r, http.StatusBadRequest, constants.ErrorInvalidRequest, "Invalid id")
+		return nil, false
+	}
+
+	sub, err := s.Webhooks.GetSubscription(r.Context(), id)
+	if err != nil {
+		logError(r, "Failed to get webhook", err)
+		respondError(w, r, http.StatusInternalServerError, constants.ErrorInternal, "Failed to get webhook")
+		return nil, false
+	}
+
+	if sub == nil {
+		respondError(w, r, http.StatusNotFound, constants.ErrorWebhookNotFound, "Webhook not found")
+		return nil, false
+	}
+
+	return sub, true
+}
+
+func (s *Server) webhookDeliveryFromRequest(w http.ResponseWriter, r *http.Request) (*models.WebhookDelivery, bool) {
+	id, err := strconv.ParseInt(idParam(r), 10, 64)
+	if err != nil {
+		respondError(w, r, http.StatusBadRequest, constants.ErrorInvalidRequest, "Invalid id")
+		return nil, false
+	}
+
+	delivery, err := s.Webhooks.GetDelivery(r.Context(), id)
+	if err != nil {
+		logError(r, "Failed to get delivery", err)
+		respondError(w, r, http.StatusInternalServerError, constants.ErrorInternal, "Failed to get delivery")
+		return nil, false
+	}
+
+	if delivery == nil {
+		respondError(w, r, http.StatusNotFound, constants.ErrorDeliveryNotFound, "Delivery not found")
+		return nil, false
+	}
+
+	return delivery, true
+}
+
+func (s *Server) recordWebhookChange(r *http.Request, action string, subscriptionID int) {
+	actor := GetUserFromCtx(r)
+	s.recordAudit(r, models.AuditEvent{
+		EventType:   constants.EventWebhookChange,
+		Outcome:     constants.OutcomeSuccess,
+		ActorUserID: intPtr(actor.ID),
+		Details:     map[string]interface{}{"action": action, "subscription_id": subscriptionID},
+	})
+}
+
+func generateWebhookSecret() (string, error) {
+	bytes := make([]byte, 32)
+
+	if _, err := rand.Read(bytes); err != nil {
+		return "", err
+	}
+
+	return "whsec_" + hex.EncodeToString(bytes), nil
+}
+
+func toWebhookSubscriptionResponse(sub *models.WebhookSubscription, withSecret bool) WebhookSubscriptionResponse {
+	response := WebhookSubscriptionResponse{
+		ID:          sub.ID,
+		URL:         sub.URL,
+		Events:      sub.Events,
+		Description: sub.Description,
+		Active:      sub.Active,
+		CreatedAt:   sub.CreatedAt,
+		UpdatedAt:   sub.UpdatedAt,
+	}
+
+	if withSecret {
+		response.Secret = sub.Secret
+	}
+
+	return response
+}
+
+func toWebhookDeliveryResponse(delivery *models.WebhookDelivery) WebhookDeliveryResponse {
+	return WebhookDeliveryResponse{
+		ID:             delivery.ID,
+		SubscriptionID: delivery.SubscriptionID,
+		EventID:        delivery.EventID,
+		EventType:      delivery.EventType,
+		Payload:        json.RawMessage(delivery.Payload),
+		Status:         delivery.Status,
+		Attempts:       delivery.Attempts,
+		NextAttemptAt:  delivery.NextAttemptAt,
+		LastStatusCode: delivery.LastStatusCode,
+		LastError:      delivery.LastError,
+		CreatedAt:      delivery.CreatedAt,
+	}
+}
@@ -0,0 +1,87 @@
+package certs
+
+import (
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// kinds of certificate identity an IdentityMap entry can name
+var identityKinds = []string{"uri", "dns", "email", "cn"}
+
+// IdentityMap maps client certificate identities like `uri:spiffe://prod/billing`
+// or `cn:billing-service` to the email of the user or service account they act as
+type IdentityMap map[string]string
+
+// handle parse `kind:value=email` entries, kind is uri, dns, email or cn. The value
+// may contain `=` itself, the email is what follows the last one
+func ParseIdentities(entries []string) (IdentityMap, error) {
+	identities := IdentityMap{}
+
+	for _, entry := range entries {
+		separator := strings.LastIndex(entry, "=")
+		if separator < 0 {
+			return nil, fmt.Errorf("client identity %q must look like kind:value=email", entry)
+		}
+
+		identity, email := strings.TrimSpace(entry[:separator]), strings.TrimSpace(entry[separator+1:])
+		kind, value, found := strings.Cut(identity, ":")
+
+		if !found || value == "" || !validKind(kind) {
+			return nil, fmt.Errorf("client identity %q must start with uri:, dns:, email: or cn:", entry)
+		}
+
+		if !strings.Contains(email, "@") {
+			return nil, fmt.Errorf("client identity %q must map to a user email", entry)
+		}
+
+		identities[kind+":"+value] = email
+	}
+
+	return identities, nil
+}
+
+func validKind(kind string) bool {
+	for _, known := range identityKinds {
+		if kind == known {
+			return true
+		}
+	}
+	return false
+}
+
+// handle email of the user the certificate acts as, SANs are checked before the
+// subject common name and the first mapped identity wins
+func (m IdentityMap) Lookup(cert *x509.Certificate) (string, bool) {
+	var candidates []string
+
+	for _, uri := range cert.URIs {
+		candidates = append(candidates, "uri:"+uri.String())
+	}
+	for _, name := range cert.DNSNames {
+		candidates = append(candidates, "dns:"+name)
+	}
+	for _, address := range cert.EmailAddresses {
+		candidates = append(candidates, "email:"+address)
+	}
+	if cert.Subject.CommonName != "" {
+		candidates = append(candidates, "cn:"+cert.Subject.CommonName)
+	}
+
+	for _, candidate := range candidates {
+		if email, ok := m[candidate]; ok {
+			return email, true
+		}
+	}
+
+	return "", false
+}
+
+// handle leaf certificate the client presented, only when it was verified against the client CAs
+func ClientCertificate(r *http.Request) *x509.Certificate {
+	if r.TLS == nil || len(r.TLS.VerifiedChains) == 0 || len(r.TLS.VerifiedChains[0]) == 0 {
+		return nil
+	}
+	return r.TLS.VerifiedChains[0][0]
+}
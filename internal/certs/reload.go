@@ -0,0 +1,158 @@
+package certs
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"log/slog"
+	"os"
+	"sync"
+	"time"
+)
+
+// Reloader serves the certificate of a cert and key file pair and swaps it when the
+// files change, so renewed certificates apply without a restart
+type Reloader struct {
+	certFile string
+	keyFile  string
+	// how often the files are checked for changes
+	Interval time.Duration
+
+	mu       sync.RWMutex
+	current  *tls.Certificate
+	modified time.Time
+}
+
+// handle load the pair once, a broken pair fails startup instead of the first handshake
+func NewReloader(certFile, keyFile string) (*Reloader, error) {
+	r := &Reloader{certFile: certFile, keyFile: keyFile, Interval: 10 * time.Second}
+
+	if err := r.Reload(); err != nil {
+		return nil, err
+	}
+
+	return r, nil
+}
+
+// handle tls.Config.GetCertificate returning the latest loaded pair
+func (r *Reloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.current, nil
+}
+
+// handle read the pair again, the previous certificate stays in use when it fails
+func (r *Reloader) Reload() error {
+	modified, err := r.lastModified()
+	if err != nil {
+		return err
+	}
+
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return fmt.Errorf("failed to load certificate %s: %w", r.certFile, err)
+	}
+
+	r.mu.Lock()
+	r.current = &cert
+	r.modified = modified
+	r.mu.Unlock()
+
+	return nil
+}
+
+// handle check the files every Interval and reload once either changed
+func (r *Reloader) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		modified, err := r.lastModified()
+		if err != nil {
+			slog.Error("Failed to check certificate files", "error", err)
+			continue
+		}
+
+		r.mu.RLock()
+		changed := !modified.Equal(r.modified)
+		r.mu.RUnlock()
+
+		if !changed {
+			continue
+		}
+
+		if err := r.Reload(); err != nil {
+			// a renewal may be half written, the next tick tries again
+			slog.Error("Failed to reload certificate", "error", err)
+			continue
+		}
+		slog.Info("Reloaded certificate", "cert_file", r.certFile)
+	}
+}
+
+// handle latest modification time of the cert and key file
+func (r *Reloader) lastModified() (time.Time, error) {
+	var latest time.Time
+
+	for _, path := range []string{r.certFile, r.keyFile} {
+		info, err := os.Stat(path)
+		if err != nil {
+			return time.Time{}, err
+		}
+
+		if info.ModTime().After(latest) {
+			latest = info.ModTime()
+		}
+	}
+
+	return latest, nil
+}
+
+// handle read PEM encoded CA certificates client certificates must chain to
+func LoadCertPool(path string) (*x509.CertPool, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(content) {
+		return nil, fmt.Errorf("no PEM certificates found in %s", path)
+	}
+
+	return pool, nil
+}
+
+// client certificate modes of ServerConfig
+const (
+	ClientAuthNone     = "none"
+	ClientAuthOptional = "optional"
+	ClientAuthRequire  = "require"
+)
+
+// handle TLS config serving the reloader's certificate. With optional client auth a client
+// may present a certificate signed by clientCAs, with require every connection must
+func ServerConfig(reloader *Reloader, clientCAs *x509.CertPool, clientAuth string) *tls.Config {
+	cfg := &tls.Config{
+		MinVersion:     tls.VersionTLS12,
+		GetCertificate: reloader.GetCertificate,
+	}
+
+	switch clientAuth {
+	case ClientAuthOptional:
+		cfg.ClientAuth = tls.VerifyClientCertIfGiven
+		cfg.ClientCAs = clientCAs
+	case ClientAuthRequire:
+		cfg.ClientAuth = tls.RequireAndVerifyClientCert
+		cfg.ClientCAs = clientCAs
+	}
+
+	return cfg
+}
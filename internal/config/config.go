@@ -1,104 +1,383 @@
 package config
 
 import (
-	"bufio"
 	"database/sql"
+	"encoding/base64"
 	"fmt"
-	"log"
+	"log/slog"
 	"os"
+	"strconv"
 	"strings"
+	"time"
+	"user-auth-go/internal/certs"
+	"user-auth-go/internal/cookies"
+	"user-auth-go/internal/dotenv"
+	"user-auth-go/internal/logging"
+	"user-auth-go/internal/mail"
+	"user-auth-go/internal/middleware"
+	"user-auth-go/internal/password"
 
 	_ "github.com/go-sql-driver/mysql"
+	_ "github.com/jackc/pgx/v5/stdlib"
 	"golang.org/x/oauth2"
 	"golang.org/x/oauth2/google"
+	_ "modernc.org/sqlite"
 )
 
 var DB *sql.DB
-var GoogleOAuthConfig *oauth2.Config
 
-func Init() {
-	loadEnvFile()
+// database backend: mysql, sqlite or postgres
+var DBDriver string
+
+// apply pending schema migrations when the server starts
+var DBAutoMigrate bool
+var SessionCookie *cookies.Manager
+
+// how long a new session stays valid
+var SessionLifetime = 24 * time.Hour
+var SecurityHeaders middleware.SecurityOptions
+var CORS middleware.CORSOptions
+
+// trust X-Forwarded-For / X-Real-IP, only enable behind a reverse proxy
+var TrustProxyHeaders bool
+
+// largest JSON body the API decodes
+var MaxBodyBytes int64 = 64 << 10
+var PasswordHasher *password.Manager
+var PasswordPolicy *password.Policy
+var Mailer mail.Mailer
+
+// public URL of the app, used to build links sent by email
+var AppBaseURL string
+
+// where outbox events are published: webhook, log and/or nats
+var OutboxSinks []string
+var NATSURL string
+var NATSSubjectPrefix string
+var NATSCredsFile string
+var NATSCAFile string
+var OutboxRetention = 7 * 24 * time.Hour
+
+// users that verified client certificates authenticate as, empty unless mutual TLS is on
+var ClientIdentities certs.IdentityMap
+
+// Current is the configuration the settings above were built from
+var Current *Config
+
+// handle load config and connect the database, exits on any problem
+// args are command line flags overriding the config file and environment
+func Init(args ...string) {
+	Load(args...)
 	initDB()
-	initGoogleOAuth()
 }
 
-func loadEnvFile() {
-	file, err := os.Open(".env")
+// handle load every setting except the database connection, exits on any problem
+func Load(args ...string) {
+	if err := Setup(args); err != nil {
+		slog.Error("Failed to load config", "error", err)
+		os.Exit(1)
+	}
+}
 
+// handle resolve, validate and apply config, callers decide how to fail
+func Setup(args []string) error {
+	cfg, err := Inspect(args)
 	if err != nil {
-		return
+		return err
 	}
 
-	defer file.Close()
+	loadedArgs = args
+	return Apply(cfg)
+}
+
+// handle read env files and resolve config without applying it,
+// the config is returned next to validation problems so it can still be printed
+func Inspect(args []string) (*Config, error) {
+	if err := LoadEnvFiles(); err != nil {
+		return nil, err
+	}
+	return Resolve(args)
+}
 
-	scanner := bufio.NewScanner(file)
+// handle build the package settings from cfg
+func Apply(cfg *Config) error {
+	if err := initLogger(cfg); err != nil {
+		return err
+	}
 
-	for scanner.Scan() {
-		line := scanner.Text()
+	initGoogleOAuth(cfg)
+	initSecurity(cfg)
+	initMailer(cfg)
+	initOutbox(cfg)
+	initClientIdentities(cfg)
 
-		if line == "" || strings.HasPrefix(line, "#") {
-			continue
-		}
+	SessionLifetime = time.Duration(cfg.Session.Lifetime) * time.Second
+	if err := initSessionCookie(cfg); err != nil {
+		return err
+	}
+
+	if err := initPasswordHasher(cfg); err != nil {
+		return err
+	}
 
-		parts := strings.SplitN(line, "=", 2)
+	if err := initPasswordPolicy(cfg); err != nil {
+		return err
+	}
 
-		if len(parts) == 2 {
-			key := strings.TrimSpace(parts[0])
-			val := strings.TrimSpace(parts[1])
+	Current = cfg
+	DBDriver = cfg.Database.Driver
+	DBAutoMigrate = cfg.Database.AutoMigrate
+	return nil
+}
 
-			os.Setenv(key, val)
-		}
+// handle load `.env`, `.env.$APP_ENV` then `.env.local`, later files win and the
+// real environment wins over all of them unless DOTENV_OVERRIDE is true.
+// APP_ENV itself is only read from the real environment
+func LoadEnvFiles() error {
+	files := []string{".env"}
+
+	if appEnv := os.Getenv("APP_ENV"); appEnv != "" {
+		files = append(files, ".env."+appEnv)
 	}
+	files = append(files, ".env.local")
 
+	override, _ := strconv.ParseBool(os.Getenv("DOTENV_OVERRIDE"))
+	return dotenv.Load(override, files...)
 }
 
 func initDB() {
-	var err error
+	if err := Connect(); err != nil {
+		slog.Error("Failed to connect DB", "driver", Current.Database.Driver, "error", err)
+		os.Exit(1)
+	}
 
-	dsn := fmt.Sprintf("%s:%s@tcp(%s:%s)/%s?parseTime=true",
-		getEnv("DB_USER", "root"),
-		getEnv("DB_PASS", ""),
-		getEnv("DB_HOST", "127.0.0.1"),
-		getEnv("DB_PORT", "3306"),
-		getEnv("DB_NAME", "user_auth_db"),
-	)
+	slog.Info("DB connected", "driver", Current.Database.Driver)
+}
 
-	DB, err = sql.Open("mysql", dsn)
+// handle open and ping the configured database, callers decide how to fail
+func Connect() error {
+	if Current == nil {
+		return fmt.Errorf("config is not loaded")
+	}
 
+	db, err := OpenDB(Current.Database.Driver, Current.Database.ConnectionString())
 	if err != nil {
-		log.Fatalf("Failed to connect DB: %s", err)
+		return err
+	}
+
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return err
 	}
 
-	err = DB.Ping()
+	DB = db
+	return nil
+}
 
-	if err != nil {
-		log.Fatalf("Failed to ping DB: %s", err)
+// handle open connection pool for one of the supported drivers
+func OpenDB(driver, dsn string) (*sql.DB, error) {
+	switch driver {
+	case "mysql":
+		return sql.Open("mysql", dsn)
+	case "postgres":
+		return sql.Open("pgx", dsn)
+	case "sqlite":
+		db, err := sql.Open("sqlite", dsn)
+		if err != nil {
+			return nil, err
+		}
+
+		// sqlite allows one writer at a time, a single connection avoids busy errors
+		db.SetMaxOpenConns(1)
+		return db, nil
 	}
 
-	fmt.Println("DB connected!")
+	return nil, fmt.Errorf("unknown DB_DRIVER %q, expected mysql, sqlite or postgres", driver)
+}
+
+// handle sqlite file DSN, foreign keys are off unless enabled per connection
+func SQLiteDSN(path string) string {
+	return "file:" + path + "?_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)&_time_format=sqlite"
 }
 
-func initGoogleOAuth() {
-	GoogleOAuthConfig = &oauth2.Config{
-		ClientID:     getEnv("GOOGLE_CLIENT_ID", ""),
-		ClientSecret: getEnv("GOOGLE_CLIENT_SECRET", ""),
-		RedirectURL:  getEnv("GOOGLE_REDIRECT_URL", "http://localhost:8080/auth/google/callback"),
+// handle google login is off when GoogleOAuth returns nil
+func initGoogleOAuth(cfg *Config) {
+	if !cfg.Google.Enabled {
+		googleOAuth.Store(nil)
+		return
+	}
+
+	googleOAuth.Store(&oauth2.Config{
+		ClientID:     cfg.Google.ClientID,
+		ClientSecret: cfg.Google.ClientSecret,
+		RedirectURL:  cfg.Google.RedirectURL,
 		Scopes: []string{
 			"https://www.googleapis.com/auth/userinfo.email",
 			"https://www.googleapis.com/auth/userinfo.profile",
 		},
-		Endpoint: google.Endpoint}
+		Endpoint: google.Endpoint})
+}
+
+func initSessionCookie(cfg *Config) error {
+	sameSite, err := cookies.ParseSameSite(cfg.Session.CookieSameSite)
 
-	fmt.Println("Google OAuth configured!")
+	if err != nil {
+		return fmt.Errorf("invalid session cookie config: %w", err)
+	}
+
+	var sealKey []byte
 
+	if secret := cfg.Session.CookieSecret; secret != "" {
+		sealKey, err = base64.StdEncoding.DecodeString(secret)
+
+		if err != nil {
+			return fmt.Errorf("invalid SESSION_COOKIE_SECRET, expected base64: %w", err)
+		}
+	}
+
+	SessionCookie, err = cookies.New(cookies.Options{
+		Name:       cfg.Session.CookieName,
+		Domain:     cfg.Session.CookieDomain,
+		Path:       cfg.Session.CookiePath,
+		Secure:     cfg.Session.CookieSecure,
+		SameSite:   sameSite,
+		HostPrefix: cfg.Session.CookieHostPrefix,
+		MaxAge:     cfg.Session.Lifetime,
+		SealKey:    sealKey,
+	})
+
+	if err != nil {
+		return fmt.Errorf("invalid session cookie config: %w", err)
+	}
+
+	return nil
+}
+
+func initSecurity(cfg *Config) {
+	SecurityHeaders = middleware.SecurityOptions{
+		ContentSecurityPolicy: cfg.Security.CSP,
+		HSTSMaxAge:            cfg.Security.HSTSMaxAge,
+		HSTSIncludeSubdomains: cfg.Security.HSTSIncludeSubdomains,
+		HSTSPreload:           cfg.Security.HSTSPreload,
+		FrameOptions:          cfg.Security.FrameOptions,
+		ReferrerPolicy:        cfg.Security.ReferrerPolicy,
+		PermissionsPolicy:     cfg.Security.PermissionsPolicy,
+	}
+
+	TrustProxyHeaders = cfg.Server.TrustProxyHeaders
+	MaxBodyBytes = int64(cfg.Server.MaxBodyBytes)
+
+	CORS = middleware.CORSOptions{
+		AllowedOrigins:   cfg.CORS.AllowedOrigins,
+		AllowedMethods:   cfg.CORS.AllowedMethods,
+		AllowedHeaders:   cfg.CORS.AllowedHeaders,
+		AllowCredentials: cfg.CORS.AllowCredentials,
+		MaxAge:           cfg.CORS.MaxAge,
+	}
+}
+
+func initPasswordHasher(cfg *Config) error {
+	var preferred password.Hasher
+
+	switch algorithm := cfg.Password.HashAlgorithm; algorithm {
+	case "argon2id":
+		argon := password.DefaultArgon2id()
+		argon.Memory = uint32(cfg.Password.Argon2Memory)
+		argon.Iterations = uint32(cfg.Password.Argon2Iterations)
+		argon.Parallelism = uint8(cfg.Password.Argon2Parallelism)
+		preferred = argon
+	case "bcrypt":
+		preferred = &password.Bcrypt{Cost: cfg.Password.BcryptCost}
+	default:
+		return fmt.Errorf("unknown PASSWORD_HASH_ALGORITHM %q, expected argon2id or bcrypt", algorithm)
+	}
+
+	var err error
+	PasswordHasher, err = password.NewManager(preferred)
+
+	if err != nil {
+		return fmt.Errorf("failed to configure password hasher: %w", err)
+	}
+
+	return nil
 }
 
-func getEnv(key string, defaultVal string) string {
-	val := os.Getenv(key)
+func initPasswordPolicy(cfg *Config) error {
+	PasswordPolicy = &password.Policy{
+		MinLength:     cfg.Password.MinLength,
+		MaxLength:     cfg.Password.MaxLength,
+		RequireUpper:  cfg.Password.RequireUpper,
+		RequireLower:  cfg.Password.RequireLower,
+		RequireDigit:  cfg.Password.RequireDigit,
+		RequireSymbol: cfg.Password.RequireSymbol,
+		MinStrength:   cfg.Password.MinStrength,
+	}
 
-	if val == "" {
-		return defaultVal
+	if !cfg.Password.BreachedCheck {
+		return nil
 	}
 
-	return val
+	PasswordPolicy.Breached = password.BundledBreachedList()
+
+	if path := cfg.Password.BreachedFile; path != "" {
+		if err := PasswordPolicy.Breached.LoadFile(path); err != nil {
+			return fmt.Errorf("failed to load breached passwords: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// handle make slog's default logger the configured one, the `log` package writes through it too
+func initLogger(cfg *Config) error {
+	logger, err := logging.New(os.Stderr, cfg.Log.Format, cfg.Log.Level)
+	if err != nil {
+		return err
+	}
+
+	slog.SetDefault(logger)
+	return nil
+}
+
+func initMailer(cfg *Config) {
+	AppBaseURL = strings.TrimRight(cfg.Server.BaseURL, "/")
+
+	if cfg.Mail.Driver == "smtp" {
+		mailer := &reloadableMailer{}
+		mailer.current.Store(smtpMailer(cfg))
+		Mailer = mailer
+		return
+	}
+
+	Mailer = &mail.LogMailer{}
+}
+
+func smtpMailer(cfg *Config) *mail.SMTPMailer {
+	return &mail.SMTPMailer{
+		Host:     cfg.Mail.SMTPHost,
+		Port:     cfg.Mail.SMTPPort,
+		Username: cfg.Mail.SMTPUser,
+		Password: cfg.Mail.SMTPPass,
+		From:     cfg.Mail.From,
+	}
+}
+
+// handle identities are only honored when client certificates are verified
+func initClientIdentities(cfg *Config) {
+	ClientIdentities = nil
+	if cfg.TLS.ClientAuth == certs.ClientAuthNone {
+		return
+	}
+
+	// checked by Validate
+	ClientIdentities, _ = certs.ParseIdentities(cfg.TLS.ClientIdentities)
+}
+
+func initOutbox(cfg *Config) {
+	OutboxSinks = cfg.Outbox.Sinks
+	NATSURL = cfg.Outbox.NATSURL
+	NATSSubjectPrefix = cfg.Outbox.NATSSubjectPrefix
+	NATSCredsFile = cfg.Outbox.NATSCredsFile
+	NATSCAFile = cfg.Outbox.NATSCAFile
+	OutboxRetention = time.Duration(cfg.Outbox.Retention) * time.Second
 }
@@ -0,0 +1,274 @@
+package config
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// redacted replaces secret values when the config is printed
+const redacted = "******"
+
+// setting is one leaf field of Config with the names it is known by in every source
+type setting struct {
+	path   string // yaml path, `database.driver`
+	env    string
+	secret bool
+	value  reflect.Value
+}
+
+// handle flag name of the setting, `database.auto_migrate` is `database-auto-migrate`
+func (s setting) flagName() string {
+	return strings.NewReplacer(".", "-", "_", "-").Replace(s.path)
+}
+
+// handle walk Config and list every leaf field
+func settings(cfg *Config) []setting {
+	var list []setting
+	collectSettings(reflect.ValueOf(cfg).Elem(), "", &list)
+	return list
+}
+
+func collectSettings(value reflect.Value, prefix string, list *[]setting) {
+	for i := 0; i < value.NumField(); i++ {
+		field := value.Type().Field(i)
+		path := prefix + field.Tag.Get("yaml")
+
+		if field.Type.Kind() == reflect.Struct {
+			collectSettings(value.Field(i), path+".", list)
+			continue
+		}
+
+		*list = append(*list, setting{
+			path:   path,
+			env:    field.Tag.Get("env"),
+			secret: field.Tag.Get("secret") == "true",
+			value:  value.Field(i),
+		})
+	}
+}
+
+// handle parse a raw string into the field, lists are comma separated and drop blank items
+func (s setting) set(raw string) error {
+	switch s.value.Kind() {
+	case reflect.String:
+		s.value.SetString(raw)
+	case reflect.Bool:
+		parsed, err := strconv.ParseBool(raw)
+		if err != nil {
+			return fmt.Errorf("expected true or false, got %q", raw)
+		}
+		s.value.SetBool(parsed)
+	case reflect.Int:
+		parsed, err := strconv.Atoi(raw)
+		if err != nil {
+			return fmt.Errorf("expected an integer, got %q", raw)
+		}
+		s.value.SetInt(int64(parsed))
+	case reflect.Float64:
+		parsed, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return fmt.Errorf("expected a number, got %q", raw)
+		}
+		s.value.SetFloat(parsed)
+	case reflect.Slice:
+		var list []string
+		for _, item := range strings.Split(raw, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				list = append(list, item)
+			}
+		}
+		s.value.Set(reflect.ValueOf(list))
+	default:
+		return fmt.Errorf("unsupported setting type %s", s.value.Type())
+	}
+	return nil
+}
+
+// rawFlag keeps the flag value as given so it can be applied after the file and env
+type rawFlag struct {
+	value  string
+	isBool bool
+}
+
+func (f *rawFlag) String() string { return f.value }
+
+func (f *rawFlag) Set(value string) error {
+	f.value = value
+	return nil
+}
+
+func (f *rawFlag) IsBoolFlag() bool { return f.isBool }
+
+// handle build the config from defaults, the config file, secret providers, the environment
+// and flags, in that order of precedence, every problem found is reported in one *ValidationError
+func Resolve(args []string) (*Config, error) {
+	cfg := Default()
+	list := settings(cfg)
+
+	flags := flag.NewFlagSet("config", flag.ContinueOnError)
+	flags.SetOutput(io.Discard)
+
+	configFile := flags.String("config", os.Getenv("CONFIG_FILE"), "YAML or TOML config file")
+	raw := map[string]*rawFlag{}
+
+	for _, s := range list {
+		raw[s.flagName()] = &rawFlag{isBool: s.value.Kind() == reflect.Bool}
+		flags.Var(raw[s.flagName()], s.flagName(), "overrides "+s.env)
+	}
+
+	if err := flags.Parse(args); err != nil {
+		return nil, err
+	}
+
+	if flags.NArg() > 0 {
+		return nil, fmt.Errorf("unexpected argument %q", flags.Arg(0))
+	}
+
+	if *configFile != "" {
+		if err := decodeFile(*configFile, cfg); err != nil {
+			return nil, err
+		}
+	}
+
+	problems := &ValidationError{}
+	// settings given by env or flag, secret providers leave them alone
+	explicit := map[string]bool{}
+
+	for _, s := range list {
+		// empty variables count as unset so a blank `.env` entry keeps the default
+		value := os.Getenv(s.env)
+		if s.env == "" || value == "" {
+			continue
+		}
+
+		if s.secret && os.Getenv(s.env+"_FILE") != "" {
+			problems.add("set either %s or %s_FILE", s.env, s.env)
+		}
+
+		if err := s.set(value); err != nil {
+			problems.add("%s: %s", s.env, err)
+		}
+		explicit[s.path] = true
+	}
+
+	flags.Visit(func(f *flag.Flag) {
+		for _, s := range list {
+			if s.flagName() == f.Name {
+				if err := s.set(raw[f.Name].value); err != nil {
+					problems.add("--%s: %s", f.Name, err)
+				}
+				explicit[s.path] = true
+			}
+		}
+	})
+
+	resolveSecrets(cfg, list, explicit, problems)
+
+	if err := cfg.Validate(); err != nil {
+		problems.Problems = append(problems.Problems, err.(*ValidationError).Problems...)
+	}
+
+	if len(problems.Problems) > 0 {
+		return cfg, problems
+	}
+
+	return cfg, nil
+}
+
+// handle decode the config file on top of cfg, unknown keys are rejected to catch typos
+func decodeFile(path string, cfg *Config) error {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("config file: %w", err)
+	}
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		decoder := yaml.NewDecoder(bytes.NewReader(content))
+		decoder.KnownFields(true)
+
+		if err := decoder.Decode(cfg); err != nil && err != io.EOF {
+			return fmt.Errorf("config file %s: %w", path, err)
+		}
+
+	case ".toml":
+		meta, err := toml.Decode(string(content), cfg)
+		if err != nil {
+			return fmt.Errorf("config file %s: %w", path, err)
+		}
+
+		if undecoded := meta.Undecoded(); len(undecoded) > 0 {
+			return fmt.Errorf("config file %s: unknown setting %s", path, undecoded[0])
+		}
+
+	default:
+		return fmt.Errorf("config file %s: expected a .yaml, .yml or .toml extension", path)
+	}
+
+	return nil
+}
+
+// handle env names of the settings secret providers can fill
+func SecretNames() []string {
+	var names []string
+
+	for _, s := range settings(Default()) {
+		if s.secret {
+			names = append(names, s.env)
+		}
+	}
+
+	return names
+}
+
+// handle copy of the config with secrets masked, safe to print or log
+func (c *Config) Redacted() *Config {
+	copied := *c
+
+	for _, s := range settings(&copied) {
+		if s.secret && s.value.String() != "" {
+			s.value.SetString(redacted)
+		}
+	}
+
+	return &copied
+}
+
+// handle render the config as `ENV_NAME=value` lines in field order
+func (c *Config) Env() string {
+	var builder strings.Builder
+
+	for _, s := range settings(c) {
+		value := fmt.Sprint(s.value.Interface())
+		if list, ok := s.value.Interface().([]string); ok {
+			value = strings.Join(list, ",")
+		}
+		builder.WriteString(s.env + "=" + value + "\n")
+	}
+
+	return builder.String()
+}
+
+// handle render the config as YAML
+func (c *Config) YAML() (string, error) {
+	var buffer bytes.Buffer
+
+	encoder := yaml.NewEncoder(&buffer)
+	encoder.SetIndent(2)
+
+	if err := encoder.Encode(c); err != nil {
+		return "", err
+	}
+
+	return buffer.String(), encoder.Close()
+}
@@ -0,0 +1,256 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+	"user-auth-go/internal/mail"
+	"user-auth-go/internal/secrets"
+
+	"golang.org/x/oauth2"
+)
+
+// SecretProvider resolves `secret` settings from a source other than plain env vars.
+// Providers are asked in order, before env vars and flags which still win over them
+type SecretProvider interface {
+	// Name shows up in errors
+	Name() string
+	// Secret returns the value for the env name of a setting, like GOOGLE_CLIENT_SECRET
+	Secret(env string) (value string, ok bool, err error)
+}
+
+var (
+	providersMu         sync.Mutex
+	registeredProviders []SecretProvider
+)
+
+// handle add a provider asked after the built in `*_FILE` and encrypted file providers
+func RegisterSecretProvider(provider SecretProvider) {
+	providersMu.Lock()
+	defer providersMu.Unlock()
+
+	registeredProviders = append(registeredProviders, provider)
+}
+
+// FileEnvProvider reads `<NAME>_FILE` env vars, the way Docker and Kubernetes mount secrets
+type FileEnvProvider struct{}
+
+func (FileEnvProvider) Name() string { return "*_FILE" }
+
+func (FileEnvProvider) Secret(env string) (string, bool, error) {
+	path := os.Getenv(env + "_FILE")
+	if path == "" {
+		return "", false, nil
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return "", false, err
+	}
+
+	// editors and `echo` leave a trailing newline that is never part of the secret
+	return strings.TrimRight(string(content), "\r\n"), true, nil
+}
+
+// EncryptedFileProvider reads secrets sealed with the master key, see `admin secrets`
+type EncryptedFileProvider struct {
+	Path string
+	Key  []byte
+
+	once   sync.Once
+	values map[string]string
+	err    error
+}
+
+func (p *EncryptedFileProvider) Name() string { return p.Path }
+
+func (p *EncryptedFileProvider) Secret(env string) (string, bool, error) {
+	p.once.Do(func() {
+		p.values, p.err = secrets.ReadFile(p.Path, p.Key)
+	})
+
+	if p.err != nil {
+		return "", false, p.err
+	}
+
+	value, ok := p.values[env]
+	return value, ok, nil
+}
+
+// handle master key from SECRETS_MASTER_KEY or the file in SECRETS_MASTER_KEY_FILE,
+// it is never read from the config file so it cannot end up next to the secrets
+func SecretsMasterKey() ([]byte, error) {
+	encoded := os.Getenv("SECRETS_MASTER_KEY")
+
+	if path := os.Getenv("SECRETS_MASTER_KEY_FILE"); path != "" {
+		if encoded != "" {
+			return nil, fmt.Errorf("set either SECRETS_MASTER_KEY or SECRETS_MASTER_KEY_FILE")
+		}
+
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		encoded = string(content)
+	}
+
+	if encoded == "" {
+		return nil, fmt.Errorf("SECRETS_MASTER_KEY is required to read %s", os.Getenv("SECRETS_FILE"))
+	}
+
+	return secrets.ParseKey(encoded)
+}
+
+// handle providers for one resolve, fresh ones so reloads read files again
+func secretProviders(cfg *Config) ([]SecretProvider, error) {
+	providers := []SecretProvider{FileEnvProvider{}}
+
+	if cfg.Secrets.File != "" {
+		key, err := SecretsMasterKey()
+		if err != nil {
+			return nil, err
+		}
+		providers = append(providers, &EncryptedFileProvider{Path: cfg.Secrets.File, Key: key})
+	}
+
+	providersMu.Lock()
+	defer providersMu.Unlock()
+
+	return append(providers, registeredProviders...), nil
+}
+
+// handle fill secret settings not set by env or flags from the providers
+func resolveSecrets(cfg *Config, list []setting, explicit map[string]bool, problems *ValidationError) {
+	providers, err := secretProviders(cfg)
+	if err != nil {
+		problems.add("secrets: %s", err)
+		return
+	}
+
+	for _, s := range list {
+		if !s.secret || explicit[s.path] {
+			continue
+		}
+
+		for _, provider := range providers {
+			value, ok, err := provider.Secret(s.env)
+			if err != nil {
+				problems.add("%s from %s: %s", s.env, provider.Name(), err)
+				break
+			}
+			if ok {
+				s.value.SetString(value)
+				break
+			}
+		}
+	}
+}
+
+var googleOAuth atomic.Pointer[oauth2.Config]
+
+// handle Google OAuth client of the current config, nil when Google login is disabled
+func GoogleOAuth() *oauth2.Config {
+	return googleOAuth.Load()
+}
+
+// reloadableMailer lets rotated SMTP credentials apply to the next mails sent
+type reloadableMailer struct {
+	current atomic.Pointer[mail.SMTPMailer]
+}
+
+func (m *reloadableMailer) Send(msg mail.Message) error {
+	return m.current.Load().Send(msg)
+}
+
+// args the config was loaded with, reloads resolve it the same way
+var loadedArgs []string
+
+// handle resolve config again and apply secrets that changed. Google and SMTP credentials
+// take effect immediately, database credentials and the cookie secret are reported in
+// pending since they only apply on restart
+func ReloadSecrets() (applied, pending []string, err error) {
+	if Current == nil {
+		return nil, nil, fmt.Errorf("config is not loaded")
+	}
+
+	cfg, err := Resolve(loadedArgs)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	updated := *Current
+	previous := settings(Current)
+	next := settings(cfg)
+	target := settings(&updated)
+
+	for i, s := range previous {
+		if !s.secret || s.value.String() == next[i].value.String() {
+			continue
+		}
+
+		switch s.env {
+		case "GOOGLE_CLIENT_SECRET", "SMTP_PASS":
+			target[i].value.SetString(next[i].value.String())
+			applied = append(applied, s.env)
+		default:
+			pending = append(pending, s.env)
+		}
+	}
+
+	if len(applied) == 0 {
+		return applied, pending, nil
+	}
+
+	initGoogleOAuth(&updated)
+
+	if mailer, ok := Mailer.(*reloadableMailer); ok {
+		mailer.current.Store(smtpMailer(&updated))
+	}
+
+	Current = &updated
+	return applied, pending, nil
+}
+
+// handle reload secrets on SIGHUP and every secrets.reload_interval seconds until ctx is done
+func WatchSecrets(ctx context.Context) {
+	hangup := make(chan os.Signal, 1)
+	signal.Notify(hangup, syscall.SIGHUP)
+	defer signal.Stop(hangup)
+
+	var tick <-chan time.Time
+	if interval := Current.Secrets.ReloadInterval; interval > 0 {
+		ticker := time.NewTicker(time.Duration(interval) * time.Second)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-hangup:
+		case <-tick:
+		}
+
+		applied, pending, err := ReloadSecrets()
+		if err != nil {
+			slog.Error("Failed to reload secrets", "error", err)
+			continue
+		}
+
+		if len(applied) > 0 {
+			slog.Info("Reloaded secrets", "names", applied)
+		}
+
+		if len(pending) > 0 {
+			slog.Warn("Changed secrets apply on restart", "names", pending)
+		}
+	}
+}
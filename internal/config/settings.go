@@ -0,0 +1,524 @@
+package config
+
+import (
+	"encoding/base64"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"user-auth-go/internal/certs"
+	"user-auth-go/internal/cookies"
+	"user-auth-go/internal/logging"
+	"user-auth-go/internal/middleware"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Config holds every setting of the app. Values come from Default, then the config
+// file, then secret providers for `secret` fields, then the environment, then command
+// line flags, each source overriding the previous.
+// The `env` tag names the environment variable, flags are named after the yaml path
+// (`database.auto_migrate` is `--database-auto-migrate`), `secret` fields are redacted when printed
+type Config struct {
+	Server   ServerConfig   `yaml:"server" toml:"server" json:"server"`
+	TLS      TLSConfig      `yaml:"tls" toml:"tls" json:"tls"`
+	Log      LogConfig      `yaml:"log" toml:"log" json:"log"`
+	Metrics  MetricsConfig  `yaml:"metrics" toml:"metrics" json:"metrics"`
+	Tracing  TracingConfig  `yaml:"tracing" toml:"tracing" json:"tracing"`
+	Database DatabaseConfig `yaml:"database" toml:"database" json:"database"`
+	Google   GoogleConfig   `yaml:"google" toml:"google" json:"google"`
+	Session  SessionConfig  `yaml:"session" toml:"session" json:"session"`
+	Security SecurityConfig `yaml:"security" toml:"security" json:"security"`
+	CORS     CORSConfig     `yaml:"cors" toml:"cors" json:"cors"`
+	Password PasswordConfig `yaml:"password" toml:"password" json:"password"`
+	Mail     MailConfig     `yaml:"mail" toml:"mail" json:"mail"`
+	Outbox   OutboxConfig   `yaml:"outbox" toml:"outbox" json:"outbox"`
+	Secrets  SecretsConfig  `yaml:"secrets" toml:"secrets" json:"secrets"`
+}
+
+type ServerConfig struct {
+	Port string `yaml:"port" toml:"port" json:"port" env:"PORT"`
+	// public URL of the app, used to build links sent by email
+	BaseURL string `yaml:"base_url" toml:"base_url" json:"base_url" env:"APP_BASE_URL"`
+	// trust X-Forwarded-For / X-Real-IP, only enable behind a reverse proxy
+	TrustProxyHeaders bool `yaml:"trust_proxy_headers" toml:"trust_proxy_headers" json:"trust_proxy_headers" env:"TRUST_PROXY_HEADERS"`
+	// timeouts in seconds, they keep slow clients from holding connections open
+	ReadHeaderTimeout int `yaml:"read_header_timeout" toml:"read_header_timeout" json:"read_header_timeout" env:"SERVER_READ_HEADER_TIMEOUT"`
+	ReadTimeout       int `yaml:"read_timeout" toml:"read_timeout" json:"read_timeout" env:"SERVER_READ_TIMEOUT"`
+	WriteTimeout      int `yaml:"write_timeout" toml:"write_timeout" json:"write_timeout" env:"SERVER_WRITE_TIMEOUT"`
+	IdleTimeout       int `yaml:"idle_timeout" toml:"idle_timeout" json:"idle_timeout" env:"SERVER_IDLE_TIMEOUT"`
+	MaxHeaderBytes    int `yaml:"max_header_bytes" toml:"max_header_bytes" json:"max_header_bytes" env:"SERVER_MAX_HEADER_BYTES"`
+	// largest JSON body the API decodes, bigger requests get 413
+	MaxBodyBytes int `yaml:"max_body_bytes" toml:"max_body_bytes" json:"max_body_bytes" env:"SERVER_MAX_BODY_BYTES"`
+	// seconds /readyz fails before shutting down, so load balancers stop routing here first
+	DrainDelay int `yaml:"drain_delay" toml:"drain_delay" json:"drain_delay" env:"SHUTDOWN_DRAIN_DELAY"`
+	// seconds in-flight requests get to finish once shutdown starts, then connections are closed
+	ShutdownTimeout int `yaml:"shutdown_timeout" toml:"shutdown_timeout" json:"shutdown_timeout" env:"SHUTDOWN_TIMEOUT"`
+}
+
+// TLSConfig serves HTTPS when CertFile and KeyFile are set, the pair is reloaded when
+// the files change. ClientAuth optional or require turns on mutual TLS, verified client
+// certificates listed in ClientIdentities then authenticate as the mapped user
+type TLSConfig struct {
+	CertFile string `yaml:"cert_file" toml:"cert_file" json:"cert_file" env:"TLS_CERT_FILE"`
+	KeyFile  string `yaml:"key_file" toml:"key_file" json:"key_file" env:"TLS_KEY_FILE"`
+	// none, optional or require
+	ClientAuth string `yaml:"client_auth" toml:"client_auth" json:"client_auth" env:"TLS_CLIENT_AUTH"`
+	// PEM file of the CAs client certificates must chain to
+	ClientCAFile string `yaml:"client_ca_file" toml:"client_ca_file" json:"client_ca_file" env:"TLS_CLIENT_CA_FILE"`
+	// `kind:value=email` entries, kind is uri, dns, email or cn (`cn:billing-service=billing@example.com`)
+	ClientIdentities []string `yaml:"client_identities" toml:"client_identities" json:"client_identities" env:"TLS_CLIENT_IDENTITIES"`
+}
+
+type LogConfig struct {
+	// debug, info, warn or error
+	Level string `yaml:"level" toml:"level" json:"level" env:"LOG_LEVEL"`
+	// json or text
+	Format string `yaml:"format" toml:"format" json:"format" env:"LOG_FORMAT"`
+}
+
+// MetricsConfig exposes `/metrics` on its own listener when Addr is set, or on the
+// app port behind Token otherwise, it is not served at all when both are empty
+type MetricsConfig struct {
+	// admin listener like `127.0.0.1:9090`, keep it off the public network
+	Addr string `yaml:"addr" toml:"addr" json:"addr" env:"METRICS_ADDR"`
+	// bearer token scrapers must send, required on the app port
+	Token string `yaml:"token" toml:"token" json:"token" env:"METRICS_TOKEN" secret:"true"`
+}
+
+type TracingConfig struct {
+	// none, stdout or otlp
+	Exporter string `yaml:"exporter" toml:"exporter" json:"exporter" env:"TRACING_EXPORTER"`
+	// OTLP/HTTP endpoint like `http://localhost:4318`, empty falls back to OTEL_EXPORTER_OTLP_ENDPOINT
+	Endpoint    string `yaml:"endpoint" toml:"endpoint" json:"endpoint" env:"TRACING_OTLP_ENDPOINT"`
+	ServiceName string `yaml:"service_name" toml:"service_name" json:"service_name" env:"TRACING_SERVICE_NAME"`
+	// share of new traces kept, between 0 and 1
+	SampleRatio float64 `yaml:"sample_ratio" toml:"sample_ratio" json:"sample_ratio" env:"TRACING_SAMPLE_RATIO"`
+}
+
+type DatabaseConfig struct {
+	// mysql, sqlite or postgres
+	Driver string `yaml:"driver" toml:"driver" json:"driver" env:"DB_DRIVER"`
+	// full connection string, overrides the settings below
+	DSN     string `yaml:"dsn" toml:"dsn" json:"dsn" env:"DB_DSN" secret:"true"`
+	Host    string `yaml:"host" toml:"host" json:"host" env:"DB_HOST"`
+	Port    string `yaml:"port" toml:"port" json:"port" env:"DB_PORT"`
+	User    string `yaml:"user" toml:"user" json:"user" env:"DB_USER"`
+	Pass    string `yaml:"pass" toml:"pass" json:"pass" env:"DB_PASS" secret:"true"`
+	Name    string `yaml:"name" toml:"name" json:"name" env:"DB_NAME"`
+	SSLMode string `yaml:"sslmode" toml:"sslmode" json:"sslmode" env:"DB_SSLMODE"`
+	// sqlite only, path of the database file
+	Path string `yaml:"path" toml:"path" json:"path" env:"DB_PATH"`
+	// apply pending schema migrations when the server starts
+	AutoMigrate bool `yaml:"auto_migrate" toml:"auto_migrate" json:"auto_migrate" env:"DB_AUTO_MIGRATE"`
+}
+
+type GoogleConfig struct {
+	// turn off to run without Google login, the credentials are required otherwise
+	Enabled      bool   `yaml:"enabled" toml:"enabled" json:"enabled" env:"GOOGLE_OAUTH_ENABLED"`
+	ClientID     string `yaml:"client_id" toml:"client_id" json:"client_id" env:"GOOGLE_CLIENT_ID"`
+	ClientSecret string `yaml:"client_secret" toml:"client_secret" json:"client_secret" env:"GOOGLE_CLIENT_SECRET" secret:"true"`
+	RedirectURL  string `yaml:"redirect_url" toml:"redirect_url" json:"redirect_url" env:"GOOGLE_REDIRECT_URL"`
+}
+
+type SessionConfig struct {
+	// seconds a login stays valid, the cookie expires at the same time
+	Lifetime         int    `yaml:"lifetime" toml:"lifetime" json:"lifetime" env:"SESSION_LIFETIME"`
+	CookieName       string `yaml:"cookie_name" toml:"cookie_name" json:"cookie_name" env:"SESSION_COOKIE_NAME"`
+	CookieDomain     string `yaml:"cookie_domain" toml:"cookie_domain" json:"cookie_domain" env:"SESSION_COOKIE_DOMAIN"`
+	CookiePath       string `yaml:"cookie_path" toml:"cookie_path" json:"cookie_path" env:"SESSION_COOKIE_PATH"`
+	CookieSecure     bool   `yaml:"cookie_secure" toml:"cookie_secure" json:"cookie_secure" env:"SESSION_COOKIE_SECURE"`
+	CookieSameSite   string `yaml:"cookie_samesite" toml:"cookie_samesite" json:"cookie_samesite" env:"SESSION_COOKIE_SAMESITE"`
+	CookieHostPrefix bool   `yaml:"cookie_host_prefix" toml:"cookie_host_prefix" json:"cookie_host_prefix" env:"SESSION_COOKIE_HOST_PREFIX"`
+	// base64 key sealing the cookie value, empty sends the plain token
+	CookieSecret string `yaml:"cookie_secret" toml:"cookie_secret" json:"cookie_secret" env:"SESSION_COOKIE_SECRET" secret:"true"`
+}
+
+type SecurityConfig struct {
+	CSP                   string `yaml:"csp" toml:"csp" json:"csp" env:"SECURITY_CSP"`
+	HSTSMaxAge            int    `yaml:"hsts_max_age" toml:"hsts_max_age" json:"hsts_max_age" env:"SECURITY_HSTS_MAX_AGE"`
+	HSTSIncludeSubdomains bool   `yaml:"hsts_include_subdomains" toml:"hsts_include_subdomains" json:"hsts_include_subdomains" env:"SECURITY_HSTS_INCLUDE_SUBDOMAINS"`
+	HSTSPreload           bool   `yaml:"hsts_preload" toml:"hsts_preload" json:"hsts_preload" env:"SECURITY_HSTS_PRELOAD"`
+	FrameOptions          string `yaml:"frame_options" toml:"frame_options" json:"frame_options" env:"SECURITY_FRAME_OPTIONS"`
+	ReferrerPolicy        string `yaml:"referrer_policy" toml:"referrer_policy" json:"referrer_policy" env:"SECURITY_REFERRER_POLICY"`
+	PermissionsPolicy     string `yaml:"permissions_policy" toml:"permissions_policy" json:"permissions_policy" env:"SECURITY_PERMISSIONS_POLICY"`
+}
+
+type CORSConfig struct {
+	AllowedOrigins   []string `yaml:"allowed_origins" toml:"allowed_origins" json:"allowed_origins" env:"CORS_ALLOWED_ORIGINS"`
+	AllowedMethods   []string `yaml:"allowed_methods" toml:"allowed_methods" json:"allowed_methods" env:"CORS_ALLOWED_METHODS"`
+	AllowedHeaders   []string `yaml:"allowed_headers" toml:"allowed_headers" json:"allowed_headers" env:"CORS_ALLOWED_HEADERS"`
+	AllowCredentials bool     `yaml:"allow_credentials" toml:"allow_credentials" json:"allow_credentials" env:"CORS_ALLOW_CREDENTIALS"`
+	MaxAge           int      `yaml:"max_age" toml:"max_age" json:"max_age" env:"CORS_MAX_AGE"`
+}
+
+type PasswordConfig struct {
+	// argon2id or bcrypt
+	HashAlgorithm     string `yaml:"hash_algorithm" toml:"hash_algorithm" json:"hash_algorithm" env:"PASSWORD_HASH_ALGORITHM"`
+	Argon2Memory      int    `yaml:"argon2_memory" toml:"argon2_memory" json:"argon2_memory" env:"ARGON2_MEMORY"`
+	Argon2Iterations  int    `yaml:"argon2_iterations" toml:"argon2_iterations" json:"argon2_iterations" env:"ARGON2_ITERATIONS"`
+	Argon2Parallelism int    `yaml:"argon2_parallelism" toml:"argon2_parallelism" json:"argon2_parallelism" env:"ARGON2_PARALLELISM"`
+	BcryptCost        int    `yaml:"bcrypt_cost" toml:"bcrypt_cost" json:"bcrypt_cost" env:"BCRYPT_COST"`
+	MinLength         int    `yaml:"min_length" toml:"min_length" json:"min_length" env:"PASSWORD_MIN_LENGTH"`
+	MaxLength         int    `yaml:"max_length" toml:"max_length" json:"max_length" env:"PASSWORD_MAX_LENGTH"`
+	RequireUpper      bool   `yaml:"require_upper" toml:"require_upper" json:"require_upper" env:"PASSWORD_REQUIRE_UPPER"`
+	RequireLower      bool   `yaml:"require_lower" toml:"require_lower" json:"require_lower" env:"PASSWORD_REQUIRE_LOWER"`
+	RequireDigit      bool   `yaml:"require_digit" toml:"require_digit" json:"require_digit" env:"PASSWORD_REQUIRE_DIGIT"`
+	RequireSymbol     bool   `yaml:"require_symbol" toml:"require_symbol" json:"require_symbol" env:"PASSWORD_REQUIRE_SYMBOL"`
+	MinStrength       int    `yaml:"min_strength" toml:"min_strength" json:"min_strength" env:"PASSWORD_MIN_STRENGTH"`
+	BreachedCheck     bool   `yaml:"breached_check" toml:"breached_check" json:"breached_check" env:"PASSWORD_BREACHED_CHECK"`
+	BreachedFile      string `yaml:"breached_file" toml:"breached_file" json:"breached_file" env:"PASSWORD_BREACHED_FILE"`
+}
+
+type MailConfig struct {
+	// smtp, or log for development, it has no default so production never logs mail by accident
+	Driver   string `yaml:"driver" toml:"driver" json:"driver" env:"MAIL_DRIVER"`
+	From     string `yaml:"from" toml:"from" json:"from" env:"MAIL_FROM"`
+	SMTPHost string `yaml:"smtp_host" toml:"smtp_host" json:"smtp_host" env:"SMTP_HOST"`
+	SMTPPort string `yaml:"smtp_port" toml:"smtp_port" json:"smtp_port" env:"SMTP_PORT"`
+	SMTPUser string `yaml:"smtp_user" toml:"smtp_user" json:"smtp_user" env:"SMTP_USER"`
+	SMTPPass string `yaml:"smtp_pass" toml:"smtp_pass" json:"smtp_pass" env:"SMTP_PASS" secret:"true"`
+}
+
+type OutboxConfig struct {
+	// where outbox events are published: webhook, log and/or nats
+	Sinks             []string `yaml:"sinks" toml:"sinks" json:"sinks" env:"OUTBOX_SINKS"`
+	NATSURL           string   `yaml:"nats_url" toml:"nats_url" json:"nats_url" env:"NATS_URL"`
+	NATSSubjectPrefix string   `yaml:"nats_subject_prefix" toml:"nats_subject_prefix" json:"nats_subject_prefix" env:"NATS_SUBJECT_PREFIX"`
+	// JWT and NKey seed of the NATS user, and the CA verifying a tls:// server
+	NATSCredsFile string `yaml:"nats_creds_file" toml:"nats_creds_file" json:"nats_creds_file" env:"NATS_CREDS_FILE"`
+	NATSCAFile    string `yaml:"nats_ca_file" toml:"nats_ca_file" json:"nats_ca_file" env:"NATS_CA_FILE"`
+	// seconds sent events are kept before the dispatcher deletes them, 0 keeps them forever
+	Retention int `yaml:"retention" toml:"retention" json:"retention" env:"OUTBOX_RETENTION"`
+}
+
+// SecretsConfig points at the encrypted secrets file, its master key only comes
+// from SECRETS_MASTER_KEY or SECRETS_MASTER_KEY_FILE
+type SecretsConfig struct {
+	File string `yaml:"file" toml:"file" json:"file" env:"SECRETS_FILE"`
+	// seconds between secret reloads, 0 reloads on SIGHUP only
+	ReloadInterval int `yaml:"reload_interval" toml:"reload_interval" json:"reload_interval" env:"SECRETS_RELOAD_INTERVAL"`
+}
+
+// handle settings used when no source sets a value
+func Default() *Config {
+	return &Config{
+		Server: ServerConfig{
+			Port:              "8080",
+			BaseURL:           "http://localhost:8080",
+			ReadHeaderTimeout: 5,
+			ReadTimeout:       15,
+			WriteTimeout:      30,
+			IdleTimeout:       120,
+			MaxHeaderBytes:    1 << 20,
+			MaxBodyBytes:      64 << 10,
+			DrainDelay:        5,
+			ShutdownTimeout:   30,
+		},
+		TLS: TLSConfig{
+			ClientAuth: "none",
+		},
+		Log: LogConfig{
+			Level:  "info",
+			Format: "json",
+		},
+		Tracing: TracingConfig{
+			Exporter:    "none",
+			ServiceName: "user-auth-go",
+			SampleRatio: 1,
+		},
+		Database: DatabaseConfig{
+			Driver:  "mysql",
+			Host:    "127.0.0.1",
+			Name:    "user_auth_db",
+			SSLMode: "disable",
+			Path:    "user_auth.db",
+		},
+		Google: GoogleConfig{
+			Enabled:     true,
+			RedirectURL: "http://localhost:8080/api/v1/auth/google/callback",
+		},
+		Session: SessionConfig{
+			Lifetime:       86400,
+			CookieName:     "session_token",
+			CookiePath:     "/",
+			CookieSameSite: "lax",
+		},
+		Security: SecurityConfig{
+			CSP:                   middleware.DefaultContentSecurityPolicy,
+			HSTSMaxAge:            31536000,
+			HSTSIncludeSubdomains: true,
+			FrameOptions:          "DENY",
+			ReferrerPolicy:        "strict-origin-when-cross-origin",
+			PermissionsPolicy:     "camera=(), microphone=(), geolocation=(), payment=()",
+		},
+		CORS: CORSConfig{
+			AllowedMethods: []string{"GET", "POST", "PUT", "DELETE"},
+			AllowedHeaders: []string{"Content-Type"},
+			MaxAge:         600,
+		},
+		Password: PasswordConfig{
+			HashAlgorithm:     "argon2id",
+			Argon2Memory:      64 * 1024,
+			Argon2Iterations:  3,
+			Argon2Parallelism: 2,
+			BcryptCost:        bcrypt.DefaultCost,
+			MinLength:         8,
+			MaxLength:         128,
+			MinStrength:       2,
+			BreachedCheck:     true,
+		},
+		Mail: MailConfig{
+			From:     "no-reply@localhost",
+			SMTPHost: "localhost",
+			SMTPPort: "587",
+		},
+		Outbox: OutboxConfig{
+			Sinks:             []string{"webhook"},
+			NATSURL:           "nats://localhost:4222",
+			NATSSubjectPrefix: "user-auth",
+			Retention:         604800,
+		},
+	}
+}
+
+// handle connection string for the driver, DSN overrides the individual settings
+func (d DatabaseConfig) ConnectionString() string {
+	if d.DSN != "" {
+		return d.DSN
+	}
+
+	switch d.Driver {
+	case "postgres":
+		return fmt.Sprintf("postgres://%s:%s@%s:%s/%s?sslmode=%s",
+			url.QueryEscape(valueOr(d.User, "postgres")),
+			url.QueryEscape(d.Pass),
+			d.Host,
+			valueOr(d.Port, "5432"),
+			d.Name,
+			d.SSLMode,
+		)
+	case "sqlite":
+		return SQLiteDSN(d.Path)
+	}
+
+	return fmt.Sprintf("%s:%s@tcp(%s:%s)/%s?parseTime=true",
+		valueOr(d.User, "root"),
+		d.Pass,
+		d.Host,
+		valueOr(d.Port, "3306"),
+		d.Name,
+	)
+}
+
+func valueOr(value, fallback string) string {
+	if value == "" {
+		return fallback
+	}
+	return value
+}
+
+// ValidationError lists every invalid setting so they can all be fixed in one go
+type ValidationError struct {
+	Problems []string
+}
+
+func (e *ValidationError) Error() string {
+	return "invalid configuration:\n  - " + strings.Join(e.Problems, "\n  - ")
+}
+
+func (e *ValidationError) add(format string, args ...interface{}) {
+	e.Problems = append(e.Problems, fmt.Sprintf(format, args...))
+}
+
+// handle check settings are complete and consistent, returns a *ValidationError
+func (c *Config) Validate() error {
+	problems := &ValidationError{}
+
+	if port, err := strconv.Atoi(c.Server.Port); err != nil || port < 1 || port > 65535 {
+		problems.add("server.port (PORT) must be a port number, got %q", c.Server.Port)
+	}
+
+	validateURL(problems, "server.base_url (APP_BASE_URL)", c.Server.BaseURL)
+
+	if c.Server.DrainDelay < 0 {
+		problems.add("server.drain_delay (SHUTDOWN_DRAIN_DELAY) must not be negative")
+	}
+
+	positive := []struct {
+		name  string
+		value int
+	}{
+		{"server.read_header_timeout (SERVER_READ_HEADER_TIMEOUT)", c.Server.ReadHeaderTimeout},
+		{"server.read_timeout (SERVER_READ_TIMEOUT)", c.Server.ReadTimeout},
+		{"server.write_timeout (SERVER_WRITE_TIMEOUT)", c.Server.WriteTimeout},
+		{"server.idle_timeout (SERVER_IDLE_TIMEOUT)", c.Server.IdleTimeout},
+		{"server.max_header_bytes (SERVER_MAX_HEADER_BYTES)", c.Server.MaxHeaderBytes},
+		{"server.max_body_bytes (SERVER_MAX_BODY_BYTES)", c.Server.MaxBodyBytes},
+		{"server.shutdown_timeout (SHUTDOWN_TIMEOUT)", c.Server.ShutdownTimeout},
+		{"session.lifetime (SESSION_LIFETIME)", c.Session.Lifetime},
+	}
+	for _, setting := range positive {
+		if setting.value <= 0 {
+			problems.add("%s must be positive", setting.name)
+		}
+	}
+
+	if _, err := logging.New(io.Discard, c.Log.Format, c.Log.Level); err != nil {
+		problems.add("log.level (LOG_LEVEL) must be debug, info, warn or error and log.format (LOG_FORMAT) json or text: %s", err)
+	}
+
+	if c.CORS.AllowCredentials && slices.Contains(c.CORS.AllowedOrigins, "*") {
+		problems.add("cors.allowed_origins (CORS_ALLOWED_ORIGINS) cannot contain * when cors.allow_credentials (CORS_ALLOW_CREDENTIALS) is on, list the origins")
+	}
+
+	if c.Metrics.Addr != "" {
+		if _, _, err := net.SplitHostPort(c.Metrics.Addr); err != nil {
+			problems.add("metrics.addr (METRICS_ADDR) must be host:port: %s", err)
+		}
+	}
+
+	switch c.Tracing.Exporter {
+	case "none", "stdout":
+	case "otlp":
+		if c.Tracing.Endpoint != "" {
+			validateURL(problems, "tracing.endpoint (TRACING_OTLP_ENDPOINT)", c.Tracing.Endpoint)
+		}
+	default:
+		problems.add("tracing.exporter (TRACING_EXPORTER) must be none, stdout or otlp, got %q", c.Tracing.Exporter)
+	}
+
+	if c.Tracing.SampleRatio < 0 || c.Tracing.SampleRatio > 1 {
+		problems.add("tracing.sample_ratio (TRACING_SAMPLE_RATIO) must be between 0 and 1, got %v", c.Tracing.SampleRatio)
+	}
+
+	switch c.Database.Driver {
+	case "mysql", "postgres":
+		if c.Database.DSN == "" && (c.Database.Host == "" || c.Database.Name == "") {
+			problems.add("database.host (DB_HOST) and database.name (DB_NAME) are required unless database.dsn (DB_DSN) is set")
+		}
+	case "sqlite":
+		if c.Database.DSN == "" && c.Database.Path == "" {
+			problems.add("database.path (DB_PATH) is required for sqlite unless database.dsn (DB_DSN) is set")
+		}
+	default:
+		problems.add("database.driver (DB_DRIVER) must be mysql, sqlite or postgres, got %q", c.Database.Driver)
+	}
+
+	if c.Google.Enabled {
+		if c.Google.ClientID == "" {
+			problems.add("google.client_id (GOOGLE_CLIENT_ID) is required, set google.enabled (GOOGLE_OAUTH_ENABLED) to false to run without Google login")
+		}
+		if c.Google.ClientSecret == "" {
+			problems.add("google.client_secret (GOOGLE_CLIENT_SECRET) is required, set google.enabled (GOOGLE_OAUTH_ENABLED) to false to run without Google login")
+		}
+		validateURL(problems, "google.redirect_url (GOOGLE_REDIRECT_URL)", c.Google.RedirectURL)
+	}
+
+	if c.Session.CookieName == "" {
+		problems.add("session.cookie_name (SESSION_COOKIE_NAME) is required")
+	}
+
+	if _, err := cookies.ParseSameSite(c.Session.CookieSameSite); err != nil {
+		problems.add("session.cookie_samesite (SESSION_COOKIE_SAMESITE): %s", err)
+	}
+
+	if c.Session.CookieSecret != "" {
+		if _, err := base64.StdEncoding.DecodeString(c.Session.CookieSecret); err != nil {
+			problems.add("session.cookie_secret (SESSION_COOKIE_SECRET) must be base64: %s", err)
+		}
+	}
+
+	switch c.Password.HashAlgorithm {
+	case "argon2id":
+		if c.Password.Argon2Memory < 1 || c.Password.Argon2Iterations < 1 || c.Password.Argon2Parallelism < 1 || c.Password.Argon2Parallelism > 255 {
+			problems.add("password.argon2_* (ARGON2_*) must be positive, parallelism at most 255")
+		}
+	case "bcrypt":
+		if c.Password.BcryptCost < bcrypt.MinCost || c.Password.BcryptCost > bcrypt.MaxCost {
+			problems.add("password.bcrypt_cost (BCRYPT_COST) must be between %d and %d, got %d", bcrypt.MinCost, bcrypt.MaxCost, c.Password.BcryptCost)
+		}
+	default:
+		problems.add("password.hash_algorithm (PASSWORD_HASH_ALGORITHM) must be argon2id or bcrypt, got %q", c.Password.HashAlgorithm)
+	}
+
+	if c.Password.MinLength < 1 {
+		problems.add("password.min_length (PASSWORD_MIN_LENGTH) must be at least 1")
+	}
+
+	if c.Password.MaxLength > 0 && c.Password.MaxLength < c.Password.MinLength {
+		problems.add("password.max_length (PASSWORD_MAX_LENGTH) must not be below password.min_length")
+	}
+
+	if c.Password.BreachedCheck && c.Password.BreachedFile != "" {
+		if _, err := os.Stat(c.Password.BreachedFile); err != nil {
+			problems.add("password.breached_file (PASSWORD_BREACHED_FILE): %s", err)
+		}
+	}
+
+	if (c.TLS.CertFile == "") != (c.TLS.KeyFile == "") {
+		problems.add("tls.cert_file (TLS_CERT_FILE) and tls.key_file (TLS_KEY_FILE) must be set together")
+	}
+
+	switch c.TLS.ClientAuth {
+	case certs.ClientAuthNone:
+	case certs.ClientAuthOptional, certs.ClientAuthRequire:
+		if c.TLS.CertFile == "" || c.TLS.ClientCAFile == "" {
+			problems.add("tls.client_auth (TLS_CLIENT_AUTH) %s needs tls.cert_file (TLS_CERT_FILE) and tls.client_ca_file (TLS_CLIENT_CA_FILE)", c.TLS.ClientAuth)
+		}
+	default:
+		problems.add("tls.client_auth (TLS_CLIENT_AUTH) must be none, optional or require, got %q", c.TLS.ClientAuth)
+	}
+
+	if _, err := certs.ParseIdentities(c.TLS.ClientIdentities); err != nil {
+		problems.add("tls.client_identities (TLS_CLIENT_IDENTITIES): %s", err)
+	}
+
+	switch c.Mail.Driver {
+	case "":
+		problems.add("mail.driver (MAIL_DRIVER) is required: smtp, or log to only log mail during development")
+	case "log":
+	case "smtp":
+		if c.Mail.SMTPHost == "" || c.Mail.From == "" {
+			problems.add("mail.smtp_host (SMTP_HOST) and mail.from (MAIL_FROM) are required for the smtp driver")
+		}
+	default:
+		problems.add("mail.driver (MAIL_DRIVER) must be log or smtp, got %q", c.Mail.Driver)
+	}
+
+	if c.Secrets.ReloadInterval < 0 {
+		problems.add("secrets.reload_interval (SECRETS_RELOAD_INTERVAL) must not be negative")
+	}
+
+	for _, sink := range c.Outbox.Sinks {
+		if sink != "webhook" && sink != "log" && sink != "nats" {
+			problems.add("outbox.sinks (OUTBOX_SINKS) has unknown sink %q, expected webhook, log or nats", sink)
+		}
+	}
+
+	if slices.Contains(c.Outbox.Sinks, "nats") {
+		if parsed, err := url.Parse(c.Outbox.NATSURL); err != nil || (parsed.Scheme != "nats" && parsed.Scheme != "tls") || parsed.Host == "" {
+			problems.add("outbox.nats_url (NATS_URL) must be a nats:// or tls:// URL")
+		}
+	}
+
+	if c.Outbox.Retention < 0 {
+		problems.add("outbox.retention (OUTBOX_RETENTION) must not be negative")
+	}
+
+	if len(problems.Problems) > 0 {
+		return problems
+	}
+	return nil
+}
+
+func validateURL(problems *ValidationError, name, value string) {
+	parsed, err := url.Parse(value)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		problems.add("%s must be an absolute http(s) URL, got %q", name, value)
+	}
+}
@@ -0,0 +1,182 @@
+package cookies
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// browsers only accept `__Host-` cookies that are Secure, scoped to `/`
+// and have no Domain attribute
+const HostPrefix = "__Host-"
+
+var (
+	ErrInvalidCookie = errors.New("invalid cookie value")
+)
+
+type Options struct {
+	Name       string
+	Domain     string
+	Path       string
+	Secure     bool
+	SameSite   http.SameSite
+	HostPrefix bool
+	MaxAge     int
+
+	// when set, cookie values are sealed with AES-GCM before they leave the server
+	// key must be 16, 24 or 32 bytes long
+	SealKey []byte
+}
+
+type Manager struct {
+	opts Options
+	aead cipher.AEAD
+}
+
+// handle build cookie manager and validate the combination of options
+func New(opts Options) (*Manager, error) {
+	if opts.Name == "" {
+		return nil, errors.New("cookie name is required")
+	}
+
+	if opts.Path == "" {
+		opts.Path = "/"
+	}
+
+	if opts.HostPrefix {
+		if !opts.Secure {
+			return nil, errors.New("__Host- cookies must be Secure")
+		}
+		if opts.Domain != "" {
+			return nil, errors.New("__Host- cookies must not set a Domain")
+		}
+		if opts.Path != "/" {
+			return nil, errors.New("__Host- cookies must use path /")
+		}
+	}
+
+	if opts.SameSite == http.SameSiteNoneMode && !opts.Secure {
+		return nil, errors.New("SameSite=None cookies must be Secure")
+	}
+
+	m := &Manager{opts: opts}
+
+	if len(opts.SealKey) > 0 {
+		block, err := aes.NewCipher(opts.SealKey)
+		if err != nil {
+			return nil, fmt.Errorf("invalid cookie seal key: %w", err)
+		}
+
+		m.aead, err = cipher.NewGCM(block)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return m, nil
+}
+
+// full cookie name, including the `__Host-` prefix when enabled
+func (m *Manager) Name() string {
+	if m.opts.HostPrefix {
+		return HostPrefix + m.opts.Name
+	}
+	return m.opts.Name
+}
+
+// handle write cookie with the given value
+func (m *Manager) Set(w http.ResponseWriter, value string) error {
+	if m.aead != nil {
+		sealed, err := m.seal(value)
+		if err != nil {
+			return err
+		}
+		value = sealed
+	}
+
+	http.SetCookie(w, m.cookie(value, m.opts.MaxAge))
+	return nil
+}
+
+// handle invalidate cookie on the client
+func (m *Manager) Clear(w http.ResponseWriter) {
+	http.SetCookie(w, m.cookie("", -1))
+}
+
+// handle read cookie value from request, unsealing it when needed
+func (m *Manager) Read(r *http.Request) (string, error) {
+	cookie, err := r.Cookie(m.Name())
+	if err != nil {
+		return "", err
+	}
+
+	if m.aead == nil {
+		return cookie.Value, nil
+	}
+
+	return m.open(cookie.Value)
+}
+
+func (m *Manager) cookie(value string, maxAge int) *http.Cookie {
+	return &http.Cookie{
+		Name:     m.Name(),
+		Value:    value,
+		Domain:   m.opts.Domain,
+		Path:     m.opts.Path,
+		HttpOnly: true,
+		Secure:   m.opts.Secure,
+		SameSite: m.opts.SameSite,
+		MaxAge:   maxAge,
+	}
+}
+
+// sealed value layout is base64url(nonce || ciphertext)
+// cookie name is used as additional data, so a value can't be moved to another cookie
+func (m *Manager) seal(value string) (string, error) {
+	nonce := make([]byte, m.aead.NonceSize())
+
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+
+	sealed := m.aead.Seal(nonce, nonce, []byte(value), []byte(m.Name()))
+	return base64.RawURLEncoding.EncodeToString(sealed), nil
+}
+
+func (m *Manager) open(value string) (string, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return "", ErrInvalidCookie
+	}
+
+	nonceSize := m.aead.NonceSize()
+	if len(raw) < nonceSize {
+		return "", ErrInvalidCookie
+	}
+
+	plain, err := m.aead.Open(nil, raw[:nonceSize], raw[nonceSize:], []byte(m.Name()))
+	if err != nil {
+		return "", ErrInvalidCookie
+	}
+
+	return string(plain), nil
+}
+
+// handle parse SameSite value from config, empty value falls back to Lax
+func ParseSameSite(value string) (http.SameSite, error) {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "", "lax":
+		return http.SameSiteLaxMode, nil
+	case "strict":
+		return http.SameSiteStrictMode, nil
+	case "none":
+		return http.SameSiteNoneMode, nil
+	default:
+		return 0, fmt.Errorf("unknown SameSite value %q", value)
+	}
+}
@@ -0,0 +1,445 @@
+package dotenv
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// SyntaxError points at the line of an env file that could not be parsed
+type SyntaxError struct {
+	File    string
+	Line    int
+	Message string
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("%s:%d: %s", e.File, e.Line, e.Message)
+}
+
+// Var is one assignment of an env file, in file order
+type Var struct {
+	Key   string
+	Value string
+	Line  int
+}
+
+// Lookup resolves `${NAME}` references, ok is false for unknown names
+type Lookup func(name string) (value string, ok bool)
+
+// handle parse env file content. Supported syntax:
+//
+//	KEY=value              # inline comments need a space before `#`
+//	export KEY=value
+//	KEY='literal $value'   # single quotes: no escapes, no interpolation
+//	KEY="line\nbreak ${HOME}"  # double quotes: \n \r \t \\ \" \$ escapes, may span lines
+//	KEY=first \
+//	  second               # a trailing backslash continues an unquoted value
+//
+// `${NAME}`, `${NAME:-default}` and `$NAME` are interpolated from earlier
+// assignments of the file, then from lookup
+func Parse(file, content string, lookup Lookup) ([]Var, error) {
+	return parse(file, content, nil, map[string]string{}, lookup)
+}
+
+// handle parse with references resolved from fixed, then seen, then fallback
+func parse(file, content string, fixed Lookup, seen map[string]string, fallback Lookup) ([]Var, error) {
+	p := &parser{
+		file:     file,
+		src:      strings.ReplaceAll(content, "\r\n", "\n"),
+		line:     1,
+		fixed:    fixed,
+		seen:     seen,
+		fallback: fallback,
+	}
+	return p.parse()
+}
+
+type parser struct {
+	file string
+	src  string
+	pos  int
+	line int
+
+	// values that win over assignments, like variables already in the environment
+	fixed Lookup
+	// assignments parsed so far
+	seen     map[string]string
+	fallback Lookup
+}
+
+func (p *parser) errorf(line int, format string, args ...interface{}) error {
+	return &SyntaxError{File: p.file, Line: line, Message: fmt.Sprintf(format, args...)}
+}
+
+func (p *parser) eof() bool {
+	return p.pos >= len(p.src)
+}
+
+func (p *parser) peek() byte {
+	return p.src[p.pos]
+}
+
+func (p *parser) next() byte {
+	char := p.src[p.pos]
+	p.pos++
+	if char == '\n' {
+		p.line++
+	}
+	return char
+}
+
+// handle skip spaces and tabs, newlines are significant
+func (p *parser) skipBlanks() {
+	for !p.eof() && (p.peek() == ' ' || p.peek() == '\t') {
+		p.pos++
+	}
+}
+
+// handle skip to the start of the next line
+func (p *parser) skipLine() {
+	for !p.eof() && p.next() != '\n' {
+	}
+}
+
+func (p *parser) parse() ([]Var, error) {
+	var vars []Var
+
+	for !p.eof() {
+		p.skipBlanks()
+
+		if p.eof() {
+			break
+		}
+
+		if p.peek() == '\n' || p.peek() == '#' {
+			p.skipLine()
+			continue
+		}
+
+		line := p.line
+
+		key, err := p.parseKey()
+		if err != nil {
+			return nil, err
+		}
+
+		value, err := p.parseValue(line)
+		if err != nil {
+			return nil, err
+		}
+
+		p.seen[key] = value
+		vars = append(vars, Var{Key: key, Value: value, Line: line})
+	}
+
+	return vars, nil
+}
+
+// handle `[export ]KEY =`
+func (p *parser) parseKey() (string, error) {
+	line := p.line
+	key := p.readName()
+
+	if key == "export" && !p.eof() && (p.peek() == ' ' || p.peek() == '\t') {
+		p.skipBlanks()
+		key = p.readName()
+	}
+
+	if key == "" {
+		return "", p.errorf(line, "expected a variable name")
+	}
+
+	p.skipBlanks()
+
+	if p.eof() || p.peek() != '=' {
+		return "", p.errorf(line, "expected = after %s", key)
+	}
+	p.pos++
+
+	return key, nil
+}
+
+func (p *parser) readName() string {
+	start := p.pos
+
+	for !p.eof() && isNameChar(p.peek(), p.pos == start) {
+		p.pos++
+	}
+
+	return p.src[start:p.pos]
+}
+
+func isNameChar(char byte, first bool) bool {
+	switch {
+	case char == '_' || (char >= 'A' && char <= 'Z') || (char >= 'a' && char <= 'z'):
+		return true
+	case char >= '0' && char <= '9':
+		return !first
+	}
+	return false
+}
+
+func (p *parser) parseValue(line int) (string, error) {
+	p.skipBlanks()
+
+	if p.eof() {
+		return "", nil
+	}
+
+	switch p.peek() {
+	case '\'':
+		p.pos++
+		start := p.pos
+
+		for !p.eof() && p.peek() != '\'' {
+			p.next()
+		}
+		if p.eof() {
+			return "", p.errorf(line, "unterminated single quoted value")
+		}
+
+		value := p.src[start:p.pos]
+		p.pos++
+		return value, p.endOfValue(line)
+
+	case '"':
+		p.pos++
+
+		value, err := p.readDoubleQuoted(line)
+		if err != nil {
+			return "", err
+		}
+		return value, p.endOfValue(line)
+	}
+
+	return p.readUnquoted(line)
+}
+
+// handle only blanks and a comment may follow a quoted value
+func (p *parser) endOfValue(line int) error {
+	p.skipBlanks()
+
+	if p.eof() {
+		return nil
+	}
+
+	switch p.peek() {
+	case '\n', '#':
+		p.skipLine()
+		return nil
+	}
+
+	return p.errorf(line, "unexpected %q after quoted value", p.peek())
+}
+
+func (p *parser) readDoubleQuoted(line int) (string, error) {
+	var builder strings.Builder
+
+	for {
+		if p.eof() {
+			return "", p.errorf(line, "unterminated double quoted value")
+		}
+
+		char := p.next()
+
+		switch char {
+		case '"':
+			return builder.String(), nil
+
+		case '\\':
+			if p.eof() {
+				return "", p.errorf(line, "unterminated double quoted value")
+			}
+
+			switch escaped := p.next(); escaped {
+			case 'n':
+				builder.WriteByte('\n')
+			case 'r':
+				builder.WriteByte('\r')
+			case 't':
+				builder.WriteByte('\t')
+			case '\\', '"', '$':
+				builder.WriteByte(escaped)
+			case '\n':
+				// escaped newline joins the lines
+			default:
+				builder.WriteByte('\\')
+				builder.WriteByte(escaped)
+			}
+
+		case '$':
+			value, err := p.readReference(p.line)
+			if err != nil {
+				return "", err
+			}
+			builder.WriteString(value)
+
+		default:
+			builder.WriteByte(char)
+		}
+	}
+}
+
+func (p *parser) readUnquoted(line int) (string, error) {
+	var builder strings.Builder
+
+	for !p.eof() {
+		char := p.peek()
+
+		if char == '\n' {
+			p.next()
+			break
+		}
+
+		// `#` starts a comment only after whitespace, so `KEY=abc#123` keeps the hash
+		if char == '#' && p.pos > 0 && (p.src[p.pos-1] == ' ' || p.src[p.pos-1] == '\t') {
+			p.skipLine()
+			break
+		}
+
+		p.next()
+
+		switch char {
+		case '\\':
+			if !p.eof() && p.peek() == '\n' {
+				p.next()
+				p.skipBlanks()
+				continue
+			}
+			if !p.eof() && p.peek() == '$' {
+				builder.WriteByte(p.next())
+				continue
+			}
+			builder.WriteByte(char)
+
+		case '$':
+			value, err := p.readReference(line)
+			if err != nil {
+				return "", err
+			}
+			builder.WriteString(value)
+
+		default:
+			builder.WriteByte(char)
+		}
+	}
+
+	return strings.TrimRight(builder.String(), " \t"), nil
+}
+
+// handle `${NAME}`, `${NAME:-default}` or `$NAME`, the `$` is already consumed
+func (p *parser) readReference(line int) (string, error) {
+	if p.eof() {
+		return "$", nil
+	}
+
+	if p.peek() != '{' {
+		name := p.readName()
+		if name == "" {
+			return "$", nil
+		}
+		value, _ := p.resolve(name)
+		return value, nil
+	}
+
+	p.pos++
+	end := strings.IndexAny(p.src[p.pos:], "}\n")
+	if end < 0 || p.src[p.pos+end] != '}' {
+		return "", p.errorf(line, "unterminated ${ reference")
+	}
+
+	body := p.src[p.pos : p.pos+end]
+	p.pos += end + 1
+
+	name, fallback, hasFallback := strings.Cut(body, ":-")
+	if name == "" || !validName(name) {
+		return "", p.errorf(line, "invalid reference ${%s}", body)
+	}
+
+	value, ok := p.resolve(name)
+	if (!ok || value == "") && hasFallback {
+		return fallback, nil
+	}
+
+	return value, nil
+}
+
+func validName(name string) bool {
+	for i := 0; i < len(name); i++ {
+		if !isNameChar(name[i], i == 0) {
+			return false
+		}
+	}
+	return true
+}
+
+func (p *parser) resolve(name string) (string, bool) {
+	if p.fixed != nil {
+		if value, ok := p.fixed(name); ok {
+			return value, true
+		}
+	}
+	if value, ok := p.seen[name]; ok {
+		return value, true
+	}
+	if p.fallback != nil {
+		return p.fallback(name)
+	}
+	return "", false
+}
+
+// handle read env files in order and set their variables, later files win over
+// earlier ones. Missing files are skipped. Variables already in the process
+// environment are kept unless override is set, references see the same values
+func Load(override bool, files ...string) error {
+	merged := map[string]string{}
+	var order []string
+
+	var fixed Lookup
+	if !override {
+		fixed = os.LookupEnv
+	}
+
+	for _, file := range files {
+		content, err := os.ReadFile(file)
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		vars, err := parse(file, string(content), fixed, merged, os.LookupEnv)
+		if err != nil {
+			return err
+		}
+
+		for _, v := range vars {
+			if !contains(order, v.Key) {
+				order = append(order, v.Key)
+			}
+		}
+	}
+
+	for _, key := range order {
+		if _, exists := os.LookupEnv(key); exists && !override {
+			continue
+		}
+		if err := os.Setenv(key, merged[key]); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func contains(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
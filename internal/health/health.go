@@ -0,0 +1,158 @@
+package health
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"sync/atomic"
+	"time"
+	"user-auth-go/internal/config"
+	"user-auth-go/internal/storage/sqlstore"
+)
+
+// status of one component, readiness fails when any of them is StatusFail
+const (
+	StatusOK       = "ok"
+	StatusFail     = "fail"
+	StatusDisabled = "disabled"
+)
+
+// Component is the result of one readiness check
+type Component struct {
+	Status string `json:"status"`
+	// `/readyz` needs no authentication, so Error is a generic reason and the cause is only logged
+	Error string `json:"error,omitempty"`
+	// set by the migrations check
+	Pending *int `json:"pending,omitempty"`
+}
+
+// Report is the body of `/readyz`
+type Report struct {
+	// ok, unavailable or draining
+	Status     string               `json:"status"`
+	Components map[string]Component `json:"components"`
+}
+
+// Checker answers liveness and readiness probes. Readiness needs the database reachable
+// within Timeout, no pending migrations and a complete OAuth config, and fails once Drain was called
+type Checker struct {
+	db       *sql.DB
+	migrator *sqlstore.Migrator
+	draining atomic.Bool
+	// how long each database check may take
+	Timeout time.Duration
+}
+
+// handle checker of the database, migrations are not checked when migrator is nil
+func New(db *sql.DB, migrator *sqlstore.Migrator) *Checker {
+	return &Checker{db: db, migrator: migrator, Timeout: 2 * time.Second}
+}
+
+// handle register `/healthz` and `/readyz`
+func (c *Checker) Routes(mux *http.ServeMux) {
+	mux.HandleFunc("GET /healthz", c.Liveness)
+	mux.HandleFunc("GET /readyz", c.Readiness)
+}
+
+// handle make readiness fail from now on, called when shutdown starts
+func (c *Checker) Drain() {
+	c.draining.Store(true)
+}
+
+// handle `/healthz`, the process is up and serving requests
+func (c *Checker) Liveness(w http.ResponseWriter, r *http.Request) {
+	respond(w, http.StatusOK, map[string]string{"status": StatusOK})
+}
+
+// handle `/readyz`, 503 unless every component is ok
+func (c *Checker) Readiness(w http.ResponseWriter, r *http.Request) {
+	report := c.Check(r.Context())
+
+	status := http.StatusOK
+	if report.Status != StatusOK {
+		status = http.StatusServiceUnavailable
+	}
+
+	respond(w, status, report)
+}
+
+// handle run every readiness check
+func (c *Checker) Check(ctx context.Context) Report {
+	report := Report{
+		Status: StatusOK,
+		Components: map[string]Component{
+			"database": c.checkDatabase(ctx),
+			"oauth":    checkOAuth(),
+		},
+	}
+
+	if c.migrator != nil {
+		report.Components["migrations"] = c.checkMigrations(ctx)
+	}
+
+	for _, component := range report.Components {
+		if component.Status == StatusFail {
+			report.Status = "unavailable"
+		}
+	}
+
+	if c.draining.Load() {
+		report.Status = "draining"
+	}
+
+	return report
+}
+
+func (c *Checker) checkDatabase(ctx context.Context) Component {
+	ctx, cancel := context.WithTimeout(ctx, c.Timeout)
+	defer cancel()
+
+	if err := c.db.PingContext(ctx); err != nil {
+		slog.Warn("Readiness database check failed", "error", err)
+		return Component{Status: StatusFail, Error: "unavailable"}
+	}
+
+	return Component{Status: StatusOK}
+}
+
+func (c *Checker) checkMigrations(ctx context.Context) Component {
+	ctx, cancel := context.WithTimeout(ctx, c.Timeout)
+	defer cancel()
+
+	pending, err := c.migrator.Pending(ctx)
+	if err != nil {
+		slog.Warn("Readiness migrations check failed", "error", err)
+		return Component{Status: StatusFail, Error: "unavailable"}
+	}
+
+	count := len(pending)
+	if count > 0 {
+		return Component{Status: StatusFail, Error: "migrations pending, run `migrate up`", Pending: &count}
+	}
+
+	return Component{Status: StatusOK, Pending: &count}
+}
+
+// handle Google login needs a client id, secret and redirect URL, a reloaded secret may be blank
+func checkOAuth() Component {
+	oauth := config.GoogleOAuth()
+	if oauth == nil {
+		return Component{Status: StatusDisabled}
+	}
+
+	if oauth.ClientID == "" || oauth.ClientSecret == "" || oauth.RedirectURL == "" {
+		return Component{Status: StatusFail, Error: "google client id, secret and redirect url are required"}
+	}
+
+	return Component{Status: StatusOK}
+}
+
+func respond(w http.ResponseWriter, status int, payload interface{}) {
+	// probes must never see a cached answer
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(payload)
+}
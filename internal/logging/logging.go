@@ -0,0 +1,151 @@
+package logging
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"regexp"
+	"strings"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+type contextKey string
+
+const (
+	requestIDCtxKey   contextKey = "request-id"
+	requestInfoCtxKey contextKey = "request-info"
+)
+
+// shown instead of tokens, passwords and other secrets
+const Redacted = "[REDACTED]"
+
+// attributes holding an email address, logged masked like `j***@example.com`
+var emailKeys = map[string]bool{
+	"email":      true,
+	"new_email":  true,
+	"identifier": true,
+	"to":         true,
+}
+
+// attributes containing one of these are never logged
+var secretKeyParts = []string{"token", "password", "secret", "cookie", "authorization", "dsn"}
+
+// free text attributes, emails inside them are masked
+var textKeys = map[string]bool{
+	"error":   true,
+	"payload": true,
+	"body":    true,
+}
+
+var emailPattern = regexp.MustCompile(`[A-Za-z0-9._%+\-]+@[A-Za-z0-9.\-]+\.[A-Za-z]{2,}`)
+
+// handle logger writing json or text lines to w at the given level (debug, info, warn, error),
+// emails and secrets are redacted from attributes whatever the call site passes
+func New(w io.Writer, format, level string) (*slog.Logger, error) {
+	var lvl slog.Level
+	if err := lvl.UnmarshalText([]byte(level)); err != nil {
+		return nil, fmt.Errorf("unknown log level %q", level)
+	}
+
+	opts := &slog.HandlerOptions{Level: lvl, ReplaceAttr: redact}
+
+	switch format {
+	case "json":
+		return slog.New(slog.NewJSONHandler(w, opts)), nil
+	case "text":
+		return slog.New(slog.NewTextHandler(w, opts)), nil
+	}
+
+	return nil, fmt.Errorf("unknown log format %q", format)
+}
+
+func redact(groups []string, attr slog.Attr) slog.Attr {
+	key := strings.ToLower(attr.Key)
+
+	if emailKeys[key] {
+		return slog.String(attr.Key, MaskEmail(attr.Value.String()))
+	}
+
+	for _, part := range secretKeyParts {
+		if strings.Contains(key, part) {
+			return slog.String(attr.Key, Redacted)
+		}
+	}
+
+	// errors from the database or SMTP server may quote the address they failed on
+	if textKeys[key] || (len(groups) == 0 && key == slog.MessageKey) {
+		return slog.String(attr.Key, emailPattern.ReplaceAllStringFunc(attr.Value.String(), MaskEmail))
+	}
+
+	return attr
+}
+
+// handle keep the first letter and domain of an email, enough to tell users apart in logs
+func MaskEmail(email string) string {
+	local, domain, found := strings.Cut(email, "@")
+	if !found || local == "" {
+		return "***"
+	}
+
+	return local[:1] + "***@" + domain
+}
+
+// handle attach request id to ctx, picked up by FromContext
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDCtxKey, id)
+}
+
+// handle request id of ctx, empty outside of a request
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDCtxKey).(string)
+	return id
+}
+
+// handle default logger with the request and trace ids of ctx attached
+func FromContext(ctx context.Context) *slog.Logger {
+	logger := slog.Default()
+
+	if id := RequestID(ctx); id != "" {
+		logger = logger.With("request_id", id)
+	}
+
+	if span := trace.SpanContextFromContext(ctx); span.IsValid() {
+		logger = logger.With("trace_id", span.TraceID().String(), "span_id", span.SpanID().String())
+	}
+
+	return logger
+}
+
+// RequestInfo collects what inner handlers learn about a request for its access log
+type RequestInfo struct {
+	Route  string
+	UserID int
+}
+
+// handle attach a RequestInfo to ctx that SetRoute and SetUserID fill in
+func WithRequestInfo(ctx context.Context) (context.Context, *RequestInfo) {
+	info := &RequestInfo{}
+	return context.WithValue(ctx, requestInfoCtxKey, info), info
+}
+
+// handle RequestInfo attached to ctx, nil when there is none
+func RequestInfoFrom(ctx context.Context) *RequestInfo {
+	info, _ := ctx.Value(requestInfoCtxKey).(*RequestInfo)
+	return info
+}
+
+// handle record the matched route pattern, no-op outside of an access logged request
+func SetRoute(ctx context.Context, route string) {
+	if info := RequestInfoFrom(ctx); info != nil {
+		info.Route = route
+	}
+}
+
+// handle record the authenticated user, no-op outside of an access logged request
+func SetUserID(ctx context.Context, userID int) {
+	if info := RequestInfoFrom(ctx); info != nil {
+		info.UserID = userID
+	}
+}
@@ -0,0 +1,70 @@
+package mail
+
+import (
+	"fmt"
+	"log/slog"
+	"net"
+	"net/smtp"
+	"regexp"
+	"strings"
+	"user-auth-go/internal/logging"
+)
+
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+type Mailer interface {
+	Send(msg Message) error
+}
+
+// SMTPMailer sends plain text emails through an SMTP server
+type SMTPMailer struct {
+	Host     string
+	Port     string
+	Username string
+	Password string
+	From     string
+}
+
+func (m *SMTPMailer) Send(msg Message) error {
+	var auth smtp.Auth
+	if m.Username != "" {
+		auth = smtp.PlainAuth("", m.Username, m.Password, m.Host)
+	}
+
+	// header values come from our own code, but never let a newline sneak in
+	for _, value := range []string{m.From, msg.To, msg.Subject} {
+		if strings.ContainsAny(value, "\r\n") {
+			return fmt.Errorf("invalid mail header value %q", value)
+		}
+	}
+
+	body := strings.Join([]string{
+		"From: " + m.From,
+		"To: " + msg.To,
+		"Subject: " + msg.Subject,
+		"MIME-Version: 1.0",
+		"Content-Type: text/plain; charset=UTF-8",
+		"",
+		msg.Body,
+	}, "\r\n")
+
+	return smtp.SendMail(net.JoinHostPort(m.Host, m.Port), auth, m.From, []string{msg.To}, []byte(body))
+}
+
+// LogMailer only writes emails to the log, for local development where no SMTP server runs
+type LogMailer struct{}
+
+// query parameters of links that grant access, like the email change `?token=`
+var linkSecretPattern = regexp.MustCompile(`(?i)([?&][a-z_]*(?:token|code|secret|key)[a-z_]*=)[^&\s]+`)
+
+func (m *LogMailer) Send(msg Message) error {
+	// a logged confirm or cancel link would let anyone reading the logs take over the account
+	body := linkSecretPattern.ReplaceAllString(msg.Body, "${1}"+logging.Redacted)
+
+	slog.Info("Mail", "to", msg.To, "subject", msg.Subject, "body", body)
+	return nil
+}
@@ -0,0 +1,138 @@
+package metrics
+
+import (
+	"context"
+	"crypto/subtle"
+	"database/sql"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Default is the registry the app records to and `/metrics` serves
+var Default = prometheus.NewRegistry()
+
+// password hashing is slow on purpose, so its buckets start higher
+var HashBuckets = []float64{.01, .025, .05, .1, .25, .5, 1, 2.5, 5}
+
+var (
+	HTTPRequests = promauto.With(Default).NewCounterVec(prometheus.CounterOpts{
+		Name: "http_requests_total",
+		Help: "HTTP requests by method, route pattern and status.",
+	}, []string{"method", "route", "status"})
+
+	HTTPDuration = promauto.With(Default).NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "http_request_duration_seconds",
+		Help:    "HTTP request latency by method, route pattern and status.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+
+	// AuthEvents is counted by the API from the audit events it records (see api.recordAudit),
+	// so a new auth outcome only shows up here once it is audited
+	AuthEvents = promauto.With(Default).NewCounterVec(prometheus.CounterOpts{
+		Name: "auth_events_total",
+		Help: "Signup, login, logout and Google callback outcomes.",
+	}, []string{"event", "outcome", "provider", "reason"})
+
+	PasswordHashDuration = promauto.With(Default).NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "password_hash_duration_seconds",
+		Help:    "Time spent hashing and verifying passwords.",
+		Buckets: HashBuckets,
+	}, []string{"algorithm", "operation"})
+)
+
+// longest a scrape waits on the database for the session count
+const collectTimeout = 2 * time.Second
+
+// activeSessions counts unexpired sessions on every scrape, a failed count leaves the gauge out
+type activeSessions struct {
+	desc  *prometheus.Desc
+	count func(ctx context.Context) (int64, error)
+}
+
+func (c *activeSessions) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.desc
+}
+
+func (c *activeSessions) Collect(ch chan<- prometheus.Metric) {
+	ctx, cancel := context.WithTimeout(context.Background(), collectTimeout)
+	defer cancel()
+
+	active, err := c.count(ctx)
+	if err != nil {
+		slog.Warn("Failed to count active sessions for metrics", "error", err)
+		return
+	}
+	ch <- prometheus.MustNewConstMetric(c.desc, prometheus.GaugeValue, float64(active))
+}
+
+// handle expose the number of unexpired sessions, counted on every scrape
+func RegisterActiveSessions(reg prometheus.Registerer, count func(ctx context.Context) (int64, error)) {
+	reg.MustRegister(&activeSessions{
+		desc:  prometheus.NewDesc("auth_active_sessions", "Sessions that have not expired.", nil, nil),
+		count: count,
+	})
+}
+
+// handle expose the connection pool stats of db
+func RegisterDBStats(reg prometheus.Registerer, db *sql.DB) {
+	gauge := func(name, help string, value func(sql.DBStats) float64) {
+		reg.MustRegister(prometheus.NewGaugeFunc(prometheus.GaugeOpts{Name: name, Help: help}, func() float64 {
+			return value(db.Stats())
+		}))
+	}
+
+	counter := func(name, help string, value func(sql.DBStats) float64) {
+		reg.MustRegister(prometheus.NewCounterFunc(prometheus.CounterOpts{Name: name, Help: help}, func() float64 {
+			return value(db.Stats())
+		}))
+	}
+
+	gauge("db_max_open_connections", "Maximum number of open connections to the database.",
+		func(s sql.DBStats) float64 { return float64(s.MaxOpenConnections) })
+	gauge("db_open_connections", "Established connections, in use and idle.",
+		func(s sql.DBStats) float64 { return float64(s.OpenConnections) })
+	gauge("db_in_use_connections", "Connections currently in use.",
+		func(s sql.DBStats) float64 { return float64(s.InUse) })
+	gauge("db_idle_connections", "Idle connections.",
+		func(s sql.DBStats) float64 { return float64(s.Idle) })
+	counter("db_wait_count_total", "Connections waited for.",
+		func(s sql.DBStats) float64 { return float64(s.WaitCount) })
+	counter("db_wait_duration_seconds_total", "Time blocked waiting for a new connection.",
+		func(s sql.DBStats) float64 { return s.WaitDuration.Seconds() })
+	counter("db_max_idle_closed_total", "Connections closed due to SetMaxIdleConns.",
+		func(s sql.DBStats) float64 { return float64(s.MaxIdleClosed) })
+	counter("db_max_idle_time_closed_total", "Connections closed due to SetConnMaxIdleTime.",
+		func(s sql.DBStats) float64 { return float64(s.MaxIdleTimeClosed) })
+	counter("db_max_lifetime_closed_total", "Connections closed due to SetConnMaxLifetime.",
+		func(s sql.DBStats) float64 { return float64(s.MaxLifetimeClosed) })
+}
+
+// handle serve reg in the format the scraper asks for, a non empty token
+// must be sent as `Authorization: Bearer <token>`
+func Handler(reg prometheus.Gatherer, token string) http.Handler {
+	serve := promhttp.HandlerFor(reg, promhttp.HandlerOpts{})
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if token != "" {
+			sent, found := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+			if !found || subtle.ConstantTimeCompare([]byte(sent), []byte(token)) != 1 {
+				w.Header().Set("WWW-Authenticate", `Bearer realm="metrics"`)
+				http.Error(w, "Unauthorized", http.StatusUnauthorized)
+				return
+			}
+		}
+
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		serve.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,85 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+type CORSOptions struct {
+	// exact origins like `https://app.example.com`, `*` allows any origin
+	AllowedOrigins   []string
+	AllowedMethods   []string
+	AllowedHeaders   []string
+	AllowCredentials bool
+
+	// how long (in seconds) browsers may cache a preflight response
+	MaxAge int
+}
+
+// handle CORS for allowlisted origins and answer preflight requests
+func CORS(opts CORSOptions) Middleware {
+	origins := make(map[string]bool, len(opts.AllowedOrigins))
+	allowAny := false
+
+	for _, origin := range opts.AllowedOrigins {
+		if origin == "*" {
+			allowAny = true
+			continue
+		}
+		origins[strings.TrimRight(origin, "/")] = true
+	}
+
+	methods := strings.Join(opts.AllowedMethods, ", ")
+	headers := strings.Join(opts.AllowedHeaders, ", ")
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			origin := r.Header.Get("Origin")
+			h := w.Header()
+			h.Add("Vary", "Origin")
+
+			if origin == "" || (!allowAny && !origins[origin]) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			// only listed origins may send cookies, any other origin gets the literal wildcard
+			// which browsers never combine with credentials
+			if origins[origin] {
+				h.Set("Access-Control-Allow-Origin", origin)
+
+				if opts.AllowCredentials {
+					h.Set("Access-Control-Allow-Credentials", "true")
+				}
+			} else {
+				h.Set("Access-Control-Allow-Origin", "*")
+			}
+
+			isPreflight := r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != ""
+			if !isPreflight {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			h.Add("Vary", "Access-Control-Request-Method")
+			h.Add("Vary", "Access-Control-Request-Headers")
+
+			if methods != "" {
+				h.Set("Access-Control-Allow-Methods", methods)
+			}
+
+			if headers != "" {
+				h.Set("Access-Control-Allow-Headers", headers)
+			} else if requested := r.Header.Get("Access-Control-Request-Headers"); requested != "" {
+				h.Set("Access-Control-Allow-Headers", requested)
+			}
+
+			if opts.MaxAge > 0 {
+				h.Set("Access-Control-Max-Age", strconv.Itoa(opts.MaxAge))
+			}
+
+			w.WriteHeader(http.StatusNoContent)
+		})
+	}
+}
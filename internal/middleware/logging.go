@@ -0,0 +1,144 @@
+package middleware
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"log/slog"
+	"net/http"
+	"time"
+	"user-auth-go/internal/logging"
+	"user-auth-go/internal/tracing"
+
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const RequestIDHeader = "X-Request-ID"
+
+// longest X-Request-ID accepted from clients, longer ones are replaced
+const maxRequestIDLength = 128
+
+// handle reuse the client's X-Request-ID or generate one, echo it on the response
+// and attach it to the request context for logs
+func RequestID() Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			id := r.Header.Get(RequestIDHeader)
+			if !validRequestID(id) {
+				id = generateRequestID()
+			}
+
+			w.Header().Set(RequestIDHeader, id)
+			next.ServeHTTP(w, r.WithContext(logging.WithRequestID(r.Context(), id)))
+		})
+	}
+}
+
+// handle log one line per request once it is served, with the route and user
+// filled in by Route and the auth guard
+func AccessLog(logger *slog.Logger) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			ctx, info := logging.WithRequestInfo(r.Context())
+			recorder := &statusRecorder{ResponseWriter: w}
+
+			next.ServeHTTP(recorder, r.WithContext(ctx))
+
+			attrs := []slog.Attr{
+				slog.String("method", r.Method),
+				slog.String("route", info.Route),
+				slog.String("path", r.URL.Path),
+				slog.Int("status", recorder.Status()),
+				slog.Int("bytes", recorder.bytes),
+				slog.Float64("duration_ms", float64(time.Since(start).Microseconds())/1000),
+			}
+
+			if info.UserID != 0 {
+				attrs = append(attrs, slog.Int("user_id", info.UserID))
+			}
+
+			if id := logging.RequestID(ctx); id != "" {
+				attrs = append(attrs, slog.String("request_id", id))
+			}
+
+			if span := trace.SpanContextFromContext(ctx); span.IsValid() {
+				attrs = append(attrs, slog.String("trace_id", span.TraceID().String()))
+			}
+
+			logger.LogAttrs(ctx, slog.LevelInfo, "request", attrs...)
+		})
+	}
+}
+
+// handle record the ServeMux pattern that matched for logs, metrics and the request span,
+// must wrap the mux directly since the mux sets the pattern on the request it was given
+func Route(mux http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mux.ServeHTTP(w, r)
+
+		if r.Pattern == "" {
+			return
+		}
+		// the method is logged on its own, `GET /api/v1/profile` is recorded as `/api/v1/profile`
+		route := tracing.RoutePath(r.Pattern)
+		logging.SetRoute(r.Context(), route)
+		span := trace.SpanFromContext(r.Context())
+		span.SetName(r.Method + " " + route)
+		span.SetAttributes(semconv.HTTPRoute(route))
+	})
+}
+
+// statusRecorder remembers the status and size of the response it writes
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+	bytes  int
+}
+
+func (s *statusRecorder) WriteHeader(status int) {
+	if s.status == 0 {
+		s.status = status
+	}
+	s.ResponseWriter.WriteHeader(status)
+}
+
+func (s *statusRecorder) Write(b []byte) (int, error) {
+	if s.status == 0 {
+		s.status = http.StatusOK
+	}
+	n, err := s.ResponseWriter.Write(b)
+	s.bytes += n
+	return n, err
+}
+
+func (s *statusRecorder) Status() int {
+	if s.status == 0 {
+		return http.StatusOK
+	}
+	return s.status
+}
+
+// lets http.ResponseController reach Flush and friends on the real writer
+func (s *statusRecorder) Unwrap() http.ResponseWriter {
+	return s.ResponseWriter
+}
+
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+
+	for _, c := range id {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '-' || c == '_' || c == '.' || c == ':') {
+			return false
+		}
+	}
+	return true
+}
+
+func generateRequestID() string {
+	bytes := make([]byte, 16)
+	rand.Read(bytes)
+	return hex.EncodeToString(bytes)
+}
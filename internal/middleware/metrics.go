@@ -0,0 +1,51 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+	"user-auth-go/internal/logging"
+	"user-auth-go/internal/metrics"
+)
+
+// handle count requests and their latency per route pattern and status,
+// requests no route matched share the `unmatched` route so paths can't blow up cardinality
+func Metrics() Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+
+			// shares the RequestInfo of AccessLog when it runs first
+			info := logging.RequestInfoFrom(r.Context())
+			if info == nil {
+				var ctx context.Context
+				ctx, info = logging.WithRequestInfo(r.Context())
+				r = r.WithContext(ctx)
+			}
+
+			recorder := &statusRecorder{ResponseWriter: w}
+			next.ServeHTTP(recorder, r)
+
+			route := info.Route
+			if route == "" {
+				route = "unmatched"
+			}
+
+			status := strconv.Itoa(recorder.Status())
+			method := metricMethod(r.Method)
+			metrics.HTTPRequests.WithLabelValues(method, route, status).Inc()
+			metrics.HTTPDuration.WithLabelValues(method, route, status).Observe(time.Since(start).Seconds())
+		})
+	}
+}
+
+// clients pick the method, anything non standard is counted as OTHER
+func metricMethod(method string) string {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodPatch,
+		http.MethodDelete, http.MethodOptions:
+		return method
+	}
+	return "OTHER"
+}
@@ -0,0 +1,111 @@
+package middleware
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+type contextKey string
+
+const nonceCtxKey contextKey = "csp-nonce"
+
+// placeholder replaced with the per-request nonce inside the CSP
+const NoncePlaceholder = "{nonce}"
+
+const DefaultContentSecurityPolicy = "default-src 'self'; script-src 'self' 'nonce-{nonce}'; style-src 'self'; " +
+	"img-src 'self' data:; object-src 'none'; base-uri 'self'; form-action 'self'; frame-ancestors 'none'"
+
+type SecurityOptions struct {
+	ContentSecurityPolicy string
+	HSTSMaxAge            int
+	HSTSIncludeSubdomains bool
+	HSTSPreload           bool
+	FrameOptions          string
+	ReferrerPolicy        string
+	PermissionsPolicy     string
+}
+
+// handle set security headers on every response
+// empty option means the header is not sent
+func SecurityHeaders(opts SecurityOptions) Middleware {
+	hsts := hstsValue(opts)
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			h := w.Header()
+			h.Set("X-Content-Type-Options", "nosniff")
+
+			if opts.ContentSecurityPolicy != "" {
+				nonce, err := generateNonce()
+				if err != nil {
+					http.Error(w, "Internal server error", http.StatusInternalServerError)
+					return
+				}
+
+				h.Set("Content-Security-Policy", strings.ReplaceAll(opts.ContentSecurityPolicy, NoncePlaceholder, nonce))
+				r = r.WithContext(context.WithValue(r.Context(), nonceCtxKey, nonce))
+			}
+
+			// HSTS is ignored by browsers on plain http, only send it over https
+			if hsts != "" && isHTTPS(r) {
+				h.Set("Strict-Transport-Security", hsts)
+			}
+
+			if opts.FrameOptions != "" {
+				h.Set("X-Frame-Options", opts.FrameOptions)
+			}
+
+			if opts.ReferrerPolicy != "" {
+				h.Set("Referrer-Policy", opts.ReferrerPolicy)
+			}
+
+			if opts.PermissionsPolicy != "" {
+				h.Set("Permissions-Policy", opts.PermissionsPolicy)
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// CSP nonce for the current request, used on inline `<script>` blocks
+func Nonce(r *http.Request) string {
+	nonce, _ := r.Context().Value(nonceCtxKey).(string)
+	return nonce
+}
+
+func generateNonce() (string, error) {
+	bytes := make([]byte, 16)
+
+	if _, err := rand.Read(bytes); err != nil {
+		return "", err
+	}
+
+	return base64.StdEncoding.EncodeToString(bytes), nil
+}
+
+func hstsValue(opts SecurityOptions) string {
+	if opts.HSTSMaxAge <= 0 {
+		return ""
+	}
+
+	value := fmt.Sprintf("max-age=%d", opts.HSTSMaxAge)
+
+	if opts.HSTSIncludeSubdomains {
+		value += "; includeSubDomains"
+	}
+
+	if opts.HSTSPreload {
+		value += "; preload"
+	}
+
+	return value
+}
+
+func isHTTPS(r *http.Request) bool {
+	return r.TLS != nil || strings.EqualFold(r.Header.Get("X-Forwarded-Proto"), "https")
+}
@@ -0,0 +1,71 @@
+package models
+
+import "time"
+
+// audit events are append-only, there is no update or delete on purpose
+type AuditEvent struct {
+	ID           int64
+	EventType    string
+	Outcome      string
+	ActorUserID  *int
+	TargetUserID *int
+	IP           string
+	UserAgent    string
+	Details      map[string]interface{}
+	CreatedAt    time.Time
+}
+
+type AuditFilter struct {
+	UserID    *int // matches actor or target
+	EventType string
+	Outcome   string
+	IP        string
+	Since     *time.Time
+	Until     *time.Time
+	Limit     int
+	Offset    int
+}
+
+// MaxAuditLimit is the most events one query returns
+const MaxAuditLimit = 500
+
+// handle clamp requested limit, anything outside the range returns the maximum
+func (f AuditFilter) PageSize() int {
+	if f.Limit <= 0 || f.Limit > MaxAuditLimit {
+		return MaxAuditLimit
+	}
+	return f.Limit
+}
+
+// handle check event passes the filter, used by stores that can't express it in SQL
+func (f AuditFilter) Matches(event AuditEvent) bool {
+	if f.UserID != nil && !intPtrEquals(event.ActorUserID, *f.UserID) && !intPtrEquals(event.TargetUserID, *f.UserID) {
+		return false
+	}
+
+	if f.EventType != "" && event.EventType != f.EventType {
+		return false
+	}
+
+	if f.Outcome != "" && event.Outcome != f.Outcome {
+		return false
+	}
+
+	if f.IP != "" && event.IP != f.IP {
+		return false
+	}
+
+	if f.Since != nil && event.CreatedAt.Before(*f.Since) {
+		return false
+	}
+
+	if f.Until != nil && !event.CreatedAt.Before(*f.Until) {
+		return false
+	}
+
+	return true
+}
+
+func intPtrEquals(value *int, expected int) bool {
+	return value != nil && *value == expected
+}
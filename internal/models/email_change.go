@@ -0,0 +1,40 @@
+package models
+
+import "time"
+
+// pending email change, applied only after the new address confirms it
+type EmailChange struct {
+	ID           int
+	UserID       int
+	NewEmail     string
+	ConfirmToken string
+	CancelToken  string
+	ExpiresAt    time.Time
+	CreatedAt    time.Time
+}
+
+const EmailChangeLifetime = 24 * time.Hour
+
+// handle build pending email change with fresh confirm and cancel tokens
+func NewEmailChange(userID int, newEmail string) (*EmailChange, error) {
+	confirmToken, err := GenerateToken()
+	if err != nil {
+		return nil, err
+	}
+
+	cancelToken, err := GenerateToken()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+
+	return &EmailChange{
+		UserID:       userID,
+		NewEmail:     newEmail,
+		ConfirmToken: confirmToken,
+		CancelToken:  cancelToken,
+		ExpiresAt:    now.Add(EmailChangeLifetime),
+		CreatedAt:    now,
+	}, nil
+}
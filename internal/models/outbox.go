@@ -0,0 +1,82 @@
+package models
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"time"
+	"user-auth-go/constants"
+)
+
+// envelope stored as outbox payload, sinks publish it as is
+type EventEnvelope struct {
+	ID        string      `json:"id"`
+	Type      string      `json:"type"`
+	CreatedAt time.Time   `json:"created_at"`
+	Data      interface{} `json:"data"`
+}
+
+type UserEventData struct {
+	ID           int    `json:"id"`
+	Email        string `json:"email"`
+	FullName     string `json:"full_name"`
+	Telephone    string `json:"telephone"`
+	AuthProvider string `json:"auth_provider"`
+}
+
+type SessionEventData struct {
+	UserID    int        `json:"user_id"`
+	SessionID int        `json:"session_id,omitempty"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	Revoked   int64      `json:"revoked,omitempty"`
+}
+
+// event written in the same transaction as the change it describes
+// AggregateID is the user the event belongs to, events of one user are published in order
+type OutboxEvent struct {
+	ID            int64
+	EventID       string
+	EventType     string
+	AggregateID   int
+	Payload       string
+	Status        string
+	Attempts      int
+	NextAttemptAt time.Time
+	LastError     string
+	CreatedAt     time.Time
+	SentAt        *time.Time
+}
+
+// claimed events are hidden from other dispatchers for this long
+const OutboxLease = 5 * time.Minute
+
+// handle build pending outbox event with its JSON envelope, repositories store it
+func NewOutboxEvent(aggregateID int, eventType string, data interface{}) (*OutboxEvent, error) {
+	id := make([]byte, 16)
+
+	if _, err := rand.Read(id); err != nil {
+		return nil, err
+	}
+
+	envelope := EventEnvelope{
+		ID:        "evt_" + hex.EncodeToString(id),
+		Type:      eventType,
+		CreatedAt: time.Now().UTC(),
+		Data:      data,
+	}
+
+	payload, err := json.Marshal(envelope)
+	if err != nil {
+		return nil, err
+	}
+
+	return &OutboxEvent{
+		EventID:       envelope.ID,
+		EventType:     eventType,
+		AggregateID:   aggregateID,
+		Payload:       string(payload),
+		Status:        constants.OutboxPending,
+		NextAttemptAt: envelope.CreatedAt,
+		CreatedAt:     envelope.CreatedAt,
+	}, nil
+}
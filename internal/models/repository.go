@@ -0,0 +1,103 @@
+package models
+
+import (
+	"context"
+	"time"
+)
+
+// repositories hide where data lives, sqlstore backs them with MySQL, SQLite or Postgres
+// and memory keeps everything in process for tests. Getters return nil without error when
+// nothing matches.
+// Every user and session change writes its outbox event atomically with the change.
+
+type UserRepository interface {
+	// Create returns ErrEmailExists when the email is taken
+	Create(ctx context.Context, email, hashedPassword string) (*User, error)
+	CreateWithGoogle(ctx context.Context, email, googleID, fullName string) (*User, error)
+	GetByID(ctx context.Context, id int) (*User, error)
+	// GetByIdentifier looks up by googleID when set, by email otherwise
+	GetByIdentifier(ctx context.Context, email, googleID string) (*User, error)
+	UpdateProfile(ctx context.Context, id int, fullName, telephone, email string) error
+	UpdatePassword(ctx context.Context, id int, hashedPassword string) error
+	UpdateRole(ctx context.Context, id int, role string) error
+	// SetDisabled blocks or restores the account, sessions are left to the caller
+	SetDisabled(ctx context.Context, id int, disabled bool) error
+	// Delete removes the user together with sessions and pending email changes
+	Delete(ctx context.Context, id int) error
+}
+
+type SessionRepository interface {
+	Create(ctx context.Context, userID int) (*Session, error)
+	// GetByToken ignores expired sessions
+	GetByToken(ctx context.Context, token string) (*Session, error)
+	Delete(ctx context.Context, token string) error
+	DeleteForUser(ctx context.Context, userID int) error
+	DeleteForUserExcept(ctx context.Context, userID int, token string) error
+	// DeleteExpired removes sessions past their expiry and returns how many
+	DeleteExpired(ctx context.Context) (int64, error)
+	// CountActive counts sessions that have not expired yet
+	CountActive(ctx context.Context) (int64, error)
+}
+
+type EmailChangeRepository interface {
+	// Create replaces any previous request of the user
+	Create(ctx context.Context, userID int, newEmail string) (*EmailChange, error)
+	// getters ignore expired requests
+	GetPending(ctx context.Context, userID int) (*EmailChange, error)
+	GetByConfirmToken(ctx context.Context, token string) (*EmailChange, error)
+	GetByCancelToken(ctx context.Context, token string) (*EmailChange, error)
+	// Confirm applies the new email and removes the request, ErrEmailExists when it got taken meanwhile
+	Confirm(ctx context.Context, change *EmailChange) error
+	Delete(ctx context.Context, id int) error
+}
+
+type AuditRepository interface {
+	Record(ctx context.Context, event *AuditEvent) error
+	// ListForUser returns the latest events where user is the actor or the target
+	ListForUser(ctx context.Context, userID int, limit int) ([]AuditEvent, error)
+	// Query returns matching events newest first
+	Query(ctx context.Context, filter AuditFilter) ([]AuditEvent, error)
+}
+
+type WebhookRepository interface {
+	CreateSubscription(ctx context.Context, url, secret string, events []string, description string) (*WebhookSubscription, error)
+	GetSubscription(ctx context.Context, id int) (*WebhookSubscription, error)
+	ListSubscriptions(ctx context.Context) ([]WebhookSubscription, error)
+	UpdateSubscription(ctx context.Context, sub *WebhookSubscription) error
+	DeleteSubscription(ctx context.Context, id int) error
+
+	// Enqueue queues the event for every active subscription interested in it,
+	// queuing the same event twice is a no-op
+	Enqueue(ctx context.Context, eventID, eventType, payload string) error
+	// ClaimDue leases due deliveries to the caller for DeliveryLease
+	ClaimDue(ctx context.Context, limit int) ([]WebhookDelivery, error)
+	RecordAttempt(ctx context.Context, delivery *WebhookDelivery, attempt WebhookDeliveryAttempt, status string, nextAttemptAt time.Time) error
+	GetDelivery(ctx context.Context, id int64) (*WebhookDelivery, error)
+	ListDeliveries(ctx context.Context, filter WebhookDeliveryFilter) ([]WebhookDelivery, error)
+	ListAttempts(ctx context.Context, deliveryID int64) ([]WebhookDeliveryAttempt, error)
+	// Redeliver puts the delivery back in the queue with its attempts counter reset,
+	// the attempts history is kept
+	Redeliver(ctx context.Context, id int64) error
+}
+
+type OutboxRepository interface {
+	// Claim leases due events for OutboxLease, only the oldest pending event of every
+	// user is returned so a user's events never overtake each other
+	Claim(ctx context.Context, limit int) ([]OutboxEvent, error)
+	MarkSent(ctx context.Context, id int64) error
+	// MarkFailed stores a failed publish, status is pending to retry at nextAttemptAt or failed to give up
+	MarkFailed(ctx context.Context, id int64, status string, nextAttemptAt time.Time, lastError string) error
+	ListForAggregate(ctx context.Context, aggregateID int) ([]OutboxEvent, error)
+	// DeleteSent removes events sent before the given time and returns how many
+	DeleteSent(ctx context.Context, before time.Time) (int64, error)
+}
+
+// Store bundles the repositories of one backend
+type Store struct {
+	Users        UserRepository
+	Sessions     SessionRepository
+	EmailChanges EmailChangeRepository
+	Audit        AuditRepository
+	Webhooks     WebhookRepository
+	Outbox       OutboxRepository
+}
@@ -1,15 +1,11 @@
 package models
 
 import (
-	"database/sql"
+	"context"
 	"errors"
-	"strings"
 	"time"
 	"user-auth-go/constants"
 	"user-auth-go/internal/config"
-
-	"github.com/go-sql-driver/mysql"
-	"golang.org/x/crypto/bcrypt"
 )
 
 type User struct {
@@ -20,126 +16,50 @@ type User struct {
 	Telephone    string
 	AuthProvider string
 	GoogleID     string
-	CreatedAt    time.Time
-	UpdatedAt    time.Time
+	Role         string
+	// set while an operator has disabled the account
+	DisabledAt *time.Time
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
 }
 
 func (u *User) CheckPassword(password string) bool {
-	err := bcrypt.CompareHashAndPassword([]byte(u.Password), []byte(password))
-	return err == nil
+	return u.CheckPasswordContext(context.Background(), password)
 }
 
-var (
-	ErrEmailExists = errors.New("email already exists")
-)
-
-// handle create user with provider type is `local`
-func CreateUser(email, password string) (*User, error) {
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
-
-	if err != nil {
-		return nil, err
-	}
-
-	result, err := config.DB.Exec("INSERT INTO users (email, password, auth_provider) VALUES (?, ? , ?)",
-		email, string(hashedPassword), constants.AuthProviderLocal)
-
-	if err != nil {
-		if isDuplicateEntryError(err) {
-			return nil, ErrEmailExists
-		}
-		return nil, err
+// handle CheckPassword with the verification traced under ctx
+func (u *User) CheckPasswordContext(ctx context.Context, password string) bool {
+	if u.Password == "" {
+		return false
 	}
 
-	id, _ := result.LastInsertId()
-	return &User{ID: int(id), Email: email, AuthProvider: constants.AuthProviderLocal}, nil
+	ok, err := config.PasswordHasher.VerifyContext(ctx, password, u.Password)
+	return err == nil && ok
 }
 
-// handle create user with provider type is `google`
-func CreateUserWithGoogle(email, googleID, fullName string) (*User, error) {
-	result, err := config.DB.Exec("INSERT INTO users (email, google_id, full_name, auth_provider) VALUES (?, ?, ?, ?)",
-		email, googleID, fullName, constants.AuthProviderGoogle)
-
-	if err != nil {
-		if isDuplicateEntryError(err) {
-			return nil, ErrEmailExists
-		}
-		return nil, err
-	}
-
-	id, _ := result.LastInsertId()
-	return &User{ID: int(id), Email: email, AuthProvider: constants.AuthProviderGoogle}, nil
+func (u *User) IsDisabled() bool {
+	return u.DisabledAt != nil
 }
 
-// handle get user from theirs ID
-func GetUserByID(id int) (*User, error) {
-	user := &User{}
-	err := config.DB.QueryRow(
-		"SELECT id, email, COALESCE(password, ''), COALESCE(full_name, ''), COALESCE(telephone, ''), auth_provider, COALESCE(google_id, '') FROM users WHERE id = ?",
-		id,
-	).Scan(&user.ID, &user.Email, &user.Password, &user.FullName, &user.Telephone, &user.AuthProvider, &user.GoogleID)
-
-	if err == sql.ErrNoRows {
-		return nil, nil
-	}
-	if err != nil {
-		return nil, err
-	}
-	return user, nil
+func (u *User) IsAdmin() bool {
+	return u.Role == constants.RoleAdmin
 }
 
-// handle get user based on identifier
-// identifier can be email or googleID
-func GetUserByIdentifier(email, googleID string) (*User, error) {
-	var condition string
-	var arg interface{}
-
-	if googleID != "" {
-		condition = "google_id = ?"
-		arg = googleID
-	} else {
-		condition = "email = ?"
-		arg = email
-	}
-
-	user := &User{}
-	err := config.DB.QueryRow(
-		"SELECT id, email, COALESCE(password, ''), COALESCE(full_name, ''), COALESCE(telephone, ''), auth_provider, COALESCE(google_id, '') FROM users WHERE "+condition,
-		arg,
-	).Scan(&user.ID, &user.Email, &user.Password, &user.FullName, &user.Telephone, &user.AuthProvider, &user.GoogleID)
-
-	if err == sql.ErrNoRows {
-		return nil, nil
-	}
-	if err != nil {
-		return nil, err
-	}
-	return user, nil
+// handle check stored hash uses outdated algorithm or params
+func (u *User) PasswordNeedsRehash() bool {
+	return u.Password != "" && config.PasswordHasher.NeedsRehash(u.Password)
 }
 
-// handle update user profile
-func UpdateUserProfile(id int, fullName, telephone, email string) error {
-	_, err := config.DB.Exec(
-		"UPDATE users SET full_name = ?, telephone = ?, email = ? WHERE id = ?",
-		fullName, telephone, email, id,
-	)
-	if err != nil {
-		if isDuplicateEntryError(err) {
-			return ErrEmailExists
-		}
-		return err
+func (u *User) EventData() UserEventData {
+	return UserEventData{
+		ID:           u.ID,
+		Email:        u.Email,
+		FullName:     u.FullName,
+		Telephone:    u.Telephone,
+		AuthProvider: u.AuthProvider,
 	}
-	return nil
 }
 
-// handle catch the error, with duplicate constraint error
-// with this we don't need to check manually is email is already exists or not
-func isDuplicateEntryError(err error) bool {
-	var mysqlErr *mysql.MySQLError
-
-	if errors.As(err, &mysqlErr) {
-		return mysqlErr.Number == 1062
-	}
-
-	return strings.Contains(err.Error(), "Duplicate entry")
-}
+var (
+	ErrEmailExists = errors.New("email already exists")
+)
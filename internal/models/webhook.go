@@ -0,0 +1,75 @@
+package models
+
+import (
+	"time"
+	"user-auth-go/constants"
+)
+
+type WebhookSubscription struct {
+	ID          int
+	URL         string
+	Secret      string
+	Events      []string
+	Description string
+	Active      bool
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+}
+
+// one event queued for one subscription, retried until it succeeds or runs out of attempts
+type WebhookDelivery struct {
+	ID             int64
+	SubscriptionID int
+	EventID        string
+	EventType      string
+	Payload        string
+	Status         string
+	Attempts       int
+	NextAttemptAt  time.Time
+	LastStatusCode *int
+	LastError      string
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+}
+
+type WebhookDeliveryAttempt struct {
+	ID         int64
+	DeliveryID int64
+	StatusCode *int
+	Error      string
+	Duration   time.Duration
+	CreatedAt  time.Time
+}
+
+type WebhookDeliveryFilter struct {
+	SubscriptionID int
+	Status         string
+	Limit          int
+}
+
+// claimed deliveries are hidden from other workers for this long
+const DeliveryLease = 5 * time.Minute
+
+func (s *WebhookSubscription) Subscribes(eventType string) bool {
+	for _, event := range s.Events {
+		if event == eventType || event == constants.WebhookAllEvents {
+			return true
+		}
+	}
+	return false
+}
+
+// handle clamp requested limit to a sane page size
+func (f WebhookDeliveryFilter) PageSize() int {
+	if f.Limit <= 0 || f.Limit > 500 {
+		return 100
+	}
+	return f.Limit
+}
+
+func (f WebhookDeliveryFilter) Matches(delivery WebhookDelivery) bool {
+	if f.SubscriptionID > 0 && delivery.SubscriptionID != f.SubscriptionID {
+		return false
+	}
+	return f.Status == "" || delivery.Status == f.Status
+}
@@ -0,0 +1,151 @@
+package outbox
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+	"user-auth-go/constants"
+	"user-auth-go/internal/config"
+	"user-auth-go/internal/models"
+	"user-auth-go/internal/webhooks"
+)
+
+// Sink receives every outbox event, Publish must be safe to repeat for the same event
+// because an event is retried until every sink accepted it
+type Sink interface {
+	Name() string
+	Publish(ctx context.Context, event models.OutboxEvent) error
+}
+
+// Dispatcher publishes pending outbox events to its sinks
+type Dispatcher struct {
+	Outbox      models.OutboxRepository
+	Sinks       []Sink
+	Interval    time.Duration
+	BatchSize   int
+	MaxAttempts int
+	BaseBackoff time.Duration
+	MaxBackoff  time.Duration
+	// sent events older than Retention are deleted every PruneInterval, 0 keeps them
+	Retention     time.Duration
+	PruneInterval time.Duration
+}
+
+func NewDispatcher(outbox models.OutboxRepository, sinks ...Sink) *Dispatcher {
+	return &Dispatcher{
+		Outbox:      outbox,
+		Sinks:       sinks,
+		Interval:    time.Second,
+		BatchSize:   50,
+		MaxAttempts: 20,
+		BaseBackoff: 5 * time.Second,
+		MaxBackoff:  time.Hour,
+
+		Retention:     config.OutboxRetention,
+		PruneInterval: time.Hour,
+	}
+}
+
+// handle build sinks listed in OUTBOX_SINKS
+func ConfiguredSinks(store *models.Store) []Sink {
+	var sinks []Sink
+
+	for _, name := range config.OutboxSinks {
+		switch name {
+		case "webhook":
+			sinks = append(sinks, WebhookSink{Webhooks: store.Webhooks})
+		case "log":
+			sinks = append(sinks, LogSink{})
+		case "nats":
+			sink := NewNATSSink(config.NATSURL, config.NATSSubjectPrefix)
+			sink.CredsFile = config.NATSCredsFile
+			sink.CAFile = config.NATSCAFile
+			sinks = append(sinks, sink)
+		}
+	}
+
+	return sinks
+}
+
+// handle poll for pending events until context is cancelled
+func (d *Dispatcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(d.Interval)
+	defer ticker.Stop()
+
+	var prunedAt time.Time
+
+	for {
+		if _, err := d.DispatchPending(ctx); err != nil {
+			slog.Error("Outbox dispatcher failed", "error", err)
+		}
+
+		if d.Retention > 0 && time.Since(prunedAt) >= d.PruneInterval {
+			prunedAt = time.Now()
+			if _, err := d.PruneSent(ctx); err != nil {
+				slog.Error("Outbox dispatcher failed to prune sent events", "error", err)
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// handle publish one batch of pending events, returns how many were attempted
+func (d *Dispatcher) DispatchPending(ctx context.Context) (int, error) {
+	events, err := d.Outbox.Claim(ctx, d.BatchSize)
+	if err != nil {
+		return 0, fmt.Errorf("failed to claim events: %w", err)
+	}
+
+	for _, event := range events {
+		if err := d.dispatch(ctx, event); err != nil {
+			slog.Error("Outbox dispatcher failed to publish event", "event_id", event.EventID, "error", err)
+		}
+	}
+
+	return len(events), nil
+}
+
+// handle delete events sent more than Retention ago, pending and failed events are kept
+func (d *Dispatcher) PruneSent(ctx context.Context) (int64, error) {
+	deleted, err := d.Outbox.DeleteSent(ctx, time.Now().Add(-d.Retention))
+	if err != nil {
+		return 0, err
+	}
+
+	if deleted > 0 {
+		slog.Info("Outbox dispatcher pruned sent events", "deleted", deleted)
+	}
+	return deleted, nil
+}
+
+func (d *Dispatcher) dispatch(ctx context.Context, event models.OutboxEvent) error {
+	var failures []string
+
+	for _, sink := range d.Sinks {
+		if err := sink.Publish(ctx, event); err != nil {
+			failures = append(failures, sink.Name()+": "+err.Error())
+		}
+	}
+
+	if len(failures) == 0 {
+		return d.Outbox.MarkSent(ctx, event.ID)
+	}
+
+	lastError := strings.Join(failures, "; ")
+	status := constants.OutboxPending
+	nextAttemptAt := time.Now().Add(webhooks.Backoff(event.Attempts+1, d.BaseBackoff, d.MaxBackoff))
+
+	if event.Attempts+1 >= d.MaxAttempts {
+		status = constants.OutboxFailed
+		slog.Warn("Outbox dispatcher giving up on event", "event_id", event.EventID, "attempts", event.Attempts+1)
+	}
+
+	return d.Outbox.MarkFailed(ctx, event.ID, status, nextAttemptAt, lastError)
+}
@@ -0,0 +1,106 @@
+package outbox
+
+import (
+	"context"
+	"sync"
+	"time"
+	"user-auth-go/internal/models"
+
+	"github.com/nats-io/nats.go"
+)
+
+// NATSSink publishes events with the official NATS client. Subjects are `<prefix>.<event type>`,
+// e.g. `user-auth.user.signed_up`. `tls://` URLs connect over TLS, user and password or a token
+// come from the URL and CredsFile holds a JWT and NKey seed. A publish only counts once the
+// server answered the flush that follows it.
+type NATSSink struct {
+	URL           string
+	SubjectPrefix string
+	CredsFile     string
+	CAFile        string
+	Timeout       time.Duration
+
+	mu   sync.Mutex
+	conn *nats.Conn
+}
+
+func NewNATSSink(url, subjectPrefix string) *NATSSink {
+	return &NATSSink{URL: url, SubjectPrefix: subjectPrefix, Timeout: 5 * time.Second}
+}
+
+func (s *NATSSink) Name() string { return "nats" }
+
+func (s *NATSSink) Publish(ctx context.Context, event models.OutboxEvent) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// the client reconnects on its own, a closed connection gave up and is replaced
+	if s.conn == nil || s.conn.IsClosed() {
+		conn, err := s.connect()
+		if err != nil {
+			return err
+		}
+		s.conn = conn
+	}
+
+	subject := event.EventType
+	if s.SubjectPrefix != "" {
+		subject = s.SubjectPrefix + "." + subject
+	}
+
+	// rejected publishes are reported asynchronously, the server sends them before the
+	// flush PONG so a new error after the flush belongs to this publish
+	previous := s.conn.LastError()
+
+	if err := s.conn.Publish(subject, []byte(event.Payload)); err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, s.Timeout)
+	defer cancel()
+
+	if err := s.conn.FlushWithContext(ctx); err != nil {
+		return err
+	}
+
+	if err := s.conn.LastError(); err != nil && err != previous {
+		return err
+	}
+
+	return nil
+}
+
+// handle flush buffered publishes and close the connection, the sink reconnects on the next publish
+func (s *NATSSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.conn == nil {
+		return nil
+	}
+
+	var err error
+	if !s.conn.IsClosed() {
+		err = s.conn.FlushTimeout(s.Timeout)
+	}
+
+	s.conn.Close()
+	s.conn = nil
+	return err
+}
+
+func (s *NATSSink) connect() (*nats.Conn, error) {
+	options := []nats.Option{
+		nats.Name("user-auth-go"),
+		nats.Timeout(s.Timeout),
+	}
+
+	if s.CredsFile != "" {
+		options = append(options, nats.UserCredentials(s.CredsFile))
+	}
+	if s.CAFile != "" {
+		options = append(options, nats.RootCAs(s.CAFile))
+	}
+
+	return nats.Connect(s.URL, options...)
+}
@@ -0,0 +1,49 @@
+package outbox
+
+import (
+	"context"
+	"log/slog"
+	"user-auth-go/internal/models"
+)
+
+// WebhookSink queues the event for webhook subscribers, the webhook worker delivers it
+type WebhookSink struct {
+	Webhooks models.WebhookRepository
+}
+
+func (WebhookSink) Name() string { return "webhook" }
+
+func (s WebhookSink) Publish(ctx context.Context, event models.OutboxEvent) error {
+	return s.Webhooks.Enqueue(ctx, event.EventID, event.EventType, event.Payload)
+}
+
+// LogSink writes events to the standard logger, handy in development
+type LogSink struct{}
+
+func (LogSink) Name() string { return "log" }
+
+func (LogSink) Publish(ctx context.Context, event models.OutboxEvent) error {
+	slog.InfoContext(ctx, "Outbox event", "event_id", event.EventID, "event_type", event.EventType, "user_id", event.AggregateID, "payload", event.Payload)
+	return nil
+}
+
+// ChannelSink hands events to in-process consumers
+type ChannelSink struct {
+	C chan models.OutboxEvent
+}
+
+func NewChannelSink(buffer int) *ChannelSink {
+	return &ChannelSink{C: make(chan models.OutboxEvent, buffer)}
+}
+
+func (s *ChannelSink) Name() string { return "channel" }
+
+// handle wait for a consumer, a cancelled context leaves the event pending
+func (s *ChannelSink) Publish(ctx context.Context, event models.OutboxEvent) error {
+	select {
+	case s.C <- event:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,94 @@
+package password
+
+import (
+	"bufio"
+	"crypto/sha1"
+	_ "embed"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+//go:embed data/breached_sha1.txt
+var bundledBreachedFile string
+
+// BreachedList is a local set of breached password SHA-1 hashes, grouped by the
+// 5 character prefix used by k-anonymity range files. Nothing leaves the process.
+type BreachedList struct {
+	byPrefix map[string]map[string]struct{}
+}
+
+// handle load the list bundled with the binary
+func BundledBreachedList() *BreachedList {
+	list := &BreachedList{byPrefix: make(map[string]map[string]struct{})}
+
+	// bundled file is generated, a parse error is a programming mistake
+	if err := list.Load(strings.NewReader(bundledBreachedFile)); err != nil {
+		panic(err)
+	}
+
+	return list
+}
+
+// handle add hashes from a file on top of the current list
+func (b *BreachedList) LoadFile(path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	if err := b.Load(file); err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	return nil
+}
+
+// handle read one SHA-1 hash per line, optionally followed by `:count` like the HIBP dumps
+func (b *BreachedList) Load(r io.Reader) error {
+	scanner := bufio.NewScanner(r)
+	lineNumber := 0
+
+	for scanner.Scan() {
+		lineNumber++
+		line := strings.TrimSpace(scanner.Text())
+
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		hash, _, _ := strings.Cut(line, ":")
+		hash = strings.ToUpper(hash)
+
+		if len(hash) != sha1.Size*2 {
+			return fmt.Errorf("line %d: expected 40 hex characters", lineNumber)
+		}
+
+		if _, err := hex.DecodeString(hash); err != nil {
+			return fmt.Errorf("line %d: %w", lineNumber, err)
+		}
+
+		prefix, suffix := hash[:5], hash[5:]
+		if b.byPrefix[prefix] == nil {
+			b.byPrefix[prefix] = make(map[string]struct{})
+		}
+		b.byPrefix[prefix][suffix] = struct{}{}
+	}
+
+	return scanner.Err()
+}
+
+func (b *BreachedList) Contains(password string) bool {
+	sum := sha1.Sum([]byte(password))
+	hash := strings.ToUpper(hex.EncodeToString(sum[:]))
+
+	suffixes, ok := b.byPrefix[hash[:5]]
+	if !ok {
+		return false
+	}
+
+	_, ok = suffixes[hash[5:]]
+	return ok
+}
@@ -0,0 +1,121 @@
+package password
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"time"
+	"user-auth-go/internal/metrics"
+	"user-auth-go/internal/tracing"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var (
+	ErrUnknownHashFormat = errors.New("unknown password hash format")
+	ErrVerifyOnly        = errors.New("hash algorithm is only supported for verification")
+)
+
+// Hasher hashes new passwords and verifies stored hashes
+// encoded hashes are self describing, they carry algorithm, params and salt
+type Hasher interface {
+	Hash(password string) (string, error)
+	Verify(password, encoded string) (bool, error)
+
+	// true when the encoded hash doesn't match this hasher's algorithm or params
+	NeedsRehash(encoded string) bool
+}
+
+// algorithm is a Hasher that can tell whether it understands an encoded hash
+type algorithm interface {
+	Hasher
+	Identify(encoded string) bool
+}
+
+// Manager hashes with the preferred algorithm and verifies every supported format,
+// including hashes imported from other systems (PBKDF2, scrypt)
+type Manager struct {
+	preferred  algorithm
+	algorithms []algorithm
+}
+
+func NewManager(preferred Hasher) (*Manager, error) {
+	p, ok := preferred.(algorithm)
+	if !ok {
+		return nil, errors.New("unsupported preferred password hasher")
+	}
+
+	return &Manager{
+		preferred: p,
+		algorithms: []algorithm{
+			p,
+			&Argon2id{},
+			&Bcrypt{},
+			&PBKDF2{},
+			&Scrypt{},
+		},
+	}, nil
+}
+
+func (m *Manager) Hash(password string) (string, error) {
+	return m.HashContext(context.Background(), password)
+}
+
+func (m *Manager) Verify(password, encoded string) (bool, error) {
+	return m.VerifyContext(context.Background(), password, encoded)
+}
+
+// handle Hash traced as a `password.hash` span under ctx
+func (m *Manager) HashContext(ctx context.Context, password string) (encoded string, err error) {
+	defer observe(ctx, m.preferred, "hash")(&err)
+	return m.preferred.Hash(password)
+}
+
+// handle Verify traced as a `password.verify` span under ctx
+func (m *Manager) VerifyContext(ctx context.Context, password, encoded string) (ok bool, err error) {
+	for _, algo := range m.algorithms {
+		if algo.Identify(encoded) {
+			defer observe(ctx, algo, "verify")(&err)
+			return algo.Verify(password, encoded)
+		}
+	}
+
+	return false, ErrUnknownHashFormat
+}
+
+// handle start a span and timer for a hash or verify, the returned func ends both
+// and records the duration in the password_hash_duration_seconds metric
+func observe(ctx context.Context, algo algorithm, operation string) func(*error) {
+	start := time.Now()
+	name := algorithmName(algo)
+	_, span := tracing.Start(ctx, "password."+operation, trace.WithAttributes(attribute.String("password.algorithm", name)))
+
+	return func(err *error) {
+		metrics.PasswordHashDuration.WithLabelValues(name, operation).Observe(time.Since(start).Seconds())
+		tracing.RecordError(span, *err)
+		span.End()
+	}
+}
+
+func algorithmName(algo algorithm) string {
+	switch algo.(type) {
+	case *Argon2id:
+		return "argon2id"
+	case *Bcrypt:
+		return "bcrypt"
+	case *PBKDF2:
+		return "pbkdf2"
+	case *Scrypt:
+		return "scrypt"
+	}
+	return "unknown"
+}
+
+func (m *Manager) NeedsRehash(encoded string) bool {
+	return !m.preferred.Identify(encoded) || m.preferred.NeedsRehash(encoded)
+}
+
+func constantTimeEqual(a, b []byte) bool {
+	return subtle.ConstantTimeCompare(a, b) == 1
+}
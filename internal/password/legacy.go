@@ -0,0 +1,176 @@
+package password
+
+import (
+	"crypto/pbkdf2"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"fmt"
+	"hash"
+	"strconv"
+	"strings"
+
+	"golang.org/x/crypto/scrypt"
+)
+
+// hashes from other systems are only verified, a successful login
+// rehashes them with the preferred algorithm
+
+// shorter stored digests are truncated or corrupt, an empty one would match any password
+const minLegacyKeyLength = 16
+
+// PBKDF2 verifies Django (`pbkdf2_sha256$iter$salt$hash`) and
+// passlib (`$pbkdf2-sha256$iter$salt$hash`) encoded hashes
+type PBKDF2 struct{}
+
+var pbkdf2Digests = map[string]func() hash.Hash{
+	"pbkdf2_sha1":    sha1.New,
+	"pbkdf2_sha256":  sha256.New,
+	"$pbkdf2":        sha1.New,
+	"$pbkdf2-sha256": sha256.New,
+	"$pbkdf2-sha512": sha512.New,
+}
+
+func (p *PBKDF2) Identify(encoded string) bool {
+	scheme, _, _ := strings.Cut(encoded, "$")
+
+	if scheme == "" {
+		// passlib format starts with `$`
+		rest := strings.TrimPrefix(encoded, "$")
+		name, _, _ := strings.Cut(rest, "$")
+		scheme = "$" + name
+	}
+
+	_, ok := pbkdf2Digests[scheme]
+	return ok
+}
+
+func (p *PBKDF2) Hash(password string) (string, error) {
+	return "", ErrVerifyOnly
+}
+
+func (p *PBKDF2) Verify(password, encoded string) (bool, error) {
+	var (
+		digest     func() hash.Hash
+		iterations int
+		salt, key  []byte
+		err        error
+	)
+
+	if strings.HasPrefix(encoded, "$") {
+		// "", scheme, iterations, salt, hash
+		parts := strings.Split(encoded, "$")
+		if len(parts) != 5 {
+			return false, ErrUnknownHashFormat
+		}
+
+		digest = pbkdf2Digests["$"+parts[1]]
+
+		if salt, err = decodeAdaptedBase64(parts[3]); err != nil {
+			return false, fmt.Errorf("invalid pbkdf2 salt: %w", err)
+		}
+
+		if key, err = decodeAdaptedBase64(parts[4]); err != nil {
+			return false, fmt.Errorf("invalid pbkdf2 hash: %w", err)
+		}
+
+		iterations, err = strconv.Atoi(parts[2])
+	} else {
+		// scheme, iterations, salt, hash
+		parts := strings.Split(encoded, "$")
+		if len(parts) != 4 {
+			return false, ErrUnknownHashFormat
+		}
+
+		digest = pbkdf2Digests[parts[0]]
+		salt = []byte(parts[2])
+
+		if key, err = base64.StdEncoding.DecodeString(parts[3]); err != nil {
+			return false, fmt.Errorf("invalid pbkdf2 hash: %w", err)
+		}
+
+		iterations, err = strconv.Atoi(parts[1])
+	}
+
+	if err != nil || iterations <= 0 {
+		return false, fmt.Errorf("invalid pbkdf2 iterations")
+	}
+
+	if digest == nil || len(key) < minLegacyKeyLength {
+		return false, ErrUnknownHashFormat
+	}
+
+	other, err := pbkdf2.Key(digest, password, salt, iterations, len(key))
+	if err != nil {
+		return false, err
+	}
+
+	return constantTimeEqual(key, other), nil
+}
+
+func (p *PBKDF2) NeedsRehash(encoded string) bool {
+	return true
+}
+
+// Scrypt verifies passlib encoded hashes: `$scrypt$ln=16,r=8,p=1$salt$hash`
+type Scrypt struct{}
+
+func (s *Scrypt) Identify(encoded string) bool {
+	return strings.HasPrefix(encoded, "$scrypt$")
+}
+
+func (s *Scrypt) Hash(password string) (string, error) {
+	return "", ErrVerifyOnly
+}
+
+func (s *Scrypt) Verify(password, encoded string) (bool, error) {
+	// "", "scrypt", params, salt, hash
+	parts := strings.Split(encoded, "$")
+	if len(parts) != 5 {
+		return false, ErrUnknownHashFormat
+	}
+
+	var logN, r, p int
+	if _, err := fmt.Sscanf(parts[2], "ln=%d,r=%d,p=%d", &logN, &r, &p); err != nil {
+		return false, fmt.Errorf("invalid scrypt params: %w", err)
+	}
+
+	if logN <= 0 || logN >= 32 {
+		return false, fmt.Errorf("invalid scrypt cost ln=%d", logN)
+	}
+
+	if r <= 0 || p <= 0 {
+		return false, fmt.Errorf("invalid scrypt params r=%d,p=%d", r, p)
+	}
+
+	salt, err := decodeAdaptedBase64(parts[3])
+	if err != nil {
+		return false, fmt.Errorf("invalid scrypt salt: %w", err)
+	}
+
+	key, err := decodeAdaptedBase64(parts[4])
+	if err != nil {
+		return false, fmt.Errorf("invalid scrypt hash: %w", err)
+	}
+
+	if len(key) < minLegacyKeyLength {
+		return false, ErrUnknownHashFormat
+	}
+
+	other, err := scrypt.Key([]byte(password), salt, 1<<logN, r, p, len(key))
+	if err != nil {
+		return false, err
+	}
+
+	return constantTimeEqual(key, other), nil
+}
+
+func (s *Scrypt) NeedsRehash(encoded string) bool {
+	return true
+}
+
+// passlib "adapted base64" uses `.` instead of `+` and no padding
+func decodeAdaptedBase64(value string) ([]byte, error) {
+	return base64.RawStdEncoding.DecodeString(strings.ReplaceAll(value, ".", "+"))
+}
@@ -0,0 +1,111 @@
+package password
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// rule identifiers returned in violations, stable for clients
+const (
+	RuleMinLength    = "min_length"
+	RuleMaxLength    = "max_length"
+	RuleUppercase    = "uppercase"
+	RuleLowercase    = "lowercase"
+	RuleDigit        = "digit"
+	RuleSymbol       = "symbol"
+	RulePersonalInfo = "personal_info"
+	RuleStrength     = "strength"
+	RuleBreached     = "breached"
+)
+
+type Policy struct {
+	MinLength     int
+	MaxLength     int
+	RequireUpper  bool
+	RequireLower  bool
+	RequireDigit  bool
+	RequireSymbol bool
+
+	// minimum EstimateStrength score, 0 disables the check
+	MinStrength int
+
+	// nil disables the breached password check
+	Breached *BreachedList
+}
+
+type Violation struct {
+	Rule    string
+	Message string
+}
+
+// handle check password against every rule and return all failures
+// personal holds values the password must not contain, like email or full name
+func (p *Policy) Validate(password string, personal ...string) []Violation {
+	var violations []Violation
+
+	length := utf8.RuneCountInString(password)
+
+	if length < p.MinLength {
+		violations = append(violations, Violation{RuleMinLength, fmt.Sprintf("Password must be at least %d characters", p.MinLength)})
+	}
+
+	if p.MaxLength > 0 && length > p.MaxLength {
+		violations = append(violations, Violation{RuleMaxLength, fmt.Sprintf("Password must be at most %d characters", p.MaxLength)})
+	}
+
+	if p.RequireUpper && strings.IndexFunc(password, unicode.IsUpper) < 0 {
+		violations = append(violations, Violation{RuleUppercase, "Password must contain an uppercase letter"})
+	}
+
+	if p.RequireLower && strings.IndexFunc(password, unicode.IsLower) < 0 {
+		violations = append(violations, Violation{RuleLowercase, "Password must contain a lowercase letter"})
+	}
+
+	if p.RequireDigit && strings.IndexFunc(password, unicode.IsDigit) < 0 {
+		violations = append(violations, Violation{RuleDigit, "Password must contain a digit"})
+	}
+
+	if p.RequireSymbol && strings.IndexFunc(password, isSymbol) < 0 {
+		violations = append(violations, Violation{RuleSymbol, "Password must contain a symbol"})
+	}
+
+	if containsPersonalInfo(password, personal) {
+		violations = append(violations, Violation{RulePersonalInfo, "Password must not contain your email or name"})
+	}
+
+	if p.Breached != nil && p.Breached.Contains(password) {
+		violations = append(violations, Violation{RuleBreached, "Password has appeared in a data breach, please choose another one"})
+	} else if p.MinStrength > 0 && EstimateStrength(password, personal...) < p.MinStrength {
+		violations = append(violations, Violation{RuleStrength, "Password is too easy to guess"})
+	}
+
+	return violations
+}
+
+// only the local part of an email counts, names are split into words
+func containsPersonalInfo(password string, personal []string) bool {
+	lower := strings.ToLower(password)
+
+	for _, value := range personal {
+		value = strings.ToLower(value)
+
+		if local, _, ok := strings.Cut(value, "@"); ok {
+			value = local
+		}
+
+		for _, token := range strings.FieldsFunc(value, isSeparator) {
+			// very short tokens would reject too many good passwords
+			if utf8.RuneCountInString(token) >= 3 && strings.Contains(lower, token) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+func isSymbol(r rune) bool {
+	return !unicode.IsLetter(r) && !unicode.IsDigit(r) && !unicode.IsSpace(r)
+}
@@ -0,0 +1,251 @@
+package password
+
+import (
+	_ "embed"
+	"math"
+	"strings"
+	"unicode"
+)
+
+//go:embed data/common_passwords.txt
+var commonPasswordsFile string
+
+// common passwords ranked by popularity, rank starts at 1
+var commonPasswords = loadRankedWords(commonPasswordsFile)
+
+var keyboardRows = []string{
+	"1234567890",
+	"qwertyuiop",
+	"asdfghjkl",
+	"zxcvbnm",
+	"1qaz2wsx3edc4rfv5tgb6yhn7ujm8ik9ol0p",
+}
+
+var leetSubstitutions = strings.NewReplacer(
+	"@", "a", "4", "a", "8", "b", "3", "e", "6", "g", "1", "i", "!", "i",
+	"0", "o", "$", "s", "5", "s", "7", "t", "+", "t", "2", "z",
+)
+
+// EstimateStrength gives a zxcvbn-style score from 0 (too guessable) to 4 (very unguessable).
+// Password is split into dictionary words, user inputs, repeats, sequences and keyboard
+// patterns; each pattern only costs a few bits, the rest is brute force over the charset
+func EstimateStrength(password string, userInputs ...string) int {
+	return scoreFromBits(estimateBits(password, userInputs))
+}
+
+func estimateBits(password string, userInputs []string) float64 {
+	runes := []rune(password)
+	if len(runes) == 0 {
+		return 0
+	}
+
+	lower := strings.ToLower(password)
+	if rank, ok := commonPasswords[lower]; ok {
+		return math.Log2(float64(rank))
+	}
+
+	if rank, ok := commonPasswords[leetSubstitutions.Replace(lower)]; ok {
+		return math.Log2(float64(rank)) + 1
+	}
+
+	m := &matcher{
+		lower:   []rune(lower),
+		covered: make([]bool, len(runes)),
+	}
+
+	m.matchDictionary(userInputs)
+	m.matchKeyboard()
+	m.matchSequences()
+	m.matchRepeats()
+
+	// everything not explained by a pattern is brute forced over the used charset
+	charsetBits := math.Log2(float64(charsetSize(password)))
+	for _, covered := range m.covered {
+		if !covered {
+			m.bits += charsetBits
+		}
+	}
+
+	return m.bits
+}
+
+// score thresholds follow zxcvbn: 10^3, 10^6, 10^8 and 10^10 guesses
+func scoreFromBits(bits float64) int {
+	switch {
+	case bits < 3*math.Log2(10):
+		return 0
+	case bits < 6*math.Log2(10):
+		return 1
+	case bits < 8*math.Log2(10):
+		return 2
+	case bits < 10*math.Log2(10):
+		return 3
+	default:
+		return 4
+	}
+}
+
+type matcher struct {
+	lower   []rune
+	covered []bool
+	bits    float64
+}
+
+// handle mark range as explained by a pattern, overlapping patterns are skipped
+func (m *matcher) cover(start, end int, bits float64) bool {
+	for i := start; i < end; i++ {
+		if m.covered[i] {
+			return false
+		}
+	}
+
+	for i := start; i < end; i++ {
+		m.covered[i] = true
+	}
+
+	m.bits += bits
+	return true
+}
+
+func (m *matcher) matchDictionary(userInputs []string) {
+	words := make(map[string]int, len(commonPasswords)+len(userInputs))
+	for word, rank := range commonPasswords {
+		words[word] = rank
+	}
+
+	// user inputs are the first thing an attacker would try
+	for _, input := range userInputs {
+		for _, token := range strings.FieldsFunc(strings.ToLower(input), isSeparator) {
+			words[token] = 1
+		}
+	}
+
+	normalized := []rune(leetSubstitutions.Replace(string(m.lower)))
+	if len(normalized) != len(m.lower) {
+		normalized = m.lower
+	}
+
+	// longest matches first so "password" wins over "pass"
+	for length := len(m.lower); length >= 3; length-- {
+		for start := 0; start+length <= len(m.lower); start++ {
+			if rank, ok := words[string(m.lower[start:start+length])]; ok {
+				m.cover(start, start+length, math.Log2(float64(rank)))
+				continue
+			}
+
+			// l33t variations cost one extra bit
+			if rank, ok := words[string(normalized[start:start+length])]; ok {
+				m.cover(start, start+length, math.Log2(float64(rank))+1)
+			}
+		}
+	}
+}
+
+func (m *matcher) matchKeyboard() {
+	for _, row := range keyboardRows {
+		for start := 0; start < len(m.lower); start++ {
+			offset := strings.IndexRune(row, m.lower[start])
+			if offset < 0 {
+				continue
+			}
+
+			end := start + 1
+			for end < len(m.lower) && offset+end-start < len(row) && rune(row[offset+end-start]) == m.lower[end] {
+				end++
+			}
+
+			if end-start >= 4 {
+				m.cover(start, end, math.Log2(float64(len(keyboardRows)*len(row)*(end-start))))
+			}
+		}
+	}
+}
+
+func (m *matcher) matchSequences() {
+	for start := 0; start < len(m.lower)-2; {
+		delta := m.lower[start+1] - m.lower[start]
+		end := start + 1
+
+		if delta == 1 || delta == -1 {
+			for end+1 < len(m.lower) && m.lower[end+1]-m.lower[end] == delta {
+				end++
+			}
+		}
+
+		if end-start+1 >= 3 {
+			m.cover(start, end+1, math.Log2(float64(26*(end-start+1))))
+			start = end + 1
+			continue
+		}
+
+		start++
+	}
+}
+
+func (m *matcher) matchRepeats() {
+	for start := 0; start < len(m.lower); {
+		end := start
+		for end+1 < len(m.lower) && m.lower[end+1] == m.lower[start] {
+			end++
+		}
+
+		if end-start+1 >= 3 {
+			m.cover(start, end+1, math.Log2(float64(charsetSize(string(m.lower[start]))*(end-start+1))))
+		}
+
+		start = end + 1
+	}
+}
+
+func charsetSize(password string) int {
+	var lower, upper, digit, symbol bool
+
+	for _, r := range password {
+		switch {
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsDigit(r):
+			digit = true
+		default:
+			symbol = true
+		}
+	}
+
+	size := 0
+	if lower {
+		size += 26
+	}
+	if upper {
+		size += 26
+	}
+	if digit {
+		size += 10
+	}
+	if symbol {
+		size += 33
+	}
+	return size
+}
+
+func isSeparator(r rune) bool {
+	return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+}
+
+func loadRankedWords(data string) map[string]int {
+	words := make(map[string]int)
+
+	for i, line := range strings.Split(data, "\n") {
+		word := strings.TrimSpace(line)
+		if word == "" {
+			continue
+		}
+
+		if _, exists := words[word]; !exists {
+			words[word] = i + 1
+		}
+	}
+
+	return words
+}
@@ -0,0 +1,160 @@
+package secrets
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// first line of every secrets file, the version picks the format of the rest
+const header = "user-auth-secrets:v1"
+
+// master keys are 32 random bytes, base64 encoded where they are stored
+const KeySize = 32
+
+var (
+	ErrInvalidKey  = errors.New("master key must be 32 bytes, base64 encoded")
+	ErrInvalidFile = errors.New("not a secrets file")
+	// returned when the key does not match or the file was modified
+	ErrDecrypt = errors.New("failed to decrypt secrets file, wrong master key or corrupted file")
+)
+
+// handle new random master key, base64 encoded
+func GenerateKey() (string, error) {
+	key := make([]byte, KeySize)
+	if _, err := rand.Read(key); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(key), nil
+}
+
+// handle decode base64 master key
+func ParseKey(encoded string) ([]byte, error) {
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+	if err != nil || len(key) != KeySize {
+		return nil, ErrInvalidKey
+	}
+	return key, nil
+}
+
+// handle encrypt values with AES-256-GCM, the header is authenticated too
+func Seal(key []byte, values map[string]string) ([]byte, error) {
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+
+	plaintext, err := json.Marshal(values)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+
+	sealed := aead.Seal(nonce, nonce, plaintext, []byte(header))
+	return []byte(header + "\n" + base64.StdEncoding.EncodeToString(sealed) + "\n"), nil
+}
+
+// handle decrypt content written by Seal
+func Open(key []byte, content []byte) (map[string]string, error) {
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+
+	first, body, _ := strings.Cut(string(content), "\n")
+	if strings.TrimSpace(first) != header {
+		return nil, ErrInvalidFile
+	}
+
+	sealed, err := base64.StdEncoding.DecodeString(strings.TrimSpace(body))
+	if err != nil || len(sealed) < aead.NonceSize() {
+		return nil, ErrInvalidFile
+	}
+
+	nonce, ciphertext := sealed[:aead.NonceSize()], sealed[aead.NonceSize():]
+
+	plaintext, err := aead.Open(nil, nonce, ciphertext, []byte(header))
+	if err != nil {
+		return nil, ErrDecrypt
+	}
+
+	values := map[string]string{}
+	if err := json.Unmarshal(plaintext, &values); err != nil {
+		return nil, ErrInvalidFile
+	}
+
+	return values, nil
+}
+
+// handle read and decrypt secrets file, a missing file holds no secrets
+func ReadFile(path string, key []byte) (map[string]string, error) {
+	content, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return map[string]string{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	values, err := Open(key, content)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	return values, nil
+}
+
+// handle encrypt and write secrets file, replaced atomically so a running
+// server reloading it never reads half a file
+func WriteFile(path string, key []byte, values map[string]string) error {
+	content, err := Seal(key, values)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".secrets-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(content); err != nil {
+		tmp.Close()
+		return err
+	}
+
+	if err := tmp.Chmod(0o600); err != nil {
+		tmp.Close()
+		return err
+	}
+
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), path)
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	if len(key) != KeySize {
+		return nil, ErrInvalidKey
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,50 @@
+package memory
+
+import (
+	"context"
+	"time"
+	"user-auth-go/internal/models"
+)
+
+type auditRepository struct {
+	*state
+}
+
+func (repo *auditRepository) Record(ctx context.Context, event *models.AuditEvent) error {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	repo.lastAuditID++
+	event.ID = repo.lastAuditID
+	event.CreatedAt = time.Now()
+
+	repo.audit = append(repo.audit, *event)
+	return nil
+}
+
+func (repo *auditRepository) ListForUser(ctx context.Context, userID int, limit int) ([]models.AuditEvent, error) {
+	return repo.Query(ctx, models.AuditFilter{UserID: &userID, Limit: limit})
+}
+
+func (repo *auditRepository) Query(ctx context.Context, filter models.AuditFilter) ([]models.AuditEvent, error) {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	events := []models.AuditEvent{}
+	skipped := 0
+
+	for i := len(repo.audit) - 1; i >= 0 && len(events) < filter.PageSize(); i-- {
+		if !filter.Matches(repo.audit[i]) {
+			continue
+		}
+
+		if skipped < filter.Offset {
+			skipped++
+			continue
+		}
+
+		events = append(events, repo.audit[i])
+	}
+
+	return events, nil
+}
@@ -0,0 +1,97 @@
+package memory
+
+import (
+	"context"
+	"time"
+	"user-auth-go/constants"
+	"user-auth-go/internal/models"
+)
+
+type emailChangeRepository struct {
+	*state
+}
+
+func (repo *emailChangeRepository) Create(ctx context.Context, userID int, newEmail string) (*models.EmailChange, error) {
+	change, err := models.NewEmailChange(userID, newEmail)
+	if err != nil {
+		return nil, err
+	}
+
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	for id, existing := range repo.emailChanges {
+		if existing.UserID == userID {
+			delete(repo.emailChanges, id)
+		}
+	}
+
+	repo.lastEmailChangeID++
+	change.ID = repo.lastEmailChangeID
+	repo.emailChanges[change.ID] = change
+
+	created := *change
+	return &created, nil
+}
+
+func (repo *emailChangeRepository) GetPending(ctx context.Context, userID int) (*models.EmailChange, error) {
+	return repo.find(func(change *models.EmailChange) bool { return change.UserID == userID })
+}
+
+func (repo *emailChangeRepository) GetByConfirmToken(ctx context.Context, token string) (*models.EmailChange, error) {
+	return repo.find(func(change *models.EmailChange) bool { return change.ConfirmToken == token })
+}
+
+func (repo *emailChangeRepository) GetByCancelToken(ctx context.Context, token string) (*models.EmailChange, error) {
+	return repo.find(func(change *models.EmailChange) bool { return change.CancelToken == token })
+}
+
+func (repo *emailChangeRepository) find(match func(*models.EmailChange) bool) (*models.EmailChange, error) {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	now := time.Now()
+
+	for _, change := range repo.emailChanges {
+		if match(change) && change.ExpiresAt.After(now) {
+			found := *change
+			return &found, nil
+		}
+	}
+	return nil, nil
+}
+
+func (repo *emailChangeRepository) Confirm(ctx context.Context, change *models.EmailChange) error {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	if repo.emailTaken(change.NewEmail, change.UserID) {
+		return models.ErrEmailExists
+	}
+
+	delete(repo.emailChanges, change.ID)
+
+	user, ok := repo.users[change.UserID]
+	if !ok {
+		return nil
+	}
+
+	updated := *user
+	updated.Email = change.NewEmail
+	updated.UpdatedAt = time.Now()
+
+	if err := repo.writeOutboxEvent(user.ID, constants.WebhookUserEmailVerified, updated.EventData()); err != nil {
+		return err
+	}
+
+	repo.users[user.ID] = &updated
+	return nil
+}
+
+func (repo *emailChangeRepository) Delete(ctx context.Context, id int) error {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	delete(repo.emailChanges, id)
+	return nil
+}
@@ -0,0 +1,62 @@
+package memory
+
+import (
+	"sync"
+	"user-auth-go/internal/models"
+)
+
+// state shared by every repository of one store, the single lock makes each
+// change and its outbox event atomic the same way a SQL transaction does
+type state struct {
+	mu sync.Mutex
+
+	users         map[int]*models.User
+	sessions      map[string]*models.Session
+	emailChanges  map[int]*models.EmailChange
+	audit         []models.AuditEvent
+	subscriptions map[int]*models.WebhookSubscription
+	deliveries    []*models.WebhookDelivery
+	attempts      []models.WebhookDeliveryAttempt
+	outbox        []*models.OutboxEvent
+
+	lastUserID         int
+	lastSessionID      int
+	lastEmailChangeID  int
+	lastSubscriptionID int
+	lastAuditID        int64
+	lastDeliveryID     int64
+	lastAttemptID      int64
+	lastOutboxID       int64
+}
+
+// handle build empty store keeping everything in process memory, meant for tests
+func New() *models.Store {
+	s := &state{
+		users:         map[int]*models.User{},
+		sessions:      map[string]*models.Session{},
+		emailChanges:  map[int]*models.EmailChange{},
+		subscriptions: map[int]*models.WebhookSubscription{},
+	}
+
+	return &models.Store{
+		Users:        &userRepository{s},
+		Sessions:     &sessionRepository{s},
+		EmailChanges: &emailChangeRepository{s},
+		Audit:        &auditRepository{s},
+		Webhooks:     &webhookRepository{s},
+		Outbox:       &outboxRepository{s},
+	}
+}
+
+// handle append outbox event, caller holds the lock
+func (s *state) writeOutboxEvent(aggregateID int, eventType string, data interface{}) error {
+	event, err := models.NewOutboxEvent(aggregateID, eventType, data)
+	if err != nil {
+		return err
+	}
+
+	s.lastOutboxID++
+	event.ID = s.lastOutboxID
+	s.outbox = append(s.outbox, event)
+	return nil
+}
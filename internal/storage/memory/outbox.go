@@ -0,0 +1,116 @@
+package memory
+
+import (
+	"context"
+	"time"
+	"user-auth-go/constants"
+	"user-auth-go/internal/models"
+)
+
+type outboxRepository struct {
+	*state
+}
+
+func (repo *outboxRepository) Claim(ctx context.Context, limit int) ([]models.OutboxEvent, error) {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	now := time.Now()
+	blocked := map[int]bool{}
+	events := []models.OutboxEvent{}
+
+	for _, event := range repo.outbox {
+		if len(events) >= limit {
+			break
+		}
+
+		if event.Status != constants.OutboxPending {
+			continue
+		}
+
+		// only the oldest pending event of a user may go out
+		if blocked[event.AggregateID] {
+			continue
+		}
+		blocked[event.AggregateID] = true
+
+		if event.NextAttemptAt.After(now) {
+			continue
+		}
+
+		events = append(events, *event)
+		event.NextAttemptAt = now.Add(models.OutboxLease)
+	}
+
+	return events, nil
+}
+
+func (repo *outboxRepository) MarkSent(ctx context.Context, id int64) error {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	if event := repo.outboxEvent(id); event != nil {
+		now := time.Now()
+		event.Status = constants.OutboxSent
+		event.Attempts++
+		event.LastError = ""
+		event.SentAt = &now
+	}
+	return nil
+}
+
+func (repo *outboxRepository) DeleteSent(ctx context.Context, before time.Time) (int64, error) {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	var deleted int64
+	kept := repo.outbox[:0]
+
+	for _, event := range repo.outbox {
+		if event.Status == constants.OutboxSent && event.SentAt != nil && event.SentAt.Before(before) {
+			deleted++
+			continue
+		}
+		kept = append(kept, event)
+	}
+	repo.outbox = kept
+
+	return deleted, nil
+}
+
+func (repo *outboxRepository) MarkFailed(ctx context.Context, id int64, status string, nextAttemptAt time.Time, lastError string) error {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	if event := repo.outboxEvent(id); event != nil {
+		event.Status = status
+		event.Attempts++
+		event.NextAttemptAt = nextAttemptAt
+		event.LastError = lastError
+	}
+	return nil
+}
+
+func (repo *outboxRepository) ListForAggregate(ctx context.Context, aggregateID int) ([]models.OutboxEvent, error) {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	events := []models.OutboxEvent{}
+
+	for _, event := range repo.outbox {
+		if event.AggregateID == aggregateID {
+			events = append(events, *event)
+		}
+	}
+
+	return events, nil
+}
+
+func (s *state) outboxEvent(id int64) *models.OutboxEvent {
+	for _, event := range s.outbox {
+		if event.ID == id {
+			return event
+		}
+	}
+	return nil
+}
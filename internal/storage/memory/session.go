@@ -0,0 +1,134 @@
+package memory
+
+import (
+	"context"
+	"time"
+	"user-auth-go/constants"
+	"user-auth-go/internal/models"
+)
+
+type sessionRepository struct {
+	*state
+}
+
+func (repo *sessionRepository) Create(ctx context.Context, userID int) (*models.Session, error) {
+	session, err := models.NewSession(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	repo.lastSessionID++
+	session.ID = repo.lastSessionID
+
+	data := models.SessionEventData{UserID: userID, SessionID: session.ID, ExpiresAt: &session.ExpiresAt}
+	if err := repo.writeOutboxEvent(userID, constants.WebhookSessionCreated, data); err != nil {
+		return nil, err
+	}
+
+	repo.sessions[session.Token] = session
+
+	created := *session
+	return &created, nil
+}
+
+func (repo *sessionRepository) GetByToken(ctx context.Context, token string) (*models.Session, error) {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	session, ok := repo.sessions[token]
+	if !ok || !session.ExpiresAt.After(time.Now()) {
+		return nil, nil
+	}
+
+	found := *session
+	return &found, nil
+}
+
+func (repo *sessionRepository) Delete(ctx context.Context, token string) error {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	session, ok := repo.sessions[token]
+	if !ok {
+		return nil
+	}
+
+	data := models.SessionEventData{UserID: session.UserID, SessionID: session.ID}
+	if err := repo.writeOutboxEvent(session.UserID, constants.WebhookSessionRevoked, data); err != nil {
+		return err
+	}
+
+	delete(repo.sessions, token)
+	return nil
+}
+
+func (repo *sessionRepository) DeleteForUser(ctx context.Context, userID int) error {
+	return repo.revoke(userID, "")
+}
+
+func (repo *sessionRepository) DeleteForUserExcept(ctx context.Context, userID int, token string) error {
+	return repo.revoke(userID, token)
+}
+
+func (repo *sessionRepository) revoke(userID int, keepToken string) error {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	var tokens []string
+
+	for token, session := range repo.sessions {
+		if session.UserID == userID && token != keepToken {
+			tokens = append(tokens, token)
+		}
+	}
+
+	if len(tokens) == 0 {
+		return nil
+	}
+
+	data := models.SessionEventData{UserID: userID, Revoked: int64(len(tokens))}
+	if err := repo.writeOutboxEvent(userID, constants.WebhookSessionRevoked, data); err != nil {
+		return err
+	}
+
+	for _, token := range tokens {
+		delete(repo.sessions, token)
+	}
+	return nil
+}
+
+func (repo *sessionRepository) DeleteExpired(ctx context.Context) (int64, error) {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	var deleted int64
+	now := time.Now()
+
+	for token, session := range repo.sessions {
+		if !session.ExpiresAt.After(now) {
+			delete(repo.sessions, token)
+			deleted++
+		}
+	}
+
+	return deleted, nil
+}
+
+func (repo *sessionRepository) CountActive(ctx context.Context) (int64, error) {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	var active int64
+	now := time.Now()
+
+	for _, session := range repo.sessions {
+		if session.ExpiresAt.After(now) {
+			active++
+		}
+	}
+
+	return active, nil
+}
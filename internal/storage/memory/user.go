@@ -0,0 +1,185 @@
+package memory
+
+import (
+	"context"
+	"time"
+	"user-auth-go/constants"
+	"user-auth-go/internal/models"
+)
+
+type userRepository struct {
+	*state
+}
+
+func (repo *userRepository) Create(ctx context.Context, email, hashedPassword string) (*models.User, error) {
+	return repo.insert(&models.User{Email: email, Password: hashedPassword, AuthProvider: constants.AuthProviderLocal})
+}
+
+func (repo *userRepository) CreateWithGoogle(ctx context.Context, email, googleID, fullName string) (*models.User, error) {
+	return repo.insert(&models.User{Email: email, GoogleID: googleID, FullName: fullName, AuthProvider: constants.AuthProviderGoogle})
+}
+
+func (repo *userRepository) insert(user *models.User) (*models.User, error) {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	if repo.emailTaken(user.Email, 0) {
+		return nil, models.ErrEmailExists
+	}
+
+	repo.lastUserID++
+	user.ID = repo.lastUserID
+	user.Role = constants.RoleUser
+	user.CreatedAt = time.Now()
+	user.UpdatedAt = user.CreatedAt
+
+	if err := repo.writeOutboxEvent(user.ID, constants.WebhookUserSignedUp, user.EventData()); err != nil {
+		return nil, err
+	}
+
+	repo.users[user.ID] = user
+
+	created := *user
+	return &created, nil
+}
+
+// handle check unique email like the users.email index does, caller holds the lock
+func (s *state) emailTaken(email string, exceptID int) bool {
+	for _, user := range s.users {
+		if user.ID != exceptID && user.Email == email {
+			return true
+		}
+	}
+	return false
+}
+
+func (repo *userRepository) GetByID(ctx context.Context, id int) (*models.User, error) {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	return copyUser(repo.users[id]), nil
+}
+
+func (repo *userRepository) GetByIdentifier(ctx context.Context, email, googleID string) (*models.User, error) {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	for _, user := range repo.users {
+		if (googleID != "" && user.GoogleID == googleID) || (googleID == "" && user.Email == email) {
+			return copyUser(user), nil
+		}
+	}
+	return nil, nil
+}
+
+func copyUser(user *models.User) *models.User {
+	if user == nil {
+		return nil
+	}
+	copied := *user
+	return &copied
+}
+
+func (repo *userRepository) UpdateProfile(ctx context.Context, id int, fullName, telephone, email string) error {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	user, ok := repo.users[id]
+	if !ok {
+		return nil
+	}
+
+	if repo.emailTaken(email, id) {
+		return models.ErrEmailExists
+	}
+
+	updated := *user
+	updated.FullName = fullName
+	updated.Telephone = telephone
+	updated.Email = email
+	updated.UpdatedAt = time.Now()
+
+	if err := repo.writeOutboxEvent(id, constants.WebhookUserUpdated, updated.EventData()); err != nil {
+		return err
+	}
+
+	repo.users[id] = &updated
+	return nil
+}
+
+func (repo *userRepository) UpdatePassword(ctx context.Context, id int, hashedPassword string) error {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	if user, ok := repo.users[id]; ok {
+		user.Password = hashedPassword
+	}
+	return nil
+}
+
+func (repo *userRepository) UpdateRole(ctx context.Context, id int, role string) error {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	if user, ok := repo.users[id]; ok {
+		user.Role = role
+	}
+	return nil
+}
+
+func (repo *userRepository) SetDisabled(ctx context.Context, id int, disabled bool) error {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	user, ok := repo.users[id]
+	if !ok || user.IsDisabled() == disabled {
+		return nil
+	}
+
+	eventType := constants.WebhookUserEnabled
+	var disabledAt *time.Time
+
+	if disabled {
+		now := time.Now()
+		eventType = constants.WebhookUserDisabled
+		disabledAt = &now
+	}
+
+	if err := repo.writeOutboxEvent(id, eventType, user.EventData()); err != nil {
+		return err
+	}
+
+	user.DisabledAt = disabledAt
+	return nil
+}
+
+// handle delete user with sessions and pending changes, like the foreign keys cascade
+func (repo *userRepository) Delete(ctx context.Context, id int) error {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	user, ok := repo.users[id]
+	if !ok {
+		return nil
+	}
+
+	if err := repo.writeOutboxEvent(id, constants.WebhookUserDeleted, user.EventData()); err != nil {
+		return err
+	}
+
+	delete(repo.users, id)
+
+	for token, session := range repo.sessions {
+		if session.UserID == id {
+			delete(repo.sessions, token)
+		}
+	}
+
+	for changeID, change := range repo.emailChanges {
+		if change.UserID == id {
+			delete(repo.emailChanges, changeID)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,242 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"time"
+	"user-auth-go/constants"
+	"user-auth-go/internal/models"
+)
+
+type webhookRepository struct {
+	*state
+}
+
+func (repo *webhookRepository) CreateSubscription(ctx context.Context, url, secret string, events []string, description string) (*models.WebhookSubscription, error) {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	now := time.Now()
+
+	repo.lastSubscriptionID++
+	sub := &models.WebhookSubscription{
+		ID:          repo.lastSubscriptionID,
+		URL:         url,
+		Secret:      secret,
+		Events:      append([]string(nil), events...),
+		Description: description,
+		Active:      true,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+	repo.subscriptions[sub.ID] = sub
+
+	created := *sub
+	return &created, nil
+}
+
+func (repo *webhookRepository) GetSubscription(ctx context.Context, id int) (*models.WebhookSubscription, error) {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	sub, ok := repo.subscriptions[id]
+	if !ok {
+		return nil, nil
+	}
+
+	found := *sub
+	return &found, nil
+}
+
+func (repo *webhookRepository) ListSubscriptions(ctx context.Context) ([]models.WebhookSubscription, error) {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	subscriptions := []models.WebhookSubscription{}
+	for _, sub := range repo.subscriptions {
+		subscriptions = append(subscriptions, *sub)
+	}
+
+	sort.Slice(subscriptions, func(i, j int) bool { return subscriptions[i].ID < subscriptions[j].ID })
+	return subscriptions, nil
+}
+
+func (repo *webhookRepository) UpdateSubscription(ctx context.Context, sub *models.WebhookSubscription) error {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	existing, ok := repo.subscriptions[sub.ID]
+	if !ok {
+		return nil
+	}
+
+	existing.URL = sub.URL
+	existing.Events = append([]string(nil), sub.Events...)
+	existing.Description = sub.Description
+	existing.Active = sub.Active
+	existing.UpdatedAt = time.Now()
+	return nil
+}
+
+// handle delete subscription with its deliveries, like the foreign keys cascade
+func (repo *webhookRepository) DeleteSubscription(ctx context.Context, id int) error {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	delete(repo.subscriptions, id)
+
+	deliveries := repo.deliveries[:0]
+	for _, delivery := range repo.deliveries {
+		if delivery.SubscriptionID != id {
+			deliveries = append(deliveries, delivery)
+		}
+	}
+	repo.deliveries = deliveries
+	return nil
+}
+
+func (repo *webhookRepository) Enqueue(ctx context.Context, eventID, eventType, payload string) error {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	now := time.Now()
+
+	for _, sub := range repo.subscriptions {
+		if !sub.Active || !sub.Subscribes(eventType) || repo.queued(sub.ID, eventID) {
+			continue
+		}
+
+		repo.lastDeliveryID++
+		repo.deliveries = append(repo.deliveries, &models.WebhookDelivery{
+			ID:             repo.lastDeliveryID,
+			SubscriptionID: sub.ID,
+			EventID:        eventID,
+			EventType:      eventType,
+			Payload:        payload,
+			Status:         constants.DeliveryPending,
+			NextAttemptAt:  now,
+			CreatedAt:      now,
+			UpdatedAt:      now,
+		})
+	}
+
+	return nil
+}
+
+func (s *state) queued(subscriptionID int, eventID string) bool {
+	for _, delivery := range s.deliveries {
+		if delivery.SubscriptionID == subscriptionID && delivery.EventID == eventID {
+			return true
+		}
+	}
+	return false
+}
+
+func (repo *webhookRepository) ClaimDue(ctx context.Context, limit int) ([]models.WebhookDelivery, error) {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	now := time.Now()
+	deliveries := []models.WebhookDelivery{}
+
+	for _, delivery := range repo.deliveries {
+		if len(deliveries) >= limit {
+			break
+		}
+
+		if delivery.Status != constants.DeliveryPending || delivery.NextAttemptAt.After(now) {
+			continue
+		}
+
+		deliveries = append(deliveries, *delivery)
+		delivery.NextAttemptAt = now.Add(models.DeliveryLease)
+	}
+
+	return deliveries, nil
+}
+
+func (repo *webhookRepository) RecordAttempt(ctx context.Context, delivery *models.WebhookDelivery, attempt models.WebhookDeliveryAttempt, status string, nextAttemptAt time.Time) error {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	repo.lastAttemptID++
+	attempt.ID = repo.lastAttemptID
+	attempt.DeliveryID = delivery.ID
+	attempt.CreatedAt = time.Now()
+	repo.attempts = append(repo.attempts, attempt)
+
+	if stored := repo.delivery(delivery.ID); stored != nil {
+		stored.Status = status
+		stored.Attempts++
+		stored.NextAttemptAt = nextAttemptAt
+		stored.LastStatusCode = attempt.StatusCode
+		stored.LastError = attempt.Error
+		stored.UpdatedAt = attempt.CreatedAt
+	}
+
+	return nil
+}
+
+func (s *state) delivery(id int64) *models.WebhookDelivery {
+	for _, delivery := range s.deliveries {
+		if delivery.ID == id {
+			return delivery
+		}
+	}
+	return nil
+}
+
+func (repo *webhookRepository) GetDelivery(ctx context.Context, id int64) (*models.WebhookDelivery, error) {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	delivery := repo.delivery(id)
+	if delivery == nil {
+		return nil, nil
+	}
+
+	found := *delivery
+	return &found, nil
+}
+
+func (repo *webhookRepository) ListDeliveries(ctx context.Context, filter models.WebhookDeliveryFilter) ([]models.WebhookDelivery, error) {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	deliveries := []models.WebhookDelivery{}
+
+	for i := len(repo.deliveries) - 1; i >= 0 && len(deliveries) < filter.PageSize(); i-- {
+		if filter.Matches(*repo.deliveries[i]) {
+			deliveries = append(deliveries, *repo.deliveries[i])
+		}
+	}
+
+	return deliveries, nil
+}
+
+func (repo *webhookRepository) ListAttempts(ctx context.Context, deliveryID int64) ([]models.WebhookDeliveryAttempt, error) {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	attempts := []models.WebhookDeliveryAttempt{}
+
+	for _, attempt := range repo.attempts {
+		if attempt.DeliveryID == deliveryID {
+			attempts = append(attempts, attempt)
+		}
+	}
+
+	return attempts, nil
+}
+
+func (repo *webhookRepository) Redeliver(ctx context.Context, id int64) error {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	if delivery := repo.delivery(id); delivery != nil {
+		delivery.Status = constants.DeliveryPending
+		delivery.Attempts = 0
+		delivery.NextAttemptAt = time.Now()
+	}
+	return nil
+}
@@ -0,0 +1,119 @@
+package sqlstore
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"strings"
+	"user-auth-go/internal/models"
+)
+
+type auditRepository struct {
+	db *db
+}
+
+// handle append event to the audit log
+func (repo *auditRepository) Record(ctx context.Context, event *models.AuditEvent) error {
+	var details interface{}
+
+	if len(event.Details) > 0 {
+		encoded, err := json.Marshal(event.Details)
+		if err != nil {
+			return err
+		}
+		details = string(encoded)
+	}
+
+	id, err := repo.db.insert(ctx,
+		"INSERT INTO audit_events (event_type, outcome, actor_user_id, target_user_id, ip, user_agent, details) VALUES (?, ?, ?, ?, ?, ?, ?)",
+		event.EventType, event.Outcome, event.ActorUserID, event.TargetUserID, event.IP, event.UserAgent, details,
+	)
+	if err != nil {
+		return err
+	}
+
+	event.ID = id
+	return nil
+}
+
+// handle get latest events where user is the actor or the target
+func (repo *auditRepository) ListForUser(ctx context.Context, userID int, limit int) ([]models.AuditEvent, error) {
+	return repo.Query(ctx, models.AuditFilter{UserID: &userID, Limit: limit})
+}
+
+// handle query audit log with optional filters, newest first
+func (repo *auditRepository) Query(ctx context.Context, filter models.AuditFilter) ([]models.AuditEvent, error) {
+	var conditions []string
+	var args []interface{}
+
+	if filter.UserID != nil {
+		conditions = append(conditions, "(actor_user_id = ? OR target_user_id = ?)")
+		args = append(args, *filter.UserID, *filter.UserID)
+	}
+
+	if filter.EventType != "" {
+		conditions = append(conditions, "event_type = ?")
+		args = append(args, filter.EventType)
+	}
+
+	if filter.Outcome != "" {
+		conditions = append(conditions, "outcome = ?")
+		args = append(args, filter.Outcome)
+	}
+
+	if filter.IP != "" {
+		conditions = append(conditions, "ip = ?")
+		args = append(args, filter.IP)
+	}
+
+	if filter.Since != nil {
+		conditions = append(conditions, "created_at >= ?")
+		args = append(args, *filter.Since)
+	}
+
+	if filter.Until != nil {
+		conditions = append(conditions, "created_at < ?")
+		args = append(args, *filter.Until)
+	}
+
+	query := "SELECT id, event_type, outcome, actor_user_id, target_user_id, COALESCE(ip, ''), COALESCE(user_agent, ''), details, created_at FROM audit_events"
+
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	query += " ORDER BY id DESC LIMIT ? OFFSET ?"
+	args = append(args, filter.PageSize(), filter.Offset)
+
+	rows, err := repo.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	events := []models.AuditEvent{}
+
+	for rows.Next() {
+		var event models.AuditEvent
+		var actorID, targetID sql.NullInt64
+		var details sql.NullString
+
+		err := rows.Scan(&event.ID, &event.EventType, &event.Outcome, &actorID, &targetID, &event.IP, &event.UserAgent, &details, &event.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+
+		event.ActorUserID = nullIntPtr(actorID)
+		event.TargetUserID = nullIntPtr(targetID)
+
+		if details.Valid && details.String != "" {
+			if err := json.Unmarshal([]byte(details.String), &event.Details); err != nil {
+				return nil, err
+			}
+		}
+
+		events = append(events, event)
+	}
+
+	return events, rows.Err()
+}
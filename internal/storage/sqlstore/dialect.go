@@ -0,0 +1,206 @@
+package sqlstore
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/jackc/pgx/v5/pgconn"
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+// Dialect is the SQL flavour of a database backend, named like the DB_DRIVER setting.
+// Queries are written with `?` placeholders and the dialect adapts them
+type Dialect string
+
+const (
+	MySQL    Dialect = "mysql"
+	SQLite   Dialect = "sqlite"
+	Postgres Dialect = "postgres"
+)
+
+// handle rewrite `?` placeholders to `$1, $2, ...` for postgres
+func (d Dialect) rebind(query string) string {
+	if d != Postgres || !strings.Contains(query, "?") {
+		return query
+	}
+
+	var builder strings.Builder
+	n := 0
+
+	for _, char := range query {
+		if char == '?' {
+			n++
+			builder.WriteString("$" + strconv.Itoa(n))
+			continue
+		}
+		builder.WriteRune(char)
+	}
+
+	return builder.String()
+}
+
+// handle times are stored in UTC so every backend compares them the same way,
+// MySQL TIMESTAMP columns keep whole seconds so they are truncated instead of rounded up
+func (d Dialect) bindArgs(args []interface{}) []interface{} {
+	bound := make([]interface{}, len(args))
+
+	for i, arg := range args {
+		value, ok := arg.(time.Time)
+		if !ok {
+			bound[i] = arg
+			continue
+		}
+
+		value = value.UTC()
+		if d == MySQL {
+			value = value.Truncate(time.Second)
+		}
+		bound[i] = value
+	}
+
+	return bound
+}
+
+// handle row locking suffix for claim queries, sqlite locks the whole database on write instead
+func (d Dialect) forUpdate(skipLocked bool) string {
+	if d == SQLite {
+		return ""
+	}
+	if skipLocked {
+		return " FOR UPDATE SKIP LOCKED"
+	}
+	return " FOR UPDATE"
+}
+
+// handle insert that silently skips rows violating a unique key
+func (d Dialect) insertIgnore(table, columns string, values int) string {
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", values), ", ")
+
+	switch d {
+	case SQLite:
+		return fmt.Sprintf("INSERT OR IGNORE INTO %s (%s) VALUES (%s)", table, columns, placeholders)
+	case Postgres:
+		return fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s) ON CONFLICT DO NOTHING", table, columns, placeholders)
+	}
+	return fmt.Sprintf("INSERT IGNORE INTO %s (%s) VALUES (%s)", table, columns, placeholders)
+}
+
+// handle catch the error, with duplicate constraint error
+// with this we don't need to check manually is email is already exists or not
+func isDuplicateEntryError(err error) bool {
+	var mysqlErr *mysql.MySQLError
+	if errors.As(err, &mysqlErr) {
+		return mysqlErr.Number == 1062
+	}
+
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		return pgErr.Code == "23505"
+	}
+
+	var sqliteErr *sqlite.Error
+	if errors.As(err, &sqliteErr) {
+		return sqliteErr.Code() == sqlite3.SQLITE_CONSTRAINT_UNIQUE || sqliteErr.Code() == sqlite3.SQLITE_CONSTRAINT_PRIMARYKEY
+	}
+
+	return strings.Contains(err.Error(), "Duplicate entry")
+}
+
+// querier is implemented by both db and tx so helpers can run inside or outside a transaction
+type querier interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+// db wraps the connection pool and adapts every query to the dialect
+type db struct {
+	*sql.DB
+	dialect Dialect
+}
+
+func (d *db) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	ctx, span := startQuerySpan(ctx, d.dialect, query)
+	result, err := d.DB.ExecContext(ctx, d.dialect.rebind(query), d.dialect.bindArgs(args)...)
+	endQuerySpan(span, err)
+	return result, err
+}
+
+func (d *db) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	ctx, span := startQuerySpan(ctx, d.dialect, query)
+	rows, err := d.DB.QueryContext(ctx, d.dialect.rebind(query), d.dialect.bindArgs(args)...)
+	endQuerySpan(span, err)
+	return rows, err
+}
+
+func (d *db) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	ctx, span := startQuerySpan(ctx, d.dialect, query)
+	row := d.DB.QueryRowContext(ctx, d.dialect.rebind(query), d.dialect.bindArgs(args)...)
+	endQuerySpan(span, row.Err())
+	return row
+}
+
+func (d *db) BeginTx(ctx context.Context, opts *sql.TxOptions) (*tx, error) {
+	sqlTx, err := d.DB.BeginTx(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	return &tx{Tx: sqlTx, dialect: d.dialect}, nil
+}
+
+// handle insert a row and return its generated id
+func (d *db) insert(ctx context.Context, query string, args ...interface{}) (int64, error) {
+	return insertID(ctx, d, d.dialect, query, args...)
+}
+
+type tx struct {
+	*sql.Tx
+	dialect Dialect
+}
+
+func (t *tx) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	ctx, span := startQuerySpan(ctx, t.dialect, query)
+	result, err := t.Tx.ExecContext(ctx, t.dialect.rebind(query), t.dialect.bindArgs(args)...)
+	endQuerySpan(span, err)
+	return result, err
+}
+
+func (t *tx) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	ctx, span := startQuerySpan(ctx, t.dialect, query)
+	rows, err := t.Tx.QueryContext(ctx, t.dialect.rebind(query), t.dialect.bindArgs(args)...)
+	endQuerySpan(span, err)
+	return rows, err
+}
+
+func (t *tx) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	ctx, span := startQuerySpan(ctx, t.dialect, query)
+	row := t.Tx.QueryRowContext(ctx, t.dialect.rebind(query), t.dialect.bindArgs(args)...)
+	endQuerySpan(span, row.Err())
+	return row
+}
+
+func (t *tx) insert(ctx context.Context, query string, args ...interface{}) (int64, error) {
+	return insertID(ctx, t, t.dialect, query, args...)
+}
+
+// handle postgres has no LastInsertId, the id comes back through RETURNING instead
+func insertID(ctx context.Context, q querier, dialect Dialect, query string, args ...interface{}) (int64, error) {
+	if dialect == Postgres {
+		var id int64
+		err := q.QueryRowContext(ctx, query+" RETURNING id", args...).Scan(&id)
+		return id, err
+	}
+
+	result, err := q.ExecContext(ctx, query, args...)
+	if err != nil {
+		return 0, err
+	}
+	return result.LastInsertId()
+}
@@ -0,0 +1,113 @@
+package sqlstore
+
+import (
+	"context"
+	"database/sql"
+	"time"
+	"user-auth-go/constants"
+	"user-auth-go/internal/models"
+)
+
+type emailChangeRepository struct {
+	db *db
+}
+
+// handle create pending email change, replacing any previous request of the user
+func (repo *emailChangeRepository) Create(ctx context.Context, userID int, newEmail string) (*models.EmailChange, error) {
+	change, err := models.NewEmailChange(userID, newEmail)
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := repo.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, "DELETE FROM email_changes WHERE user_id = ?", userID); err != nil {
+		return nil, err
+	}
+
+	id, err := tx.insert(ctx,
+		"INSERT INTO email_changes (user_id, new_email, confirm_token, cancel_token, expires_at) VALUES (?, ?, ?, ?, ?)",
+		change.UserID, change.NewEmail, change.ConfirmToken, change.CancelToken, change.ExpiresAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	change.ID = int(id)
+	return change, nil
+}
+
+// handle get pending, not expired email change of user
+func (repo *emailChangeRepository) GetPending(ctx context.Context, userID int) (*models.EmailChange, error) {
+	return repo.get(ctx, "user_id = ?", userID)
+}
+
+func (repo *emailChangeRepository) GetByConfirmToken(ctx context.Context, token string) (*models.EmailChange, error) {
+	return repo.get(ctx, "confirm_token = ?", token)
+}
+
+func (repo *emailChangeRepository) GetByCancelToken(ctx context.Context, token string) (*models.EmailChange, error) {
+	return repo.get(ctx, "cancel_token = ?", token)
+}
+
+func (repo *emailChangeRepository) get(ctx context.Context, condition string, arg interface{}) (*models.EmailChange, error) {
+	change := &models.EmailChange{}
+	err := repo.db.QueryRowContext(ctx,
+		"SELECT id, user_id, new_email, confirm_token, cancel_token, expires_at FROM email_changes WHERE "+condition+" AND expires_at > ?",
+		arg, time.Now(),
+	).Scan(&change.ID, &change.UserID, &change.NewEmail, &change.ConfirmToken, &change.CancelToken, &change.ExpiresAt)
+
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return change, nil
+}
+
+// handle apply pending email change to the user and remove the request
+func (repo *emailChangeRepository) Confirm(ctx context.Context, change *models.EmailChange) error {
+	tx, err := repo.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, "UPDATE users SET email = ? WHERE id = ?", change.NewEmail, change.UserID); err != nil {
+		if isDuplicateEntryError(err) {
+			return models.ErrEmailExists
+		}
+		return err
+	}
+
+	if _, err := tx.ExecContext(ctx, "DELETE FROM email_changes WHERE id = ?", change.ID); err != nil {
+		return err
+	}
+
+	data, err := userEventDataTx(ctx, tx, change.UserID)
+	if err != nil {
+		return err
+	}
+
+	if data != nil {
+		if err := writeOutboxEvent(ctx, tx, change.UserID, constants.WebhookUserEmailVerified, data); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+func (repo *emailChangeRepository) Delete(ctx context.Context, id int) error {
+	_, err := repo.db.ExecContext(ctx, "DELETE FROM email_changes WHERE id = ?", id)
+	return err
+}
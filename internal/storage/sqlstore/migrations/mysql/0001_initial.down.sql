@@ -0,0 +1,8 @@
+DROP TABLE IF EXISTS outbox;
+DROP TABLE IF EXISTS webhook_delivery_attempts;
+DROP TABLE IF EXISTS webhook_deliveries;
+DROP TABLE IF EXISTS webhook_subscriptions;
+DROP TABLE IF EXISTS audit_events;
+DROP TABLE IF EXISTS email_changes;
+DROP TABLE IF EXISTS sessions;
+DROP TABLE IF EXISTS users;
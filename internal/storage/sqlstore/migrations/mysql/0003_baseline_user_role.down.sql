@@ -0,0 +1 @@
+-- role belongs to the schema of 0001, reverting only forgets this migration ran
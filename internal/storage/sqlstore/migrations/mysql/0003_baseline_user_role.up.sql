@@ -0,0 +1,14 @@
+-- databases created from the ddl.sql of the first release have a users table without role,
+-- 0001 keeps existing tables as they are so the column is added here when it is missing.
+-- role is the only gap: sessions is unchanged and every other table is new in 0001
+SET @add_role = (
+    SELECT IF(COUNT(*) = 0,
+        'ALTER TABLE users ADD COLUMN role ENUM(''user'', ''admin'') DEFAULT ''user'' AFTER google_id',
+        'DO 0')
+    FROM information_schema.columns
+    WHERE table_schema = DATABASE() AND table_name = 'users' AND column_name = 'role'
+);
+
+PREPARE add_role FROM @add_role;
+EXECUTE add_role;
+DEALLOCATE PREPARE add_role;
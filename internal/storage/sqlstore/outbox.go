@@ -0,0 +1,145 @@
+package sqlstore
+
+import (
+	"context"
+	"database/sql"
+	"time"
+	"user-auth-go/constants"
+	"user-auth-go/internal/models"
+)
+
+type outboxRepository struct {
+	db *db
+}
+
+// handle write event in the caller's transaction, it is only published if the transaction commits
+func writeOutboxEvent(ctx context.Context, tx *tx, aggregateID int, eventType string, data interface{}) error {
+	event, err := models.NewOutboxEvent(aggregateID, eventType, data)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx,
+		"INSERT INTO outbox (event_id, event_type, aggregate_id, payload, status) VALUES (?, ?, ?, ?, ?)",
+		event.EventID, event.EventType, event.AggregateID, event.Payload, event.Status,
+	)
+	return err
+}
+
+// handle read user inside transaction so the event carries the committed state
+func userEventDataTx(ctx context.Context, tx *tx, id int) (*models.UserEventData, error) {
+	data := &models.UserEventData{}
+	err := tx.QueryRowContext(ctx,
+		"SELECT id, email, COALESCE(full_name, ''), COALESCE(telephone, ''), auth_provider FROM users WHERE id = ?",
+		id,
+	).Scan(&data.ID, &data.Email, &data.FullName, &data.Telephone, &data.AuthProvider)
+
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// handle lock due events for this dispatcher
+// while an event is retried the ones behind it wait, failed events stop blocking them
+func (repo *outboxRepository) Claim(ctx context.Context, limit int) ([]models.OutboxEvent, error) {
+	tx, err := repo.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(ctx,
+		outboxSelect+` o WHERE o.status = ? AND o.next_attempt_at <= ?
+			AND NOT EXISTS (SELECT 1 FROM outbox prev WHERE prev.aggregate_id = o.aggregate_id AND prev.status = ? AND prev.id < o.id)
+			ORDER BY o.id LIMIT ?`+tx.dialect.forUpdate(true),
+		constants.OutboxPending, time.Now(), constants.OutboxPending, limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	events, err := scanOutboxEvents(rows)
+	if err != nil {
+		return nil, err
+	}
+
+	leaseUntil := time.Now().Add(models.OutboxLease)
+
+	for _, event := range events {
+		if _, err := tx.ExecContext(ctx, "UPDATE outbox SET next_attempt_at = ? WHERE id = ?", leaseUntil, event.ID); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return events, nil
+}
+
+func (repo *outboxRepository) MarkSent(ctx context.Context, id int64) error {
+	_, err := repo.db.ExecContext(ctx,
+		"UPDATE outbox SET status = ?, attempts = attempts + 1, last_error = NULL, sent_at = ? WHERE id = ?",
+		constants.OutboxSent, time.Now(), id,
+	)
+	return err
+}
+
+// handle remove published events, the outbox only needs them until every sink accepted them
+func (repo *outboxRepository) DeleteSent(ctx context.Context, before time.Time) (int64, error) {
+	result, err := repo.db.ExecContext(ctx, "DELETE FROM outbox WHERE status = ? AND sent_at < ?", constants.OutboxSent, before)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+func (repo *outboxRepository) MarkFailed(ctx context.Context, id int64, status string, nextAttemptAt time.Time, lastError string) error {
+	_, err := repo.db.ExecContext(ctx,
+		"UPDATE outbox SET status = ?, attempts = attempts + 1, next_attempt_at = ?, last_error = ? WHERE id = ?",
+		status, nextAttemptAt, lastError, id,
+	)
+	return err
+}
+
+func (repo *outboxRepository) ListForAggregate(ctx context.Context, aggregateID int) ([]models.OutboxEvent, error) {
+	rows, err := repo.db.QueryContext(ctx, outboxSelect+" o WHERE o.aggregate_id = ? ORDER BY o.id", aggregateID)
+	if err != nil {
+		return nil, err
+	}
+
+	return scanOutboxEvents(rows)
+}
+
+const outboxSelect = "SELECT o.id, o.event_id, o.event_type, o.aggregate_id, o.payload, o.status, o.attempts, o.next_attempt_at, COALESCE(o.last_error, ''), o.created_at, o.sent_at FROM outbox"
+
+func scanOutboxEvents(rows *sql.Rows) ([]models.OutboxEvent, error) {
+	defer rows.Close()
+
+	events := []models.OutboxEvent{}
+
+	for rows.Next() {
+		var event models.OutboxEvent
+		var sentAt sql.NullTime
+
+		err := rows.Scan(
+			&event.ID, &event.EventID, &event.EventType, &event.AggregateID, &event.Payload, &event.Status,
+			&event.Attempts, &event.NextAttemptAt, &event.LastError, &event.CreatedAt, &sentAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		if sentAt.Valid {
+			event.SentAt = &sentAt.Time
+		}
+		events = append(events, event)
+	}
+
+	return events, rows.Err()
+}
@@ -0,0 +1,146 @@
+package sqlstore
+
+import (
+	"context"
+	"database/sql"
+	"time"
+	"user-auth-go/constants"
+	"user-auth-go/internal/models"
+)
+
+type sessionRepository struct {
+	db *db
+}
+
+// handle create session for user after login
+func (repo *sessionRepository) Create(ctx context.Context, userID int) (*models.Session, error) {
+	session, err := models.NewSession(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := repo.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	id, err := tx.insert(ctx,
+		"INSERT INTO sessions (user_id, token, expires_at) VALUES (?, ?, ?)",
+		session.UserID, session.Token, session.ExpiresAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	session.ID = int(id)
+
+	data := models.SessionEventData{UserID: userID, SessionID: session.ID, ExpiresAt: &session.ExpiresAt}
+	if err := writeOutboxEvent(ctx, tx, userID, constants.WebhookSessionCreated, data); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return session, nil
+}
+
+// handle get session logged user by token
+func (repo *sessionRepository) GetByToken(ctx context.Context, token string) (*models.Session, error) {
+	session := &models.Session{}
+	err := repo.db.QueryRowContext(ctx,
+		"SELECT id, user_id, token, expires_at FROM sessions WHERE token = ? AND expires_at > ?",
+		token, time.Now(),
+	).Scan(&session.ID, &session.UserID, &session.Token, &session.ExpiresAt)
+
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return session, nil
+}
+
+// handle delete session logged user by token
+func (repo *sessionRepository) Delete(ctx context.Context, token string) error {
+	tx, err := repo.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var id, userID int
+	err = tx.QueryRowContext(ctx, "SELECT id, user_id FROM sessions WHERE token = ?"+tx.dialect.forUpdate(false), token).Scan(&id, &userID)
+
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if _, err := tx.ExecContext(ctx, "DELETE FROM sessions WHERE id = ?", id); err != nil {
+		return err
+	}
+
+	if err := writeOutboxEvent(ctx, tx, userID, constants.WebhookSessionRevoked, models.SessionEventData{UserID: userID, SessionID: id}); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// handle delete session logged user by userID
+func (repo *sessionRepository) DeleteForUser(ctx context.Context, userID int) error {
+	return repo.revoke(ctx, userID, "DELETE FROM sessions WHERE user_id = ?", userID)
+}
+
+// handle delete every session of user except the given one
+func (repo *sessionRepository) DeleteForUserExcept(ctx context.Context, userID int, token string) error {
+	return repo.revoke(ctx, userID, "DELETE FROM sessions WHERE user_id = ? AND token <> ?", userID, token)
+}
+
+// handle run session delete query and record how many sessions were revoked
+func (repo *sessionRepository) revoke(ctx context.Context, userID int, query string, args ...interface{}) error {
+	tx, err := repo.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+
+	revoked, _ := result.RowsAffected()
+	if revoked == 0 {
+		return tx.Commit()
+	}
+
+	if err := writeOutboxEvent(ctx, tx, userID, constants.WebhookSessionRevoked, models.SessionEventData{UserID: userID, Revoked: revoked}); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// handle remove expired sessions, they are already ignored so no event is written
+func (repo *sessionRepository) DeleteExpired(ctx context.Context) (int64, error) {
+	result, err := repo.db.ExecContext(ctx, "DELETE FROM sessions WHERE expires_at <= ?", time.Now())
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+func (repo *sessionRepository) CountActive(ctx context.Context) (int64, error) {
+	var active int64
+	err := repo.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM sessions WHERE expires_at > ?", time.Now()).Scan(&active)
+	return active, err
+}
@@ -0,0 +1,29 @@
+package sqlstore
+
+import (
+	"database/sql"
+	"user-auth-go/internal/models"
+)
+
+// handle build repositories backed by the given connection, dialect matches its driver
+func New(conn *sql.DB, dialect Dialect) *models.Store {
+	d := &db{DB: conn, dialect: dialect}
+
+	return &models.Store{
+		Users:        &userRepository{db: d},
+		Sessions:     &sessionRepository{db: d},
+		EmailChanges: &emailChangeRepository{db: d},
+		Audit:        &auditRepository{db: d},
+		Webhooks:     &webhookRepository{db: d},
+		Outbox:       &outboxRepository{db: d},
+	}
+}
+
+func nullIntPtr(value sql.NullInt64) *int {
+	if !value.Valid {
+		return nil
+	}
+
+	id := int(value.Int64)
+	return &id
+}
@@ -0,0 +1,47 @@
+package sqlstore
+
+import (
+	"context"
+	"regexp"
+	"strings"
+	"user-auth-go/internal/tracing"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// first table a query touches, only used to name its span
+var queryTable = regexp.MustCompile(`(?i)\b(?:from|into|update)\s+([a-z_]+)`)
+
+// semconv db.system.name of each dialect
+var dbSystems = map[Dialect]string{
+	MySQL:    "mysql",
+	SQLite:   "sqlite",
+	Postgres: "postgresql",
+}
+
+// handle client span for one query, named like `SELECT users`. The statement is recorded
+// with its `?` placeholders, argument values never end up in traces
+func startQuerySpan(ctx context.Context, dialect Dialect, query string) (context.Context, trace.Span) {
+	operation, _, _ := strings.Cut(strings.TrimSpace(query), " ")
+	operation = strings.ToUpper(operation)
+
+	name := operation
+	if match := queryTable.FindStringSubmatch(query); match != nil {
+		name += " " + match[1]
+	}
+
+	return tracing.Start(ctx, name,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("db.system.name", dbSystems[dialect]),
+			attribute.String("db.operation.name", operation),
+			attribute.String("db.query.text", query),
+		),
+	)
+}
+
+func endQuerySpan(span trace.Span, err error) {
+	tracing.RecordError(span, err)
+	span.End()
+}
@@ -0,0 +1,216 @@
+package sqlstore
+
+import (
+	"context"
+	"database/sql"
+	"time"
+	"user-auth-go/constants"
+	"user-auth-go/internal/models"
+)
+
+type userRepository struct {
+	db *db
+}
+
+const userSelect = "SELECT id, email, COALESCE(password, ''), COALESCE(full_name, ''), COALESCE(telephone, ''), auth_provider, COALESCE(google_id, ''), COALESCE(role, 'user'), disabled_at FROM users"
+
+// handle create user with provider type is `local`
+func (repo *userRepository) Create(ctx context.Context, email, hashedPassword string) (*models.User, error) {
+	tx, err := repo.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	id, err := tx.insert(ctx, "INSERT INTO users (email, password, auth_provider) VALUES (?, ? , ?)",
+		email, hashedPassword, constants.AuthProviderLocal)
+
+	if err != nil {
+		if isDuplicateEntryError(err) {
+			return nil, models.ErrEmailExists
+		}
+		return nil, err
+	}
+
+	user := &models.User{ID: int(id), Email: email, Password: hashedPassword, AuthProvider: constants.AuthProviderLocal, Role: constants.RoleUser}
+
+	if err := writeOutboxEvent(ctx, tx, user.ID, constants.WebhookUserSignedUp, user.EventData()); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+// handle create user with provider type is `google`
+func (repo *userRepository) CreateWithGoogle(ctx context.Context, email, googleID, fullName string) (*models.User, error) {
+	tx, err := repo.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	id, err := tx.insert(ctx, "INSERT INTO users (email, google_id, full_name, auth_provider) VALUES (?, ?, ?, ?)",
+		email, googleID, fullName, constants.AuthProviderGoogle)
+
+	if err != nil {
+		if isDuplicateEntryError(err) {
+			return nil, models.ErrEmailExists
+		}
+		return nil, err
+	}
+
+	user := &models.User{ID: int(id), Email: email, GoogleID: googleID, FullName: fullName, AuthProvider: constants.AuthProviderGoogle, Role: constants.RoleUser}
+
+	if err := writeOutboxEvent(ctx, tx, user.ID, constants.WebhookUserSignedUp, user.EventData()); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+// handle get user from theirs ID
+func (repo *userRepository) GetByID(ctx context.Context, id int) (*models.User, error) {
+	return scanUser(repo.db.QueryRowContext(ctx, userSelect+" WHERE id = ?", id))
+}
+
+// handle get user based on identifier
+// identifier can be email or googleID
+func (repo *userRepository) GetByIdentifier(ctx context.Context, email, googleID string) (*models.User, error) {
+	if googleID != "" {
+		return scanUser(repo.db.QueryRowContext(ctx, userSelect+" WHERE google_id = ?", googleID))
+	}
+	return scanUser(repo.db.QueryRowContext(ctx, userSelect+" WHERE email = ?", email))
+}
+
+func scanUser(row *sql.Row) (*models.User, error) {
+	user := &models.User{}
+	var disabledAt sql.NullTime
+	err := row.Scan(&user.ID, &user.Email, &user.Password, &user.FullName, &user.Telephone, &user.AuthProvider, &user.GoogleID, &user.Role, &disabledAt)
+
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if disabledAt.Valid {
+		user.DisabledAt = &disabledAt.Time
+	}
+	return user, nil
+}
+
+// handle update user profile
+func (repo *userRepository) UpdateProfile(ctx context.Context, id int, fullName, telephone, email string) error {
+	tx, err := repo.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx,
+		"UPDATE users SET full_name = ?, telephone = ?, email = ? WHERE id = ?",
+		fullName, telephone, email, id,
+	)
+	if err != nil {
+		if isDuplicateEntryError(err) {
+			return models.ErrEmailExists
+		}
+		return err
+	}
+
+	data, err := userEventDataTx(ctx, tx, id)
+	if err != nil {
+		return err
+	}
+
+	if data != nil {
+		if err := writeOutboxEvent(ctx, tx, id, constants.WebhookUserUpdated, data); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// handle store new password hash for user
+func (repo *userRepository) UpdatePassword(ctx context.Context, id int, hashedPassword string) error {
+	_, err := repo.db.ExecContext(ctx, "UPDATE users SET password = ? WHERE id = ?", hashedPassword, id)
+	return err
+}
+
+func (repo *userRepository) UpdateRole(ctx context.Context, id int, role string) error {
+	_, err := repo.db.ExecContext(ctx, "UPDATE users SET role = ? WHERE id = ?", role, id)
+	return err
+}
+
+// handle block or restore account, the event is only written when the state changes
+func (repo *userRepository) SetDisabled(ctx context.Context, id int, disabled bool) error {
+	tx, err := repo.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := "UPDATE users SET disabled_at = ? WHERE id = ? AND disabled_at IS NULL"
+	args := []interface{}{time.Now(), id}
+	eventType := constants.WebhookUserDisabled
+
+	if !disabled {
+		query = "UPDATE users SET disabled_at = NULL WHERE id = ? AND disabled_at IS NOT NULL"
+		args = []interface{}{id}
+		eventType = constants.WebhookUserEnabled
+	}
+
+	result, err := tx.ExecContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+
+	if changed, _ := result.RowsAffected(); changed == 0 {
+		return nil
+	}
+
+	data, err := userEventDataTx(ctx, tx, id)
+	if err != nil {
+		return err
+	}
+
+	if err := writeOutboxEvent(ctx, tx, id, eventType, data); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// handle delete user, sessions and pending changes go with it through foreign keys
+func (repo *userRepository) Delete(ctx context.Context, id int) error {
+	tx, err := repo.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	data, err := userEventDataTx(ctx, tx, id)
+	if err != nil || data == nil {
+		return err
+	}
+
+	if _, err := tx.ExecContext(ctx, "DELETE FROM users WHERE id = ?", id); err != nil {
+		return err
+	}
+
+	if err := writeOutboxEvent(ctx, tx, id, constants.WebhookUserDeleted, data); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
@@ -0,0 +1,275 @@
+package sqlstore
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"time"
+	"user-auth-go/constants"
+	"user-auth-go/internal/models"
+)
+
+type webhookRepository struct {
+	db *db
+}
+
+func (repo *webhookRepository) CreateSubscription(ctx context.Context, url, secret string, events []string, description string) (*models.WebhookSubscription, error) {
+	id, err := repo.db.insert(ctx,
+		"INSERT INTO webhook_subscriptions (url, secret, events, description, active) VALUES (?, ?, ?, ?, ?)",
+		url, secret, strings.Join(events, ","), description, true,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return repo.GetSubscription(ctx, int(id))
+}
+
+func (repo *webhookRepository) GetSubscription(ctx context.Context, id int) (*models.WebhookSubscription, error) {
+	subscriptions, err := repo.querySubscriptions(ctx, "WHERE id = ?", id)
+	if err != nil || len(subscriptions) == 0 {
+		return nil, err
+	}
+	return &subscriptions[0], nil
+}
+
+func (repo *webhookRepository) ListSubscriptions(ctx context.Context) ([]models.WebhookSubscription, error) {
+	return repo.querySubscriptions(ctx, "ORDER BY id")
+}
+
+func (repo *webhookRepository) UpdateSubscription(ctx context.Context, sub *models.WebhookSubscription) error {
+	_, err := repo.db.ExecContext(ctx,
+		"UPDATE webhook_subscriptions SET url = ?, events = ?, description = ?, active = ?, updated_at = ? WHERE id = ?",
+		sub.URL, strings.Join(sub.Events, ","), sub.Description, sub.Active, time.Now(), sub.ID,
+	)
+	return err
+}
+
+func (repo *webhookRepository) DeleteSubscription(ctx context.Context, id int) error {
+	_, err := repo.db.ExecContext(ctx, "DELETE FROM webhook_subscriptions WHERE id = ?", id)
+	return err
+}
+
+func (repo *webhookRepository) querySubscriptions(ctx context.Context, clause string, args ...interface{}) ([]models.WebhookSubscription, error) {
+	rows, err := repo.db.QueryContext(ctx,
+		"SELECT id, url, secret, events, COALESCE(description, ''), active, created_at, updated_at FROM webhook_subscriptions "+clause,
+		args...,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	subscriptions := []models.WebhookSubscription{}
+
+	for rows.Next() {
+		var sub models.WebhookSubscription
+		var events string
+
+		if err := rows.Scan(&sub.ID, &sub.URL, &sub.Secret, &events, &sub.Description, &sub.Active, &sub.CreatedAt, &sub.UpdatedAt); err != nil {
+			return nil, err
+		}
+
+		sub.Events = strings.Split(events, ",")
+		subscriptions = append(subscriptions, sub)
+	}
+
+	return subscriptions, rows.Err()
+}
+
+// handle queue event for every active subscription interested in it
+// queuing the same event twice is a no-op, so the outbox can safely retry
+func (repo *webhookRepository) Enqueue(ctx context.Context, eventID, eventType, payload string) error {
+	subscriptions, err := repo.querySubscriptions(ctx, "WHERE active = ?", true)
+	if err != nil {
+		return err
+	}
+
+	for _, sub := range subscriptions {
+		if !sub.Subscribes(eventType) {
+			continue
+		}
+
+		_, err := repo.db.ExecContext(ctx,
+			repo.db.dialect.insertIgnore("webhook_deliveries", "subscription_id, event_id, event_type, payload, status", 5),
+			sub.ID, eventID, eventType, payload, constants.DeliveryPending,
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// handle lock due deliveries for this worker, SKIP LOCKED keeps concurrent instances apart
+// and the lease makes claimed rows invisible until the worker reports back
+func (repo *webhookRepository) ClaimDue(ctx context.Context, limit int) ([]models.WebhookDelivery, error) {
+	tx, err := repo.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(ctx,
+		deliverySelect+" WHERE status = ? AND next_attempt_at <= ? ORDER BY id LIMIT ?"+tx.dialect.forUpdate(true),
+		constants.DeliveryPending, time.Now(), limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	deliveries, err := scanWebhookDeliveries(rows)
+	if err != nil {
+		return nil, err
+	}
+
+	leaseUntil := time.Now().Add(models.DeliveryLease)
+
+	for _, delivery := range deliveries {
+		if _, err := tx.ExecContext(ctx, "UPDATE webhook_deliveries SET next_attempt_at = ? WHERE id = ?", leaseUntil, delivery.ID); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return deliveries, nil
+}
+
+// handle store attempt result and schedule next state of the delivery
+func (repo *webhookRepository) RecordAttempt(ctx context.Context, delivery *models.WebhookDelivery, attempt models.WebhookDeliveryAttempt, status string, nextAttemptAt time.Time) error {
+	tx, err := repo.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx,
+		"INSERT INTO webhook_delivery_attempts (delivery_id, status_code, error, duration_ms) VALUES (?, ?, ?, ?)",
+		delivery.ID, attempt.StatusCode, attempt.Error, attempt.Duration.Milliseconds(),
+	)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx,
+		"UPDATE webhook_deliveries SET status = ?, attempts = attempts + 1, next_attempt_at = ?, last_status_code = ?, last_error = ?, updated_at = ? WHERE id = ?",
+		status, nextAttemptAt, attempt.StatusCode, attempt.Error, time.Now(), delivery.ID,
+	)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (repo *webhookRepository) GetDelivery(ctx context.Context, id int64) (*models.WebhookDelivery, error) {
+	rows, err := repo.db.QueryContext(ctx, deliverySelect+" WHERE id = ?", id)
+	if err != nil {
+		return nil, err
+	}
+
+	deliveries, err := scanWebhookDeliveries(rows)
+	if err != nil || len(deliveries) == 0 {
+		return nil, err
+	}
+	return &deliveries[0], nil
+}
+
+func (repo *webhookRepository) ListDeliveries(ctx context.Context, filter models.WebhookDeliveryFilter) ([]models.WebhookDelivery, error) {
+	var conditions []string
+	var args []interface{}
+
+	if filter.SubscriptionID > 0 {
+		conditions = append(conditions, "subscription_id = ?")
+		args = append(args, filter.SubscriptionID)
+	}
+
+	if filter.Status != "" {
+		conditions = append(conditions, "status = ?")
+		args = append(args, filter.Status)
+	}
+
+	query := deliverySelect
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	query += " ORDER BY id DESC LIMIT ?"
+	args = append(args, filter.PageSize())
+
+	rows, err := repo.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	return scanWebhookDeliveries(rows)
+}
+
+func (repo *webhookRepository) ListAttempts(ctx context.Context, deliveryID int64) ([]models.WebhookDeliveryAttempt, error) {
+	rows, err := repo.db.QueryContext(ctx,
+		"SELECT id, delivery_id, status_code, COALESCE(error, ''), duration_ms, created_at FROM webhook_delivery_attempts WHERE delivery_id = ? ORDER BY id",
+		deliveryID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	attempts := []models.WebhookDeliveryAttempt{}
+
+	for rows.Next() {
+		var attempt models.WebhookDeliveryAttempt
+		var statusCode sql.NullInt64
+		var durationMs int64
+
+		if err := rows.Scan(&attempt.ID, &attempt.DeliveryID, &statusCode, &attempt.Error, &durationMs, &attempt.CreatedAt); err != nil {
+			return nil, err
+		}
+
+		attempt.StatusCode = nullIntPtr(statusCode)
+		attempt.Duration = time.Duration(durationMs) * time.Millisecond
+		attempts = append(attempts, attempt)
+	}
+
+	return attempts, rows.Err()
+}
+
+// handle put delivery back in the queue with a fresh retry budget, attempts history is kept
+func (repo *webhookRepository) Redeliver(ctx context.Context, id int64) error {
+	_, err := repo.db.ExecContext(ctx,
+		"UPDATE webhook_deliveries SET status = ?, attempts = 0, next_attempt_at = ?, updated_at = ? WHERE id = ?",
+		constants.DeliveryPending, time.Now(), time.Now(), id,
+	)
+	return err
+}
+
+const deliverySelect = "SELECT id, subscription_id, event_id, event_type, payload, status, attempts, next_attempt_at, last_status_code, COALESCE(last_error, ''), created_at, updated_at FROM webhook_deliveries"
+
+func scanWebhookDeliveries(rows *sql.Rows) ([]models.WebhookDelivery, error) {
+	defer rows.Close()
+
+	deliveries := []models.WebhookDelivery{}
+
+	for rows.Next() {
+		var delivery models.WebhookDelivery
+		var statusCode sql.NullInt64
+
+		err := rows.Scan(
+			&delivery.ID, &delivery.SubscriptionID, &delivery.EventID, &delivery.EventType, &delivery.Payload,
+			&delivery.Status, &delivery.Attempts, &delivery.NextAttemptAt, &statusCode, &delivery.LastError,
+			&delivery.CreatedAt, &delivery.UpdatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		delivery.LastStatusCode = nullIntPtr(statusCode)
+		deliveries = append(deliveries, delivery)
+	}
+
+	return deliveries, rows.Err()
+}
@@ -0,0 +1,116 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// instrumentation scope of every span started by the app
+const instrumentationName = "user-auth-go"
+
+type Options struct {
+	// none, stdout or otlp
+	Exporter string
+	// OTLP/HTTP endpoint like `http://localhost:4318`, empty uses the OTEL_EXPORTER_OTLP_* variables
+	Endpoint    string
+	ServiceName string
+	// share of new traces kept, between 0 and 1, traces started upstream keep their decision
+	SampleRatio float64
+}
+
+// handle install the global tracer provider and W3C propagation, the returned
+// shutdown flushes pending spans and must run before the process exits
+func Setup(ctx context.Context, opts Options) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	var exporter sdktrace.SpanExporter
+	var err error
+
+	switch opts.Exporter {
+	case "", "none":
+		return func(context.Context) error { return nil }, nil
+	case "stdout":
+		exporter, err = stdouttrace.New(stdouttrace.WithWriter(os.Stdout))
+	case "otlp":
+		var exporterOpts []otlptracehttp.Option
+		if opts.Endpoint != "" {
+			exporterOpts = append(exporterOpts, otlptracehttp.WithEndpointURL(opts.Endpoint))
+		}
+		exporter, err = otlptracehttp.New(ctx, exporterOpts...)
+	default:
+		return nil, fmt.Errorf("unknown tracing exporter %q, expected none, stdout or otlp", opts.Exporter)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to create %s trace exporter: %w", opts.Exporter, err)
+	}
+
+	res, err := resource.New(ctx, resource.WithTelemetrySDK(), resource.WithAttributes(semconv.ServiceName(opts.ServiceName)))
+	if err != nil {
+		return nil, err
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(opts.SampleRatio))),
+	)
+	otel.SetTracerProvider(provider)
+
+	return provider.Shutdown, nil
+}
+
+// handle start a span under ctx with the global tracer provider, looked up on every
+// call so providers installed later (like in tests) are used
+func Start(ctx context.Context, name string, opts ...trace.SpanStartOption) (context.Context, trace.Span) {
+	return otel.Tracer(instrumentationName).Start(ctx, name, opts...)
+}
+
+// handle mark the span failed when err is set
+func RecordError(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+}
+
+// handle server span per request, continuing traces from incoming `traceparent` headers.
+// The span is renamed to the route pattern once the mux matched, see middleware.Route
+func Middleware() func(http.Handler) http.Handler {
+	return otelhttp.NewMiddleware("http.server", otelhttp.WithSpanNameFormatter(spanName))
+}
+
+// handle `POST /api/v1/login` once the mux matched, the bare method before that
+func spanName(_ string, r *http.Request) string {
+	if r.Pattern == "" {
+		return r.Method
+	}
+	return r.Method + " " + RoutePath(r.Pattern)
+}
+
+// handle path of a ServeMux pattern, patterns like `GET /api/v1/profile` already name the method
+func RoutePath(pattern string) string {
+	if method, path, found := strings.Cut(pattern, " "); found && !strings.HasPrefix(method, "/") {
+		return path
+	}
+	return pattern
+}
+
+// handle HTTP client creating a span per outgoing request and sending `traceparent`
+func HTTPClient() *http.Client {
+	return &http.Client{Transport: otelhttp.NewTransport(http.DefaultTransport)}
+}
@@ -0,0 +1,6 @@
+package webhooks
+
+import "user-auth-go/internal/models"
+
+// Event is the JSON body posted to subscribers, it is the outbox envelope unchanged
+type Event = models.EventEnvelope
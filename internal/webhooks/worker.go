@@ -0,0 +1,158 @@
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+	"user-auth-go/constants"
+	"user-auth-go/internal/models"
+)
+
+// Worker delivers queued webhook deliveries to their subscriptions
+type Worker struct {
+	Webhooks    models.WebhookRepository
+	Client      *http.Client
+	Interval    time.Duration
+	BatchSize   int
+	MaxAttempts int
+	BaseBackoff time.Duration
+	MaxBackoff  time.Duration
+}
+
+func NewWorker(webhooks models.WebhookRepository) *Worker {
+	return &Worker{
+		Webhooks:    webhooks,
+		Client:      &http.Client{Timeout: 10 * time.Second},
+		Interval:    5 * time.Second,
+		BatchSize:   20,
+		MaxAttempts: 10,
+		BaseBackoff: 30 * time.Second,
+		MaxBackoff:  6 * time.Hour,
+	}
+}
+
+// handle poll for due deliveries until context is cancelled
+func (w *Worker) Run(ctx context.Context) {
+	ticker := time.NewTicker(w.Interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := w.ProcessDue(ctx); err != nil {
+			slog.Error("Webhook worker failed", "error", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// handle deliver one batch of due deliveries, returns how many were attempted
+func (w *Worker) ProcessDue(ctx context.Context) (int, error) {
+	deliveries, err := w.Webhooks.ClaimDue(ctx, w.BatchSize)
+	if err != nil {
+		return 0, fmt.Errorf("failed to claim deliveries: %w", err)
+	}
+
+	for i := range deliveries {
+		if err := w.process(ctx, &deliveries[i]); err != nil {
+			slog.Error("Webhook worker failed to deliver", "delivery_id", deliveries[i].ID, "error", err)
+		}
+	}
+
+	return len(deliveries), nil
+}
+
+func (w *Worker) process(ctx context.Context, delivery *models.WebhookDelivery) error {
+	sub, err := w.Webhooks.GetSubscription(ctx, delivery.SubscriptionID)
+	if err != nil {
+		return err
+	}
+
+	if sub == nil || !sub.Active {
+		attempt := models.WebhookDeliveryAttempt{Error: "subscription inactive or deleted"}
+		return w.Webhooks.RecordAttempt(ctx, delivery, attempt, constants.DeliveryFailed, time.Now())
+	}
+
+	started := time.Now()
+	statusCode, deliverErr := Deliver(ctx, w.Client, sub.URL, sub.Secret, delivery.EventID, delivery.EventType, []byte(delivery.Payload), started)
+
+	attempt := models.WebhookDeliveryAttempt{Duration: time.Since(started)}
+	if statusCode > 0 {
+		attempt.StatusCode = &statusCode
+	}
+	if deliverErr != nil {
+		attempt.Error = deliverErr.Error()
+	}
+
+	status := constants.DeliverySucceeded
+	nextAttemptAt := time.Now()
+
+	if deliverErr != nil {
+		status = constants.DeliveryPending
+		nextAttemptAt = nextAttemptAt.Add(Backoff(delivery.Attempts+1, w.BaseBackoff, w.MaxBackoff))
+
+		if delivery.Attempts+1 >= w.MaxAttempts {
+			status = constants.DeliveryFailed
+		}
+	}
+
+	return w.Webhooks.RecordAttempt(ctx, delivery, attempt, status, nextAttemptAt)
+}
+
+// Deliver POSTs a signed payload, any non 2xx answer counts as failure
+func Deliver(ctx context.Context, client *http.Client, url, secret, eventID, eventType string, body []byte, now time.Time) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+
+	timestamp := now.Unix()
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "user-auth-go-webhooks/1")
+	req.Header.Set(HeaderID, eventID)
+	req.Header.Set(HeaderEvent, eventType)
+	req.Header.Set(HeaderTimestamp, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(HeaderSignature, Sign(secret, timestamp, body))
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	// drain a bit of the body so the connection can be reused
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64*1024))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+
+	return resp.StatusCode, nil
+}
+
+// Backoff doubles the wait after each failed attempt: base, 2*base, 4*base... capped at max
+func Backoff(attempt int, base, max time.Duration) time.Duration {
+	if attempt < 1 {
+		attempt = 1
+	}
+
+	if attempt > 32 {
+		return max
+	}
+
+	wait := base << (attempt - 1)
+	if wait <= 0 || wait > max {
+		return max
+	}
+
+	return wait
+}
@@ -0,0 +1,251 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"user-auth-go/constants"
+	"user-auth-go/internal/admin"
+	"user-auth-go/internal/models"
+	"user-auth-go/internal/storage/sqlstore"
+)
+
+type adminResult struct {
+	OK       bool            `json:"ok"`
+	Message  string          `json:"message"`
+	Data     json.RawMessage `json:"data"`
+	Error    string          `json:"error"`
+	ExitCode int             `json:"exit_code"`
+}
+
+// handle run the admin CLI in JSON mode against the store and decode its output
+func runAdmin(t *testing.T, store *models.Store, stdin string, args ...string) (int, adminResult) {
+	t.Helper()
+
+	var stdout, stderr bytes.Buffer
+	cli := &admin.CLI{
+		Stdout: &stdout,
+		Stderr: &stderr,
+		Stdin:  strings.NewReader(stdin),
+		Open:   func() (*models.Store, error) { return store, nil },
+	}
+
+	code := cli.Run(append([]string{"--json"}, args...))
+
+	var result adminResult
+	if err := json.Unmarshal(stdout.Bytes(), &result); err != nil {
+		t.Fatalf("Expected JSON output for %v, got %q: %s", args, stdout.String(), err)
+	}
+
+	if result.ExitCode != code {
+		t.Errorf("Expected exit_code %d in output, got %d", code, result.ExitCode)
+	}
+
+	return code, result
+}
+
+// tests user create reports conflicts, unknown users and password policy with distinct exit codes
+func TestAdminUserCreate(t *testing.T) {
+	store := newTestStore(t)
+
+	code, result := runAdmin(t, store, "", "user", "create", "--email", "cli@example.com", "--password", "violet-Harbor-42", "--admin")
+	if code != admin.ExitOK || !result.OK {
+		t.Fatalf("Expected user create to succeed, got %d: %s", code, result.Error)
+	}
+
+	var created admin.UserOutput
+	json.Unmarshal(result.Data, &created)
+	if created.Email != "cli@example.com" || created.Role != constants.RoleAdmin {
+		t.Errorf("Expected created admin in output, got %+v", created)
+	}
+
+	user, _ := store.Users.GetByID(context.Background(), created.ID)
+	if user == nil || !user.CheckPassword("violet-Harbor-42") || !user.IsAdmin() {
+		t.Fatalf("Expected admin user with hashed password to be stored, got %+v", user)
+	}
+
+	code, _ = runAdmin(t, store, "", "user", "create", "--email", "cli@example.com", "--password", "violet-Harbor-42")
+	if code != admin.ExitConflict {
+		t.Errorf("Expected exit code %d for existing email, got %d", admin.ExitConflict, code)
+	}
+
+	code, _ = runAdmin(t, store, "", "user", "create", "--email", "weak@example.com", "--password", "short")
+	if code != admin.ExitUsage {
+		t.Errorf("Expected exit code %d for rejected password, got %d", admin.ExitUsage, code)
+	}
+
+	code, _ = runAdmin(t, store, "violet-Harbor-42\n", "user", "create", "--email", "stdin@example.com", "--password-stdin")
+	if code != admin.ExitOK {
+		t.Errorf("Expected password from stdin to be accepted, got %d", code)
+	}
+
+	code, _ = runAdmin(t, store, "", "user", "promote", "--user", "missing@example.com")
+	if code != admin.ExitNotFound {
+		t.Errorf("Expected exit code %d for unknown user, got %d", admin.ExitNotFound, code)
+	}
+
+	code, _ = runAdmin(t, store, "", "user", "frobnicate")
+	if code != admin.ExitUsage {
+		t.Errorf("Expected exit code %d for unknown command, got %d", admin.ExitUsage, code)
+	}
+}
+
+// tests disabled users are signed out and rejected until enabled again
+func TestAdminDisableUser(t *testing.T) {
+	srv, store := newTestServer(t)
+
+	user := createUser(t, store, "disable_me@example.com", "password123")
+	session, _ := store.Sessions.Create(context.Background(), user.ID)
+
+	code, result := runAdmin(t, store, "", "user", "disable", "--user", "disable_me@example.com")
+	if code != admin.ExitOK {
+		t.Fatalf("Expected disable to succeed, got %d: %s", code, result.Error)
+	}
+
+	var disabled admin.UserOutput
+	json.Unmarshal(result.Data, &disabled)
+	if !disabled.Disabled || disabled.DisabledAt == nil {
+		t.Errorf("Expected disabled user in output, got %+v", disabled)
+	}
+
+	if existing, _ := store.Sessions.GetByToken(context.Background(), session.Token); existing != nil {
+		t.Errorf("Expected sessions to be revoked on disable")
+	}
+
+	rr := postJSON(srv.Login, "/api/login", map[string]string{
+		"email":    "disable_me@example.com",
+		"password": "password123",
+	})
+	if rr.Code != http.StatusForbidden {
+		t.Errorf("Expected status 403 for disabled login, got %d", rr.Code)
+	}
+
+	// a session created before the flag was set is rejected too
+	stale, _ := store.Sessions.Create(context.Background(), user.ID)
+	rr = getWithSession(srv.AuthGuard(srv.GetProfile), "/api/profile", stale.Token)
+	if rr.Code != http.StatusForbidden {
+		t.Errorf("Expected status 403 for disabled session, got %d", rr.Code)
+	}
+
+	code, _ = runAdmin(t, store, "", "user", "enable", "--user", "disable_me@example.com")
+	if code != admin.ExitOK {
+		t.Fatalf("Expected enable to succeed, got %d", code)
+	}
+
+	rr = postJSON(srv.Login, "/api/login", map[string]string{
+		"email":    "disable_me@example.com",
+		"password": "password123",
+	})
+	if rr.Code != http.StatusOK {
+		t.Errorf("Expected status 200 after enable, got %d", rr.Code)
+	}
+
+	events, _ := store.Audit.Query(context.Background(), models.AuditFilter{UserID: &user.ID, EventType: constants.EventUserDisable})
+	if len(events) != 1 || events[0].UserAgent != "admin-cli" {
+		t.Errorf("Expected one audited CLI disable, got %+v", events)
+	}
+}
+
+// tests set-password replaces the hash and revokes every session
+func TestAdminSetPassword(t *testing.T) {
+	store := newTestStore(t)
+
+	user := createUser(t, store, "reset_me@example.com", "password123")
+	session, _ := store.Sessions.Create(context.Background(), user.ID)
+
+	code, result := runAdmin(t, store, "", "user", "set-password", "--user", strconv.Itoa(user.ID), "--password", "violet-Harbor-42")
+	if code != admin.ExitOK {
+		t.Fatalf("Expected set-password to succeed, got %d: %s", code, result.Error)
+	}
+
+	updated, _ := store.Users.GetByID(context.Background(), user.ID)
+	if !updated.CheckPassword("violet-Harbor-42") {
+		t.Errorf("Expected new password to be stored")
+	}
+
+	if existing, _ := store.Sessions.GetByToken(context.Background(), session.Token); existing != nil {
+		t.Errorf("Expected sessions to be revoked after password reset")
+	}
+
+	code, _ = runAdmin(t, store, "", "user", "set-password", "--user", "reset_me@example.com")
+	if code != admin.ExitUsage {
+		t.Errorf("Expected exit code %d without password, got %d", admin.ExitUsage, code)
+	}
+}
+
+// tests session commands revoke a user's sessions and purge expired ones
+func TestAdminSessions(t *testing.T) {
+	store := newTestStore(t)
+
+	user := createUser(t, store, "sessions_cli@example.com", "password123")
+	first, _ := store.Sessions.Create(context.Background(), user.ID)
+	store.Sessions.Create(context.Background(), user.ID)
+
+	code, _ := runAdmin(t, store, "", "sessions", "revoke", "--user", "sessions_cli@example.com")
+	if code != admin.ExitOK {
+		t.Fatalf("Expected revoke to succeed, got %d", code)
+	}
+
+	if existing, _ := store.Sessions.GetByToken(context.Background(), first.Token); existing != nil {
+		t.Errorf("Expected sessions to be revoked")
+	}
+
+	code, result := runAdmin(t, store, "", "sessions", "purge-expired")
+	if code != admin.ExitOK {
+		t.Fatalf("Expected purge to succeed, got %d: %s", code, result.Error)
+	}
+
+	var purged map[string]int64
+	json.Unmarshal(result.Data, &purged)
+	if _, ok := purged["deleted"]; !ok {
+		t.Errorf("Expected deleted count in output, got %s", result.Data)
+	}
+
+	code, _ = runAdmin(t, store, "", "sessions", "revoke")
+	if code != admin.ExitUsage {
+		t.Errorf("Expected exit code %d without --user, got %d", admin.ExitUsage, code)
+	}
+}
+
+// tests migrate and config check report the schema state of the database
+func TestAdminMigrateAndConfigCheck(t *testing.T) {
+	db := openSQLite(t, filepath.Join(t.TempDir(), "admin.db"))
+
+	var stdout bytes.Buffer
+	cli := &admin.CLI{
+		Stdout:   &stdout,
+		Stderr:   &bytes.Buffer{},
+		Migrator: func() (*sqlstore.Migrator, error) { return sqlstore.NewMigrator(db, sqlstore.SQLite) },
+	}
+
+	if code := cli.Run([]string{"--json", "config", "check"}); code != admin.ExitFailure {
+		t.Errorf("Expected config check to fail with pending migrations, got %d", code)
+	}
+
+	if code := cli.Run([]string{"migrate", "up"}); code != admin.ExitOK {
+		t.Fatalf("Expected migrate up to succeed, got %d: %s", code, stdout.String())
+	}
+
+	stdout.Reset()
+	if code := cli.Run([]string{"config", "check"}); code != admin.ExitOK {
+		t.Fatalf("Expected config check to pass, got %d: %s", code, stdout.String())
+	}
+
+	var result adminResult
+	json.Unmarshal(stdout.Bytes(), &result)
+
+	var check admin.ConfigCheck
+	json.Unmarshal(result.Data, &check)
+	if check.Database != "ok" || check.PendingMigrations != 0 {
+		t.Errorf("Expected reachable database without pending migrations, got %+v", check)
+	}
+
+	if code := cli.Run([]string{"migrate", "down", "zero"}); code != admin.ExitUsage {
+		t.Errorf("Expected exit code %d for invalid steps, got %d", admin.ExitUsage, code)
+	}
+}
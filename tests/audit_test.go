@@ -0,0 +1,158 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"unicode/utf8"
+	"user-auth-go/constants"
+	"user-auth-go/internal/api"
+	"user-auth-go/internal/config"
+	"user-auth-go/internal/models"
+)
+
+func getWithSession(handler http.HandlerFunc, path, token string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	req.AddCookie(&http.Cookie{Name: config.SessionCookie.Name(), Value: token})
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	return rr
+}
+
+func decodeAuditEvents(t *testing.T, rr *httptest.ResponseRecorder) []api.AuditEventResponse {
+	t.Helper()
+
+	var response struct {
+		Data []api.AuditEventResponse `json:"data"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to decode response: %s", err)
+	}
+	return response.Data
+}
+
+// tests login attempts end up in the user's security activity
+func TestAuditLoginEvents(t *testing.T) {
+	srv, store := newTestServer(t)
+
+	createUser(t, store, "audit_login@example.com", "password123")
+
+	postJSON(srv.Login, "/api/login", map[string]string{"email": "audit_login@example.com", "password": "wrongpassword"})
+	rr := postJSON(srv.Login, "/api/login", map[string]string{"email": "audit_login@example.com", "password": "password123"})
+
+	token := tokenFromResponse(t, rr)
+	events := decodeAuditEvents(t, getWithSession(srv.AuthGuard(srv.SecurityActivity), "/api/profile/activity", token))
+
+	if len(events) < 2 {
+		t.Fatalf("Expected at least 2 events, got %d", len(events))
+	}
+
+	// newest first
+	if events[0].EventType != constants.EventLogin || events[0].Outcome != constants.OutcomeSuccess {
+		t.Errorf("Expected successful login first, got %s/%s", events[0].EventType, events[0].Outcome)
+	}
+
+	if events[1].EventType != constants.EventLogin || events[1].Outcome != constants.OutcomeFailure {
+		t.Errorf("Expected failed login second, got %s/%s", events[1].EventType, events[1].Outcome)
+	}
+
+	if events[1].Details["reason"] != "wrong_password" {
+		t.Errorf("Expected wrong_password reason, got %v", events[1].Details["reason"])
+	}
+
+	if events[0].IP != "192.0.2.1" {
+		t.Errorf("Expected IP from remote address, got %q", events[0].IP)
+	}
+}
+
+// tests admin query API is restricted and filters events
+func TestAdminAuditEvents(t *testing.T) {
+	srv, store := newTestServer(t)
+
+	admin := createUser(t, store, "audit_admin@example.com", "password123")
+	user := createUser(t, store, "audit_user@example.com", "password123")
+
+	store.Users.UpdateRole(context.Background(), admin.ID, constants.RoleAdmin)
+
+	adminSession, _ := store.Sessions.Create(context.Background(), admin.ID)
+	userSession, _ := store.Sessions.Create(context.Background(), user.ID)
+
+	handler := srv.AdminGuard(srv.AdminAuditEvents)
+
+	rr := getWithSession(handler, "/api/admin/audit-events", userSession.Token)
+	if rr.Code != http.StatusForbidden {
+		t.Errorf("Expected status 403 for non admin, got %d", rr.Code)
+	}
+
+	postJSON(srv.Login, "/api/login", map[string]string{"email": "audit_user@example.com", "password": "wrongpassword"})
+
+	rr = getWithSession(handler, "/api/admin/audit-events?event_type=auth.login&outcome=failure&user_id="+strconv.Itoa(user.ID), adminSession.Token)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d. Body: %s", rr.Code, rr.Body.String())
+	}
+
+	events := decodeAuditEvents(t, rr)
+	if len(events) != 1 {
+		t.Fatalf("Expected 1 filtered event, got %d", len(events))
+	}
+
+	if events[0].TargetUserID == nil || *events[0].TargetUserID != user.ID {
+		t.Errorf("Expected event targeting user %d", user.ID)
+	}
+
+	rr = getWithSession(handler, "/api/admin/audit-events?since=yesterday", adminSession.Token)
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for invalid since, got %d", rr.Code)
+	}
+
+	rr = getWithSession(handler, "/api/admin/audit-events?limit=100000000", adminSession.Token)
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for a limit over the maximum, got %d", rr.Code)
+	}
+}
+
+// tests guard denials are audited only for requests that presented credentials
+func TestAuditGuardDenied(t *testing.T) {
+	srv, store := newTestServer(t)
+	handler := srv.AuthGuard(srv.SecurityActivity)
+
+	anonymous := httptest.NewRequest(http.MethodGet, "/api/profile/activity", nil)
+	handler.ServeHTTP(httptest.NewRecorder(), anonymous)
+
+	getWithSession(handler, "/api/profile/activity", "expired-session")
+
+	events, err := store.Audit.Query(context.Background(), models.AuditFilter{EventType: constants.EventAuthGuardDenied})
+	if err != nil {
+		t.Fatalf("Failed to query audit events: %s", err)
+	}
+
+	if len(events) != 1 || events[0].Details["reason"] != "session_expired" {
+		t.Errorf("Expected only the expired session audited, got %+v", events)
+	}
+}
+
+// tests long user agents are cut to 512 characters without splitting a rune
+func TestAuditUserAgentTruncation(t *testing.T) {
+	srv, store := newTestServer(t)
+	user := createUser(t, store, "audit_agent@example.com", "password123")
+
+	req := httptest.NewRequest(http.MethodPost, "/api/login", strings.NewReader(`{"email":"audit_agent@example.com","password":"wrongpassword"}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "a"+strings.Repeat("é", 600))
+	srv.Login(httptest.NewRecorder(), req)
+
+	events, err := store.Audit.ListForUser(context.Background(), user.ID, 10)
+	if err != nil || len(events) == 0 {
+		t.Fatalf("Expected the failed login audited, got %v", err)
+	}
+
+	agent := events[0].UserAgent
+	if !utf8.ValidString(agent) || utf8.RuneCountInString(agent) != 512 {
+		t.Errorf("Expected 512 valid characters, got %d (valid %v)", utf8.RuneCountInString(agent), utf8.ValidString(agent))
+	}
+}
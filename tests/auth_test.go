@@ -2,29 +2,36 @@ package tests
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
 	"os"
 	"path/filepath"
 	"testing"
+	"user-auth-go/constants"
 	"user-auth-go/internal/api"
 	"user-auth-go/internal/config"
 	"user-auth-go/internal/models"
+	"user-auth-go/internal/storage/memory"
+	"user-auth-go/internal/storage/sqlstore"
 )
 
 func TestMain(m *testing.M) {
 	// ggt current working directory
 	cwd, _ := os.Getwd()
 
-	// find env file based on root dir
+	// find root dir, the optional env file lives next to go.mod
 	rootDir := findRootDir(cwd)
 	if rootDir != "" {
 		os.Chdir(rootDir)
 	}
 
-	// init config
-	config.Init()
+	// init config, tests build their own store so no database connection is made here
+	// and run without Google credentials, mail is only logged
+	os.Setenv("GOOGLE_OAUTH_ENABLED", "false")
+	os.Setenv("MAIL_DRIVER", "log")
+	config.Load()
 
 	// run tests
 	code := m.Run()
@@ -35,7 +42,7 @@ func TestMain(m *testing.M) {
 func findRootDir(startDir string) string {
 	dir := startDir
 	for {
-		if _, err := os.Stat(filepath.Join(dir, ".env")); err == nil {
+		if _, err := os.Stat(filepath.Join(dir, "go.mod")); err == nil {
 			return dir
 		}
 		parent := filepath.Dir(dir)
@@ -46,10 +53,80 @@ func findRootDir(startDir string) string {
 	}
 }
 
+// handle build an API server on a fresh store
+func newTestServer(t *testing.T) (*api.Server, *models.Store) {
+	t.Helper()
+
+	store := newTestStore(t)
+	return api.NewServer(store), store
+}
+
+// handle pick the backend from TEST_DB_DRIVER so the same suite runs against
+// memory (default), sqlite, mysql or postgres, TEST_DB_DSN points at the database
+func newTestStore(t *testing.T) *models.Store {
+	t.Helper()
+
+	driver := os.Getenv("TEST_DB_DRIVER")
+	if driver == "" || driver == "memory" {
+		return memory.New()
+	}
+
+	dsn := os.Getenv("TEST_DB_DSN")
+	if driver == "sqlite" && dsn == "" {
+		dsn = config.SQLiteDSN(filepath.Join(t.TempDir(), "test.db"))
+	}
+	if dsn == "" {
+		t.Fatalf("TEST_DB_DSN is required for TEST_DB_DRIVER=%s", driver)
+	}
+
+	db, err := config.OpenDB(driver, dsn)
+	if err != nil {
+		t.Fatalf("Failed to open database: %s", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	dialect := sqlstore.Dialect(driver)
+	migrator, err := sqlstore.NewMigrator(db, dialect)
+	if err != nil {
+		t.Fatalf("Failed to load migrations: %s", err)
+	}
+	if _, err := migrator.Up(context.Background()); err != nil {
+		t.Fatalf("Failed to migrate: %s", err)
+	}
+
+	// every test starts from empty tables, children first for the foreign keys
+	tables := []string{"webhook_delivery_attempts", "webhook_deliveries", "webhook_subscriptions", "outbox", "audit_events", "email_changes", "sessions", "users"}
+	for _, table := range tables {
+		if _, err := db.Exec("DELETE FROM " + table); err != nil {
+			t.Fatalf("Failed to clear %s: %s", table, err)
+		}
+	}
+
+	return sqlstore.New(db, dialect)
+}
+
+// handle create a local user the way signup does
+func createUser(t *testing.T, store *models.Store, email, plainPassword string) *models.User {
+	t.Helper()
+
+	hashedPassword, err := config.PasswordHasher.Hash(plainPassword)
+	if err != nil {
+		t.Fatalf("Failed to hash password: %s", err)
+	}
+
+	user, err := store.Users.Create(context.Background(), email, hashedPassword)
+	if err != nil {
+		t.Fatalf("Failed to create user: %s", err)
+	}
+	return user
+}
+
 // tests login flow
 func TestLoginSuccess(t *testing.T) {
+	srv, store := newTestServer(t)
+
 	// create user first
-	models.CreateUser("login_test@example.com", "password123")
+	createUser(t, store, "login_test@example.com", "password123")
 
 	body := map[string]string{
 		"email":    "login_test@example.com",
@@ -61,7 +138,7 @@ func TestLoginSuccess(t *testing.T) {
 	req.Header.Set("Content-Type", "application/json")
 
 	rr := httptest.NewRecorder()
-	handler := http.HandlerFunc(api.Login)
+	handler := http.HandlerFunc(srv.Login)
 	handler.ServeHTTP(rr, req)
 
 	if rr.Code != http.StatusOK {
@@ -74,19 +151,15 @@ func TestLoginSuccess(t *testing.T) {
 	if !response.Success {
 		t.Errorf("Expected success true, got false")
 	}
-
-	// cleanup
-	config.DB.Exec("DELETE FROM users WHERE email = ?", "login_test@example.com")
 }
 
 // tests signup flow
 func TestSignupSuccess(t *testing.T) {
-	// cleanup
-	config.DB.Exec("DELETE FROM users WHERE email = ?", "test_signup@example.com")
+	srv, _ := newTestServer(t)
 
 	body := map[string]string{
 		"email":    "test_signup@example.com",
-		"password": "password123",
+		"password": "violet-Harbor-42",
 	}
 	jsonBody, _ := json.Marshal(body)
 
@@ -94,7 +167,7 @@ func TestSignupSuccess(t *testing.T) {
 	req.Header.Set("Content-Type", "application/json")
 
 	rr := httptest.NewRecorder()
-	handler := http.HandlerFunc(api.Signup)
+	handler := http.HandlerFunc(srv.Signup)
 	handler.ServeHTTP(rr, req)
 
 	if rr.Code != http.StatusOK {
@@ -107,18 +180,14 @@ func TestSignupSuccess(t *testing.T) {
 	if !response.Success {
 		t.Errorf("Expected success true, got false: %s", response.Message)
 	}
-
-	// cleanup
-	config.DB.Exec("DELETE FROM users WHERE email = ?", "test_signup@example.com")
 }
 
 // tests wrong password
 func TestLoginWrongPassword(t *testing.T) {
-	// cleanup
-	config.DB.Exec("DELETE FROM users WHERE email = ?", "wrong_pass@example.com")
+	srv, store := newTestServer(t)
 
 	// create user first
-	models.CreateUser("wrong_pass@example.com", "password123")
+	createUser(t, store, "wrong_pass@example.com", "password123")
 
 	body := map[string]string{
 		"email":    "wrong_pass@example.com",
@@ -130,7 +199,7 @@ func TestLoginWrongPassword(t *testing.T) {
 	req.Header.Set("Content-Type", "application/json")
 
 	rr := httptest.NewRecorder()
-	handler := http.HandlerFunc(api.Login)
+	handler := http.HandlerFunc(srv.Login)
 	handler.ServeHTTP(rr, req)
 
 	if rr.Code != http.StatusUnauthorized {
@@ -140,10 +209,7 @@ func TestLoginWrongPassword(t *testing.T) {
 	var response api.APIResponse
 	json.Unmarshal(rr.Body.Bytes(), &response)
 
-	if response.Message != "Username or password is incorrect" {
-		t.Errorf("Expected 'Password is incorrect', got '%s'", response.Message)
+	if response.Error == nil || response.Error.Code != constants.ErrorInvalidCredentials {
+		t.Errorf("Expected error code %s, got %+v", constants.ErrorInvalidCredentials, response.Error)
 	}
-
-	// cleanup
-	config.DB.Exec("DELETE FROM users WHERE email = ?", "wrong_pass@example.com")
 }
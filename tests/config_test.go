@@ -0,0 +1,175 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"user-auth-go/internal/admin"
+	"user-auth-go/internal/config"
+)
+
+func writeConfigFile(t *testing.T, name, content string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("Failed to write config file: %s", err)
+	}
+	return path
+}
+
+// tests flags override env, env overrides the file and the file overrides defaults
+func TestConfigPrecedence(t *testing.T) {
+	path := writeConfigFile(t, "config.yaml", `
+server:
+  port: "9000"
+  base_url: https://auth.example.com
+database:
+  driver: sqlite
+  path: from-file.db
+google:
+  enabled: false
+password:
+  min_length: 10
+cors:
+  allowed_origins: [https://app.example.com]
+`)
+
+	t.Setenv("DB_PATH", "from-env.db")
+	t.Setenv("PASSWORD_MIN_LENGTH", "12")
+
+	cfg, err := config.Resolve([]string{"--config", path, "--password-min-length", "14", "--database-auto-migrate"})
+	if err != nil {
+		t.Fatalf("Expected valid config, got %s", err)
+	}
+
+	if cfg.Server.Port != "9000" || cfg.Server.BaseURL != "https://auth.example.com" {
+		t.Errorf("Expected server settings from file, got %+v", cfg.Server)
+	}
+
+	if cfg.Database.Path != "from-env.db" {
+		t.Errorf("Expected env to override file, got %q", cfg.Database.Path)
+	}
+
+	if cfg.Password.MinLength != 14 || !cfg.Database.AutoMigrate {
+		t.Errorf("Expected flags to override env, got min length %d auto migrate %v", cfg.Password.MinLength, cfg.Database.AutoMigrate)
+	}
+
+	if len(cfg.CORS.AllowedOrigins) != 1 || cfg.CORS.AllowedOrigins[0] != "https://app.example.com" {
+		t.Errorf("Expected list from file, got %v", cfg.CORS.AllowedOrigins)
+	}
+
+	// untouched settings keep their defaults
+	if cfg.Password.HashAlgorithm != "argon2id" || cfg.Session.CookieName != "session_token" {
+		t.Errorf("Expected defaults for unset settings, got %q and %q", cfg.Password.HashAlgorithm, cfg.Session.CookieName)
+	}
+}
+
+// tests TOML files are read the same way
+func TestConfigTOMLFile(t *testing.T) {
+	path := writeConfigFile(t, "config.toml", `
+[database]
+driver = "postgres"
+host = "db.internal"
+
+[google]
+client_id = "client-id"
+client_secret = "client-secret"
+`)
+
+	t.Setenv("CONFIG_FILE", path)
+
+	cfg, err := config.Resolve(nil)
+	if err != nil {
+		t.Fatalf("Expected valid config, got %s", err)
+	}
+
+	if cfg.Database.Driver != "postgres" || cfg.Database.Host != "db.internal" || cfg.Google.ClientID != "client-id" {
+		t.Errorf("Expected settings from TOML file, got %+v %+v", cfg.Database, cfg.Google)
+	}
+
+	if cfg.Google.RedirectURL != "http://localhost:8080/api/v1/auth/google/callback" {
+		t.Errorf("Expected default redirect URL to match the callback route, got %q", cfg.Google.RedirectURL)
+	}
+}
+
+// tests every problem is reported at once instead of stopping at the first
+func TestConfigValidationAggregatesErrors(t *testing.T) {
+	t.Setenv("GOOGLE_OAUTH_ENABLED", "true")
+	t.Setenv("DB_DRIVER", "oracle")
+	t.Setenv("PORT", "http")
+	t.Setenv("DB_AUTO_MIGRATE", "maybe")
+	t.Setenv("MAIL_DRIVER", "pigeon")
+	t.Setenv("SERVER_READ_HEADER_TIMEOUT", "0")
+	t.Setenv("SHUTDOWN_DRAIN_DELAY", "-1")
+	t.Setenv("TLS_CLIENT_AUTH", "always")
+	t.Setenv("TLS_CLIENT_IDENTITIES", "billing=billing@example.com")
+	t.Setenv("CORS_ALLOWED_ORIGINS", "*")
+	t.Setenv("CORS_ALLOW_CREDENTIALS", "true")
+	t.Setenv("OUTBOX_SINKS", "nats")
+	t.Setenv("NATS_URL", "https://nats.example.com")
+	t.Setenv("OUTBOX_RETENTION", "-1")
+
+	_, err := config.Resolve(nil)
+
+	var validationErr *config.ValidationError
+	if !errors.As(err, &validationErr) {
+		t.Fatalf("Expected a validation error, got %v", err)
+	}
+
+	for _, expected := range []string{"GOOGLE_CLIENT_ID", "GOOGLE_CLIENT_SECRET", "DB_DRIVER", "PORT", "DB_AUTO_MIGRATE", "MAIL_DRIVER", "SERVER_READ_HEADER_TIMEOUT", "SHUTDOWN_DRAIN_DELAY", "TLS_CLIENT_AUTH", "TLS_CLIENT_IDENTITIES", "CORS_ALLOWED_ORIGINS", "NATS_URL", "OUTBOX_RETENTION"} {
+		if !strings.Contains(err.Error(), expected) {
+			t.Errorf("Expected %s to be reported, got:\n%s", expected, err)
+		}
+	}
+}
+
+// tests typos in the config file are rejected
+func TestConfigUnknownFileKey(t *testing.T) {
+	path := writeConfigFile(t, "config.yaml", "database:\n  drvier: sqlite\n")
+
+	if _, err := config.Resolve([]string{"--config", path}); err == nil || !strings.Contains(err.Error(), "drvier") {
+		t.Errorf("Expected unknown key error, got %v", err)
+	}
+}
+
+// tests config print masks secrets
+func TestConfigPrintRedactsSecrets(t *testing.T) {
+	t.Setenv("GOOGLE_OAUTH_ENABLED", "true")
+	t.Setenv("GOOGLE_CLIENT_ID", "visible-client-id")
+	t.Setenv("GOOGLE_CLIENT_SECRET", "super-secret-value")
+	t.Setenv("DB_PASS", "db-password")
+
+	var stdout bytes.Buffer
+	cli := &admin.CLI{Stdout: &stdout, Stderr: &bytes.Buffer{}}
+
+	if code := cli.Run([]string{"config", "print", "--format", "env"}); code != admin.ExitOK {
+		t.Fatalf("Expected config print to succeed, got %d", code)
+	}
+
+	output := stdout.String()
+	if strings.Contains(output, "super-secret-value") || strings.Contains(output, "db-password") {
+		t.Errorf("Expected secrets to be redacted, got:\n%s", output)
+	}
+
+	if !strings.Contains(output, "GOOGLE_CLIENT_ID=visible-client-id") || !strings.Contains(output, "GOOGLE_CLIENT_SECRET=******") {
+		t.Errorf("Expected plain and redacted values, got:\n%s", output)
+	}
+
+	stdout.Reset()
+	if code := cli.Run([]string{"--json", "config", "print"}); code != admin.ExitOK {
+		t.Fatalf("Expected config print to succeed, got %d", code)
+	}
+
+	var result struct {
+		Data config.Config `json:"data"`
+	}
+	json.Unmarshal(stdout.Bytes(), &result)
+	if result.Data.Google.ClientSecret != "******" || result.Data.Database.Pass != "******" {
+		t.Errorf("Expected redacted secrets in JSON, got %+v", result.Data.Google)
+	}
+}
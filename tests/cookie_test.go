@@ -0,0 +1,318 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+	"user-auth-go/internal/api"
+	"user-auth-go/internal/config"
+	"user-auth-go/internal/cookies"
+	"user-auth-go/internal/models"
+)
+
+// swap session cookie manager for the duration of a test
+func useSessionCookie(t *testing.T, opts cookies.Options) *cookies.Manager {
+	t.Helper()
+
+	manager, err := cookies.New(opts)
+	if err != nil {
+		t.Fatalf("Failed to create cookie manager: %s", err)
+	}
+
+	previous := config.SessionCookie
+	config.SessionCookie = manager
+	t.Cleanup(func() { config.SessionCookie = previous })
+
+	return manager
+}
+
+// handle session token of the cookie a response set, the body must not carry it
+func tokenFromResponse(t *testing.T, rr *httptest.ResponseRecorder) string {
+	t.Helper()
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	for _, cookie := range rr.Result().Cookies() {
+		req.AddCookie(cookie)
+	}
+
+	token, err := config.SessionCookie.Read(req)
+	if err != nil {
+		t.Fatalf("Expected a session cookie, got %q: %s", rr.Header().Get("Set-Cookie"), err)
+	}
+
+	if strings.Contains(rr.Body.String(), token) {
+		t.Errorf("Expected the session token to stay out of the body, got: %s", rr.Body.String())
+	}
+	return token
+}
+
+func postJSON(handler http.HandlerFunc, path string, body interface{}) *httptest.ResponseRecorder {
+	jsonBody, _ := json.Marshal(body)
+
+	req := httptest.NewRequest(http.MethodPost, path, bytes.NewBuffer(jsonBody))
+	req.Header.Set("Content-Type", "application/json")
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	return rr
+}
+
+// tests signup emits the configured cookie attributes
+func TestSignupSetCookieHeader(t *testing.T) {
+	srv, _ := newTestServer(t)
+
+	useSessionCookie(t, cookies.Options{
+		Name:     "session_token",
+		Path:     "/",
+		SameSite: http.SameSiteLaxMode,
+		MaxAge:   86400,
+	})
+
+	rr := postJSON(srv.Signup, "/api/signup", map[string]string{
+		"email":    "cookie_signup@example.com",
+		"password": "violet-Harbor-42",
+	})
+
+	token := tokenFromResponse(t, rr)
+	expected := fmt.Sprintf("session_token=%s; Path=/; Max-Age=86400; HttpOnly; SameSite=Lax", token)
+
+	if got := rr.Header().Get("Set-Cookie"); got != expected {
+		t.Errorf("Expected Set-Cookie %q, got %q", expected, got)
+	}
+}
+
+// tests login emits a __Host- prefixed, secure and strict cookie
+func TestLoginSetCookieHeaderHostPrefix(t *testing.T) {
+	srv, store := newTestServer(t)
+
+	useSessionCookie(t, cookies.Options{
+		Name:       "session_token",
+		Secure:     true,
+		SameSite:   http.SameSiteStrictMode,
+		HostPrefix: true,
+		MaxAge:     86400,
+	})
+
+	createUser(t, store, "cookie_login@example.com", "password123")
+
+	rr := postJSON(srv.Login, "/api/login", map[string]string{
+		"email":    "cookie_login@example.com",
+		"password": "password123",
+	})
+
+	token := tokenFromResponse(t, rr)
+	expected := fmt.Sprintf("__Host-session_token=%s; Path=/; Max-Age=86400; HttpOnly; Secure; SameSite=Strict", token)
+
+	if got := rr.Header().Get("Set-Cookie"); got != expected {
+		t.Errorf("Expected Set-Cookie %q, got %q", expected, got)
+	}
+}
+
+// tests login with a custom domain and sealed cookie value
+func TestLoginSetCookieHeaderSealed(t *testing.T) {
+	srv, store := newTestServer(t)
+
+	manager := useSessionCookie(t, cookies.Options{
+		Name:     "sid",
+		Domain:   "example.com",
+		Path:     "/",
+		Secure:   true,
+		SameSite: http.SameSiteNoneMode,
+		MaxAge:   86400,
+		SealKey:  bytes.Repeat([]byte{0x42}, 32),
+	})
+
+	createUser(t, store, "cookie_sealed@example.com", "password123")
+
+	rr := postJSON(srv.Login, "/api/login", map[string]string{
+		"email":    "cookie_sealed@example.com",
+		"password": "password123",
+	})
+
+	token := tokenFromResponse(t, rr)
+	header := rr.Header().Get("Set-Cookie")
+
+	value := strings.TrimPrefix(strings.SplitN(header, ";", 2)[0], "sid=")
+	if value == token {
+		t.Errorf("Expected sealed cookie value, got the raw session token")
+	}
+
+	expected := fmt.Sprintf("sid=%s; Path=/; Domain=example.com; Max-Age=86400; HttpOnly; Secure; SameSite=None", value)
+	if header != expected {
+		t.Errorf("Expected Set-Cookie %q, got %q", expected, header)
+	}
+
+	// sealed value must open back to the session token
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.AddCookie(&http.Cookie{Name: "sid", Value: value})
+
+	opened, err := manager.Read(req)
+	if err != nil || opened != token {
+		t.Errorf("Expected sealed cookie to open to %q, got %q (%v)", token, opened, err)
+	}
+}
+
+// tests logout clears the cookie with the same attributes it was set with
+func TestLogoutSetCookieHeader(t *testing.T) {
+	srv, store := newTestServer(t)
+
+	useSessionCookie(t, cookies.Options{
+		Name:     "session_token",
+		Path:     "/",
+		Secure:   true,
+		SameSite: http.SameSiteLaxMode,
+		MaxAge:   86400,
+	})
+
+	user := createUser(t, store, "cookie_logout@example.com", "password123")
+
+	session, err := store.Sessions.Create(context.Background(), user.ID)
+	if err != nil {
+		t.Fatalf("Failed to create session: %s", err)
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/api/logout", nil)
+	req.AddCookie(&http.Cookie{Name: "session_token", Value: session.Token})
+
+	rr := httptest.NewRecorder()
+	srv.AuthGuard(srv.Logout).ServeHTTP(rr, req)
+
+	expected := "session_token=; Path=/; Max-Age=0; HttpOnly; Secure; SameSite=Lax"
+	if got := rr.Header().Get("Set-Cookie"); got != expected {
+		t.Errorf("Expected Set-Cookie %q, got %q", expected, got)
+	}
+}
+
+type failingSessionDelete struct {
+	models.SessionRepository
+}
+
+func (failingSessionDelete) Delete(ctx context.Context, token string) error {
+	return errors.New("connection reset")
+}
+
+// tests a logout the store could not apply fails and keeps the cookie
+func TestLogoutSessionDeleteFails(t *testing.T) {
+	_, store := newTestServer(t)
+	store.Sessions = failingSessionDelete{store.Sessions}
+	srv := api.NewServer(store)
+
+	user := createUser(t, store, "cookie_logout_fail@example.com", "password123")
+	session, _ := store.Sessions.Create(context.Background(), user.ID)
+
+	req := httptest.NewRequest(http.MethodPost, "/api/logout", nil)
+	req.AddCookie(&http.Cookie{Name: config.SessionCookie.Name(), Value: session.Token})
+
+	rr := httptest.NewRecorder()
+	srv.AuthGuard(srv.Logout).ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusInternalServerError {
+		t.Errorf("Expected 500, got %d", rr.Code)
+	}
+
+	if got := rr.Header().Get("Set-Cookie"); got != "" {
+		t.Errorf("Expected the cookie kept, got Set-Cookie %q", got)
+	}
+}
+
+// tests auth guard clears an unknown session cookie
+func TestAuthGuardExpiredSetCookieHeader(t *testing.T) {
+	srv, _ := newTestServer(t)
+
+	useSessionCookie(t, cookies.Options{
+		Name:     "session_token",
+		Path:     "/",
+		SameSite: http.SameSiteLaxMode,
+		MaxAge:   86400,
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/api/profile", nil)
+	req.AddCookie(&http.Cookie{Name: "session_token", Value: "does-not-exist"})
+
+	rr := httptest.NewRecorder()
+	srv.AuthGuard(srv.GetProfile).ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusUnauthorized {
+		t.Errorf("Expected status 401, got %d", rr.Code)
+	}
+
+	expected := "session_token=; Path=/; Max-Age=0; HttpOnly; SameSite=Lax"
+	if got := rr.Header().Get("Set-Cookie"); got != expected {
+		t.Errorf("Expected Set-Cookie %q, got %q", expected, got)
+	}
+}
+
+// tests invalid option combinations are rejected
+func TestCookieOptionsValidation(t *testing.T) {
+	cases := []struct {
+		name string
+		opts cookies.Options
+	}{
+		{"host prefix without secure", cookies.Options{Name: "sid", HostPrefix: true}},
+		{"host prefix with domain", cookies.Options{Name: "sid", HostPrefix: true, Secure: true, Domain: "example.com"}},
+		{"host prefix with path", cookies.Options{Name: "sid", HostPrefix: true, Secure: true, Path: "/api"}},
+		{"samesite none without secure", cookies.Options{Name: "sid", SameSite: http.SameSiteNoneMode}},
+		{"bad seal key", cookies.Options{Name: "sid", SealKey: []byte("short")}},
+	}
+
+	for _, tc := range cases {
+		if _, err := cookies.New(tc.opts); err == nil {
+			t.Errorf("%s: expected error, got nil", tc.name)
+		}
+	}
+}
+
+// tests tampered sealed values are rejected
+func TestSealedCookieTampered(t *testing.T) {
+	manager, _ := cookies.New(cookies.Options{Name: "sid", SealKey: bytes.Repeat([]byte{0x01}, 32)})
+
+	rr := httptest.NewRecorder()
+	manager.Set(rr, "secret-token")
+	cookie := rr.Result().Cookies()[0]
+
+	// flip a character in the middle of the ciphertext
+	tampered := []byte(cookie.Value)
+	if tampered[20] == 'A' {
+		tampered[20] = 'B'
+	} else {
+		tampered[20] = 'A'
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.AddCookie(&http.Cookie{Name: "sid", Value: string(tampered)})
+
+	if _, err := manager.Read(req); err != cookies.ErrInvalidCookie {
+		t.Errorf("Expected ErrInvalidCookie, got %v", err)
+	}
+}
+
+// tests the cookie expires together with the session it carries
+func TestSessionCookieLifetime(t *testing.T) {
+	srv, store := newTestServer(t)
+
+	rr := postJSON(srv.Signup, "/api/v1/signup", map[string]string{
+		"email":    "cookie_lifetime@example.com",
+		"password": "violet-Harbor-42",
+	})
+
+	session, _ := store.Sessions.GetByToken(context.Background(), tokenFromResponse(t, rr))
+	if session == nil {
+		t.Fatalf("Expected the session of the cookie to exist")
+	}
+
+	cookie := rr.Result().Cookies()[0]
+	if cookie.MaxAge != int(config.SessionLifetime.Seconds()) {
+		t.Errorf("Expected Max-Age %d, got %d", int(config.SessionLifetime.Seconds()), cookie.MaxAge)
+	}
+
+	if drift := time.Until(session.ExpiresAt) - config.SessionLifetime; drift > time.Minute || drift < -time.Minute {
+		t.Errorf("Expected the session to expire after %s, got %s", config.SessionLifetime, session.ExpiresAt)
+	}
+}
@@ -0,0 +1,152 @@
+package tests
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"user-auth-go/internal/dotenv"
+)
+
+// tests the dotenv syntax, one case per feature
+func TestDotenvParse(t *testing.T) {
+	lookup := func(name string) (string, bool) {
+		if name == "HOME" {
+			return "/home/app", true
+		}
+		return "", false
+	}
+
+	cases := []struct {
+		name    string
+		content string
+		want    map[string]string
+	}{
+		{"plain", "KEY=value", map[string]string{"KEY": "value"}},
+		{"blank lines and comments", "\n# comment\n  # indented comment\nKEY=value\n\n", map[string]string{"KEY": "value"}},
+		{"spaces around equals", "KEY =  value  ", map[string]string{"KEY": "value"}},
+		{"empty value", "KEY=", map[string]string{"KEY": ""}},
+		{"export prefix", "export KEY=value", map[string]string{"KEY": "value"}},
+		{"export as a name", "export=value", map[string]string{"export": "value"}},
+		{"inline comment", "KEY=value # comment", map[string]string{"KEY": "value"}},
+		{"hash without space", "KEY=abc#123", map[string]string{"KEY": "abc#123"}},
+		{"value with equals", "DSN=user:pass@tcp(host)/db?a=b", map[string]string{"DSN": "user:pass@tcp(host)/db?a=b"}},
+		{"single quotes are literal", `KEY='a $HOME \n # b'`, map[string]string{"KEY": `a $HOME \n # b`}},
+		{"double quotes escapes", `KEY="a\tb\nc \"q\" \\ \$HOME"`, map[string]string{"KEY": "a\tb\nc \"q\" \\ $HOME"}},
+		{"double quotes keep hash", `KEY="a # b" # comment`, map[string]string{"KEY": "a # b"}},
+		{"multiline double quotes", "KEY=\"line one\nline two\"\nNEXT=1", map[string]string{"KEY": "line one\nline two", "NEXT": "1"}},
+		{"escaped newline in unquoted", "KEY=first \\\n    second\nNEXT=1", map[string]string{"KEY": "first second", "NEXT": "1"}},
+		{"interpolation from file", "HOST=db\nURL=mysql://${HOST}:3306", map[string]string{"HOST": "db", "URL": "mysql://db:3306"}},
+		{"interpolation from lookup", "DIR=$HOME/data", map[string]string{"DIR": "/home/app/data"}},
+		{"interpolation default", "PORT=${MISSING:-8080}", map[string]string{"PORT": "8080"}},
+		{"unknown reference is empty", "KEY=a${MISSING}b", map[string]string{"KEY": "ab"}},
+		{"escaped dollar", `KEY=\$HOME`, map[string]string{"KEY": "$HOME"}},
+		{"lone dollar", "KEY=5$", map[string]string{"KEY": "5$"}},
+		{"windows line endings", "A=1\r\nB=2\r\n", map[string]string{"A": "1", "B": "2"}},
+		{"later assignment wins", "KEY=one\nKEY=two", map[string]string{"KEY": "two"}},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			vars, err := dotenv.Parse(".env", tc.content, lookup)
+			if err != nil {
+				t.Fatalf("Expected no error, got %s", err)
+			}
+
+			got := map[string]string{}
+			for _, v := range vars {
+				got[v.Key] = v.Value
+			}
+
+			if len(got) != len(tc.want) {
+				t.Errorf("Expected %v, got %v", tc.want, got)
+			}
+			for key, value := range tc.want {
+				if got[key] != value {
+					t.Errorf("Expected %s=%q, got %q", key, value, got[key])
+				}
+			}
+		})
+	}
+}
+
+// tests syntax errors carry the line they were found on
+func TestDotenvSyntaxErrors(t *testing.T) {
+	cases := []struct {
+		name    string
+		content string
+		line    int
+	}{
+		{"missing equals", "A=1\nJUST_A_NAME\n", 2},
+		{"invalid name", "A=1\n\n1ABC=2", 3},
+		{"unterminated double quote", "A=1\nB=\"open\nstill open", 2},
+		{"unterminated single quote", "A='open", 1},
+		{"text after quotes", "A=\"x\" y", 1},
+		{"unterminated reference", "A=1\nB=${HOST", 2},
+		{"invalid reference", "A=${-x}", 1},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := dotenv.Parse(".env", tc.content, nil)
+
+			var syntaxErr *dotenv.SyntaxError
+			if !errors.As(err, &syntaxErr) {
+				t.Fatalf("Expected syntax error, got %v", err)
+			}
+
+			if syntaxErr.Line != tc.line || syntaxErr.File != ".env" {
+				t.Errorf("Expected error at .env:%d, got %s", tc.line, err)
+			}
+		})
+	}
+}
+
+// tests files are layered and the real environment is kept unless overridden
+func TestDotenvLoad(t *testing.T) {
+	dir := t.TempDir()
+
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		os.WriteFile(path, []byte(content), 0o600)
+		return path
+	}
+
+	base := write(".env", "DOTENV_BASE=base\nDOTENV_SHARED=base\nDOTENV_REAL=from-file\nDOTENV_REF=${DOTENV_REAL}\n")
+	local := write(".env.local", "DOTENV_SHARED=local\n")
+
+	t.Setenv("DOTENV_REAL", "from-env")
+	for _, key := range []string{"DOTENV_BASE", "DOTENV_SHARED", "DOTENV_REF"} {
+		t.Setenv(key, "")
+		os.Unsetenv(key)
+	}
+
+	if err := dotenv.Load(false, base, filepath.Join(dir, ".env.missing"), local); err != nil {
+		t.Fatalf("Expected load to succeed, got %s", err)
+	}
+
+	expected := map[string]string{
+		"DOTENV_BASE":   "base",
+		"DOTENV_SHARED": "local",
+		"DOTENV_REAL":   "from-env",
+		"DOTENV_REF":    "from-env",
+	}
+	for key, value := range expected {
+		if got := os.Getenv(key); got != value {
+			t.Errorf("Expected %s=%q, got %q", key, value, got)
+		}
+	}
+
+	if err := dotenv.Load(true, base); err != nil {
+		t.Fatalf("Expected load to succeed, got %s", err)
+	}
+
+	if got := os.Getenv("DOTENV_REAL"); got != "from-file" {
+		t.Errorf("Expected override to replace the real variable, got %q", got)
+	}
+
+	bad := write(".env.bad", "OK=1\nBROKEN\n")
+	if err := dotenv.Load(false, bad); err == nil || err.Error() != bad+":2: expected = after BROKEN" {
+		t.Errorf("Expected line numbered error, got %v", err)
+	}
+}
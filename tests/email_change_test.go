@@ -0,0 +1,145 @@
+package tests
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"sync"
+	"testing"
+	"user-auth-go/internal/config"
+	"user-auth-go/internal/mail"
+)
+
+// mailer capturing every sent message
+type captureMailer struct {
+	mu       sync.Mutex
+	messages []mail.Message
+}
+
+func (c *captureMailer) Send(msg mail.Message) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.messages = append(c.messages, msg)
+	return nil
+}
+
+func useCaptureMailer(t *testing.T) *captureMailer {
+	t.Helper()
+
+	mailer := &captureMailer{}
+	previous := config.Mailer
+	config.Mailer = mailer
+	t.Cleanup(func() { config.Mailer = previous })
+
+	return mailer
+}
+
+var tokenLinkPattern = regexp.MustCompile(`https?://\S+token=(\S+)`)
+
+func linkToken(t *testing.T, body string) string {
+	t.Helper()
+
+	match := tokenLinkPattern.FindStringSubmatch(body)
+	if match == nil {
+		t.Fatalf("Expected link with token in mail body: %s", body)
+	}
+
+	token, _ := url.QueryUnescape(match[1])
+	return token
+}
+
+// tests email change stays pending until the new address confirms
+func TestEmailChangeConfirm(t *testing.T) {
+	srv, store := newTestServer(t)
+
+	mailer := useCaptureMailer(t)
+
+	user := createUser(t, store, "old_address@example.com", "password123")
+
+	session, _ := store.Sessions.Create(context.Background(), user.ID)
+
+	// password is required to request the change
+	rr := putJSONWithSession(srv.AuthGuard(srv.UpdateProfile), "/api/profile", session.Token, map[string]string{
+		"full_name": "Old Address",
+		"email":     "new_address@example.com",
+	})
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 without current password, got %d", rr.Code)
+	}
+
+	rr = putJSONWithSession(srv.AuthGuard(srv.UpdateProfile), "/api/profile", session.Token, map[string]string{
+		"full_name":        "Old Address",
+		"email":            "new_address@example.com",
+		"current_password": "password123",
+	})
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d. Body: %s", rr.Code, rr.Body.String())
+	}
+
+	current, _ := store.Users.GetByID(context.Background(), user.ID)
+	if current.Email != "old_address@example.com" {
+		t.Errorf("Expected email to stay unchanged before confirmation, got %s", current.Email)
+	}
+
+	if len(mailer.messages) != 2 {
+		t.Fatalf("Expected 2 mails, got %d", len(mailer.messages))
+	}
+
+	if mailer.messages[0].To != "new_address@example.com" || mailer.messages[1].To != "old_address@example.com" {
+		t.Errorf("Expected mails to new then old address, got %s and %s", mailer.messages[0].To, mailer.messages[1].To)
+	}
+
+	token := linkToken(t, mailer.messages[0].Body)
+	req := httptest.NewRequest(http.MethodGet, "/api/profile/email/confirm?token="+url.QueryEscape(token), nil)
+	rr = httptest.NewRecorder()
+	srv.ConfirmEmailChange(rr, req)
+
+	if location := rr.Header().Get("Location"); location != "/profile" {
+		t.Errorf("Expected redirect to /profile, got %q", location)
+	}
+
+	current, _ = store.Users.GetByID(context.Background(), user.ID)
+	if current.Email != "new_address@example.com" {
+		t.Errorf("Expected email to change after confirmation, got %s", current.Email)
+	}
+}
+
+// tests cancel link drops the request and signs out every device
+func TestEmailChangeCancel(t *testing.T) {
+	srv, store := newTestServer(t)
+
+	mailer := useCaptureMailer(t)
+
+	user := createUser(t, store, "cancel_old@example.com", "password123")
+
+	session, _ := store.Sessions.Create(context.Background(), user.ID)
+
+	rr := putJSONWithSession(srv.AuthGuard(srv.UpdateProfile), "/api/profile", session.Token, map[string]string{
+		"full_name":        "Cancel Old",
+		"email":            "cancel_new@example.com",
+		"current_password": "password123",
+	})
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d. Body: %s", rr.Code, rr.Body.String())
+	}
+
+	token := linkToken(t, mailer.messages[1].Body)
+	req := httptest.NewRequest(http.MethodGet, "/api/profile/email/cancel?token="+url.QueryEscape(token), nil)
+	rr = httptest.NewRecorder()
+	srv.CancelEmailChange(rr, req)
+
+	if change, _ := store.EmailChanges.GetPending(context.Background(), user.ID); change != nil {
+		t.Errorf("Expected pending change to be removed")
+	}
+
+	if s, _ := store.Sessions.GetByToken(context.Background(), session.Token); s != nil {
+		t.Errorf("Expected sessions to be revoked after cancel")
+	}
+
+	current, _ := store.Users.GetByID(context.Background(), user.ID)
+	if current.Email != "cancel_old@example.com" {
+		t.Errorf("Expected email to stay unchanged, got %s", current.Email)
+	}
+}
@@ -0,0 +1,75 @@
+package tests
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"user-auth-go/constants"
+	"user-auth-go/internal/api"
+)
+
+// tests errors are RFC 7807 problem details only for clients that prefer them
+func TestProblemDetails(t *testing.T) {
+	srv, _ := newTestServer(t)
+
+	tests := []struct {
+		accept      string
+		contentType string
+	}{
+		{"", "application/json"},
+		{"application/json", "application/json"},
+		{"application/problem+json", "application/problem+json"},
+		{"application/json;q=0.5, application/problem+json", "application/problem+json"},
+		{"application/problem+json;q=0.5, application/json", "application/json"},
+	}
+
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/profile", nil)
+		if tt.accept != "" {
+			req.Header.Set("Accept", tt.accept)
+		}
+
+		rr := httptest.NewRecorder()
+		srv.AuthGuard(srv.GetProfile)(rr, req)
+
+		if rr.Header().Get("Content-Type") != tt.contentType {
+			t.Errorf("Accept %q: expected %s, got %s", tt.accept, tt.contentType, rr.Header().Get("Content-Type"))
+			continue
+		}
+
+		if tt.contentType == "application/json" {
+			var response api.APIResponse
+			json.Unmarshal(rr.Body.Bytes(), &response)
+			if response.Error == nil || response.Error.Code != constants.ErrorUnauthorized {
+				t.Errorf("Accept %q: expected error code %s, got %+v", tt.accept, constants.ErrorUnauthorized, response.Error)
+			}
+			continue
+		}
+
+		var problem api.ProblemDetails
+		json.Unmarshal(rr.Body.Bytes(), &problem)
+		if problem.Status != http.StatusUnauthorized || problem.Code != constants.ErrorUnauthorized || problem.Instance != "/api/v1/profile" {
+			t.Errorf("Accept %q: unexpected problem %+v", tt.accept, problem)
+		}
+	}
+}
+
+// tests browser redirects carry an error code instead of text
+func TestErrorRedirectCodes(t *testing.T) {
+	srv, _ := newTestServer(t)
+
+	rr := httptest.NewRecorder()
+	srv.GoogleLogin(rr, httptest.NewRequest(http.MethodGet, "/api/v1/auth/google", nil))
+
+	if location := rr.Header().Get("Location"); location != "/login?error="+constants.ErrorGoogleDisabled {
+		t.Errorf("Expected redirect with %s, got %q", constants.ErrorGoogleDisabled, location)
+	}
+
+	rr = httptest.NewRecorder()
+	srv.ConfirmEmailChange(rr, httptest.NewRequest(http.MethodGet, "/api/v1/profile/email/confirm?token=unknown", nil))
+
+	if location := rr.Header().Get("Location"); location != "/login?error="+constants.ErrorLinkInvalid {
+		t.Errorf("Expected redirect with %s, got %q", constants.ErrorLinkInvalid, location)
+	}
+}
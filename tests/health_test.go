@@ -0,0 +1,101 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"user-auth-go/internal/health"
+	"user-auth-go/internal/storage/sqlstore"
+)
+
+func probe(t *testing.T, checker *health.Checker, method, path string) (*httptest.ResponseRecorder, health.Report) {
+	t.Helper()
+
+	mux := http.NewServeMux()
+	checker.Routes(mux)
+
+	rr := httptest.NewRecorder()
+	mux.ServeHTTP(rr, httptest.NewRequest(method, path, nil))
+
+	var report health.Report
+	json.Unmarshal(rr.Body.Bytes(), &report)
+
+	return rr, report
+}
+
+// tests liveness answers without touching the database
+func TestLiveness(t *testing.T) {
+	db := openSQLite(t, filepath.Join(t.TempDir(), "health.db"))
+	db.Close()
+
+	checker := health.New(db, nil)
+
+	rr, report := probe(t, checker, http.MethodGet, "/healthz")
+	if rr.Code != http.StatusOK || report.Status != health.StatusOK {
+		t.Errorf("Expected 200 ok, got %d %q", rr.Code, report.Status)
+	}
+
+	if rr, _ := probe(t, checker, http.MethodPost, "/healthz"); rr.Code != http.StatusMethodNotAllowed {
+		t.Errorf("Expected 405 for POST, got %d", rr.Code)
+	}
+}
+
+// tests readiness reports each component and fails on pending migrations, a lost database and drain
+func TestReadiness(t *testing.T) {
+	db := openSQLite(t, filepath.Join(t.TempDir(), "health.db"))
+
+	migrator, err := sqlstore.NewMigrator(db, sqlstore.SQLite)
+	if err != nil {
+		t.Fatalf("Failed to load migrations: %s", err)
+	}
+	checker := health.New(db, migrator)
+
+	rr, report := probe(t, checker, http.MethodGet, "/readyz")
+	if rr.Code != http.StatusServiceUnavailable || report.Components["migrations"].Status != health.StatusFail {
+		t.Errorf("Expected 503 with failing migrations before migrating, got %d %+v", rr.Code, report.Components)
+	}
+
+	if _, err := migrator.Up(context.Background()); err != nil {
+		t.Fatalf("Failed to migrate: %s", err)
+	}
+
+	rr, report = probe(t, checker, http.MethodGet, "/readyz")
+	if rr.Code != http.StatusOK || report.Status != health.StatusOK {
+		t.Fatalf("Expected 200 ok once migrated, got %d %s", rr.Code, rr.Body.String())
+	}
+
+	if migrations := report.Components["migrations"]; migrations.Pending == nil || *migrations.Pending != 0 {
+		t.Errorf("Expected 0 pending migrations, got %+v", migrations)
+	}
+
+	if report.Components["database"].Status != health.StatusOK {
+		t.Errorf("Expected database ok, got %+v", report.Components["database"])
+	}
+
+	// Google login is disabled for the test suite
+	if report.Components["oauth"].Status != health.StatusDisabled {
+		t.Errorf("Expected oauth disabled, got %+v", report.Components["oauth"])
+	}
+
+	checker.Drain()
+
+	rr, report = probe(t, checker, http.MethodGet, "/readyz")
+	if rr.Code != http.StatusServiceUnavailable || report.Status != "draining" {
+		t.Errorf("Expected 503 draining, got %d %q", rr.Code, report.Status)
+	}
+
+	// liveness stays up while draining
+	if rr, _ := probe(t, checker, http.MethodGet, "/healthz"); rr.Code != http.StatusOK {
+		t.Errorf("Expected liveness to pass while draining, got %d", rr.Code)
+	}
+
+	db.Close()
+
+	_, report = probe(t, health.New(db, migrator), http.MethodGet, "/readyz")
+	if report.Status != "unavailable" || report.Components["database"].Status != health.StatusFail || report.Components["database"].Error != "unavailable" {
+		t.Errorf("Expected the database check to fail once closed, got %+v", report)
+	}
+}
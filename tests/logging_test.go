@@ -0,0 +1,246 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"user-auth-go/internal/api"
+	"user-auth-go/internal/logging"
+	"user-auth-go/internal/mail"
+	"user-auth-go/internal/middleware"
+	"user-auth-go/internal/models"
+)
+
+// handle send slog's default logger to a buffer for the test, restored on cleanup
+func captureLogs(t *testing.T) *bytes.Buffer {
+	t.Helper()
+
+	var buf bytes.Buffer
+	logger, err := logging.New(&buf, "json", "debug")
+	if err != nil {
+		t.Fatalf("Failed to create logger: %s", err)
+	}
+
+	previous := slog.Default()
+	slog.SetDefault(logger)
+	t.Cleanup(func() { slog.SetDefault(previous) })
+
+	return &buf
+}
+
+// handle decode json log lines
+func logLines(t *testing.T, buf *bytes.Buffer) []map[string]interface{} {
+	t.Helper()
+
+	var lines []map[string]interface{}
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		if line == "" {
+			continue
+		}
+
+		entry := map[string]interface{}{}
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			t.Fatalf("Invalid log line %q: %s", line, err)
+		}
+		lines = append(lines, entry)
+	}
+	return lines
+}
+
+// tests emails are masked and secrets dropped whatever the call site logs
+func TestLogRedaction(t *testing.T) {
+	buf := captureLogs(t)
+
+	slog.Info("Login failed",
+		"email", "jane@example.com",
+		"session_token", "abc123",
+		"password", "hunter2",
+		"error", errors.New("duplicate key for jane@example.com"),
+		"user_id", 7,
+	)
+
+	output := buf.String()
+	for _, leaked := range []string{"jane@example.com", "abc123", "hunter2"} {
+		if strings.Contains(output, leaked) {
+			t.Errorf("Expected %q to be redacted, got %s", leaked, output)
+		}
+	}
+
+	entry := logLines(t, buf)[0]
+	if entry["email"] != "j***@example.com" {
+		t.Errorf("Expected masked email, got %v", entry["email"])
+	}
+
+	if entry["error"] != "duplicate key for j***@example.com" {
+		t.Errorf("Expected email masked inside error, got %v", entry["error"])
+	}
+
+	if entry["user_id"] != float64(7) {
+		t.Errorf("Expected user_id to be kept, got %v", entry["user_id"])
+	}
+
+	if _, err := logging.New(buf, "xml", "info"); err == nil {
+		t.Errorf("Expected unknown format to be rejected")
+	}
+}
+
+// tests the log mailer keeps links readable but never their tokens
+func TestLogMailerRedactsLinkTokens(t *testing.T) {
+	buf := captureLogs(t)
+
+	(&mail.LogMailer{}).Send(mail.Message{
+		To:      "jane@example.com",
+		Subject: "Confirm your new email address",
+		Body:    "Open http://localhost:8080/api/v1/profile/email/confirm?token=abc123&lang=en to confirm",
+	})
+
+	body, _ := logLines(t, buf)[0]["body"].(string)
+	if strings.Contains(body, "abc123") {
+		t.Errorf("Expected the token to be redacted, got %s", body)
+	}
+	if !strings.Contains(body, "/api/v1/profile/email/confirm?token="+logging.Redacted+"&lang=en") {
+		t.Errorf("Expected the rest of the link to be kept, got %s", body)
+	}
+}
+
+// tests X-Request-ID is reused when sane and generated otherwise
+func TestRequestID(t *testing.T) {
+	tests := []struct {
+		name     string
+		incoming string
+		reuse    bool
+	}{
+		{"missing", "", false},
+		{"valid", "req-123_abc.def:1", true},
+		{"invalid characters", "bad id\nwith newline", false},
+		{"too long", strings.Repeat("a", 129), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var seen string
+			handler := middleware.RequestID()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				seen = logging.RequestID(r.Context())
+			}))
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.incoming != "" {
+				req.Header.Set("X-Request-ID", tt.incoming)
+			}
+
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			id := rr.Header().Get("X-Request-ID")
+			if id == "" || id != seen {
+				t.Fatalf("Expected response header to match context id, got %q and %q", id, seen)
+			}
+
+			if (id == tt.incoming) != tt.reuse {
+				t.Errorf("Expected reuse %v, got id %q", tt.reuse, id)
+			}
+		})
+	}
+}
+
+// tests one access log line per request with route, status and user
+func TestAccessLog(t *testing.T) {
+	srv, store := newTestServer(t)
+	user := createUser(t, store, "access@example.com", "password123")
+	session, _ := store.Sessions.Create(context.Background(), user.ID)
+
+	var buf bytes.Buffer
+	logger, _ := logging.New(&buf, "json", "info")
+
+	mux := http.NewServeMux()
+	srv.Routes(mux)
+	handler := middleware.Chain(middleware.Route(mux), middleware.RequestID(), middleware.AccessLog(logger))
+
+	rr := getWithSession(handler.ServeHTTP, "/api/profile", session.Token)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d", rr.Code)
+	}
+
+	lines := logLines(t, &buf)
+	if len(lines) != 1 {
+		t.Fatalf("Expected one access log line, got %d", len(lines))
+	}
+
+	entry := lines[0]
+	expected := map[string]interface{}{
+		"msg":        "request",
+		"method":     "GET",
+		"route":      "/api/profile",
+		"status":     float64(200),
+		"user_id":    float64(user.ID),
+		"request_id": rr.Header().Get("X-Request-ID"),
+	}
+
+	for key, value := range expected {
+		if entry[key] != value {
+			t.Errorf("Expected %s=%v, got %v", key, value, entry[key])
+		}
+	}
+
+	if _, ok := entry["duration_ms"]; !ok {
+		t.Errorf("Expected duration_ms in access log")
+	}
+
+	if strings.Contains(buf.String(), session.Token) {
+		t.Errorf("Expected session token to stay out of the access log")
+	}
+}
+
+type failingUsers struct {
+	models.UserRepository
+}
+
+func (failingUsers) GetByIdentifier(ctx context.Context, email, googleID string) (*models.User, error) {
+	return nil, errors.New("connection reset while looking up " + email)
+}
+
+// tests handlers log the cause of a 500 with the request id, clients only see the message
+func TestHandlerLogsErrorCause(t *testing.T) {
+	_, store := newTestServer(t)
+	store.Users = failingUsers{store.Users}
+	srv := api.NewServer(store)
+	buf := captureLogs(t)
+
+	handler := middleware.RequestID()(http.HandlerFunc(srv.Login))
+	rr := postJSON(handler.ServeHTTP, "/api/login", map[string]string{
+		"email":    "cause@example.com",
+		"password": "password123",
+	})
+
+	if rr.Code != http.StatusInternalServerError {
+		t.Fatalf("Expected 500, got %d", rr.Code)
+	}
+
+	if strings.Contains(rr.Body.String(), "connection reset") {
+		t.Errorf("Expected cause to stay out of the response, got %s", rr.Body.String())
+	}
+
+	lines := logLines(t, buf)
+	if len(lines) != 1 {
+		t.Fatalf("Expected one log line, got %d", len(lines))
+	}
+
+	entry := lines[0]
+	if entry["msg"] != "Failed to get user" || entry["level"] != "ERROR" {
+		t.Errorf("Expected error log for the failure, got %v", entry)
+	}
+
+	if entry["error"] != "connection reset while looking up c***@example.com" {
+		t.Errorf("Expected masked cause, got %v", entry["error"])
+	}
+
+	if entry["request_id"] != rr.Header().Get("X-Request-ID") {
+		t.Errorf("Expected request id %q, got %v", rr.Header().Get("X-Request-ID"), entry["request_id"])
+	}
+}
@@ -0,0 +1,170 @@
+package tests
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"user-auth-go/internal/config"
+	"user-auth-go/internal/metrics"
+	"user-auth-go/internal/middleware"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	dto "github.com/prometheus/client_model/go"
+)
+
+func scrape(handler http.Handler, authorization string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+	if authorization != "" {
+		req.Header.Set("Authorization", authorization)
+	}
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	return rr
+}
+
+func histogramCount(observer prometheus.Observer) uint64 {
+	var metric dto.Metric
+	observer.(prometheus.Metric).Write(&metric)
+	return metric.GetHistogram().GetSampleCount()
+}
+
+// tests the handler serves counters, histograms and gauges in the text format
+func TestMetricsTextFormat(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	counter := prometheus.NewCounterVec(prometheus.CounterOpts{Name: "test_events_total", Help: "Events."}, []string{"kind"})
+	histogram := prometheus.NewHistogramVec(prometheus.HistogramOpts{Name: "test_duration_seconds", Help: "Durations.", Buckets: []float64{0.1, 1}}, []string{"op"})
+	gauge := prometheus.NewGaugeFunc(prometheus.GaugeOpts{Name: "test_queue_size", Help: "Queue size."}, func() float64 { return 3 })
+	reg.MustRegister(counter, histogram, gauge)
+
+	counter.WithLabelValues(`quote"and\backslash`).Inc()
+	counter.WithLabelValues("plain").Add(2)
+	histogram.WithLabelValues("read").Observe(0.05)
+	histogram.WithLabelValues("read").Observe(0.5)
+
+	expected := `# HELP test_duration_seconds Durations.
+# TYPE test_duration_seconds histogram
+test_duration_seconds_bucket{op="read",le="0.1"} 1
+test_duration_seconds_bucket{op="read",le="1"} 2
+test_duration_seconds_bucket{op="read",le="+Inf"} 2
+test_duration_seconds_sum{op="read"} 0.55
+test_duration_seconds_count{op="read"} 2
+# HELP test_events_total Events.
+# TYPE test_events_total counter
+test_events_total{kind="plain"} 2
+test_events_total{kind="quote\"and\\backslash"} 1
+# HELP test_queue_size Queue size.
+# TYPE test_queue_size gauge
+test_queue_size 3
+`
+
+	rr := scrape(metrics.Handler(reg, ""), "")
+	if rr.Body.String() != expected {
+		t.Errorf("Unexpected metrics output:\n%s", rr.Body.String())
+	}
+
+	if !strings.HasPrefix(rr.Header().Get("Content-Type"), "text/plain; version=0.0.4") {
+		t.Errorf("Expected Prometheus content type, got %q", rr.Header().Get("Content-Type"))
+	}
+}
+
+// tests a token protected endpoint only answers scrapers sending the bearer token
+func TestMetricsToken(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	reg.MustRegister(prometheus.NewGaugeFunc(prometheus.GaugeOpts{Name: "test_up", Help: "Up."}, func() float64 { return 1 }))
+	handler := metrics.Handler(reg, "scrape-secret")
+
+	for _, authorization := range []string{"", "Bearer wrong", "scrape-secret"} {
+		if rr := scrape(handler, authorization); rr.Code != http.StatusUnauthorized {
+			t.Errorf("Expected 401 for %q, got %d", authorization, rr.Code)
+		}
+	}
+
+	rr := scrape(handler, "Bearer scrape-secret")
+	if rr.Code != http.StatusOK || !strings.Contains(rr.Body.String(), "test_up 1") {
+		t.Errorf("Expected metrics with the token, got %d", rr.Code)
+	}
+}
+
+// tests requests are counted per route pattern and status
+func TestHTTPMetrics(t *testing.T) {
+	srv, _ := newTestServer(t)
+
+	mux := http.NewServeMux()
+	srv.Routes(mux)
+	handler := middleware.Chain(middleware.Route(mux), middleware.Metrics())
+
+	before := testutil.ToFloat64(metrics.HTTPRequests.WithLabelValues("GET", "/api/profile", "401"))
+	beforeLatency := histogramCount(metrics.HTTPDuration.WithLabelValues("GET", "/api/profile", "401"))
+	beforeUnmatched := testutil.ToFloat64(metrics.HTTPRequests.WithLabelValues("GET", "unmatched", "404"))
+
+	getWithSession(handler.ServeHTTP, "/api/profile", "no-such-session")
+	getWithSession(handler.ServeHTTP, "/no/such/page", "")
+
+	if got := testutil.ToFloat64(metrics.HTTPRequests.WithLabelValues("GET", "/api/profile", "401")) - before; got != 1 {
+		t.Errorf("Expected one counted request, got %v", got)
+	}
+
+	if got := histogramCount(metrics.HTTPDuration.WithLabelValues("GET", "/api/profile", "401")) - beforeLatency; got != 1 {
+		t.Errorf("Expected one latency observation, got %v", got)
+	}
+
+	if got := testutil.ToFloat64(metrics.HTTPRequests.WithLabelValues("GET", "unmatched", "404")) - beforeUnmatched; got != 1 {
+		t.Errorf("Expected unknown path counted as unmatched, got %v", got)
+	}
+}
+
+// tests login outcomes are counted by provider and reason
+func TestAuthMetrics(t *testing.T) {
+	srv, store := newTestServer(t)
+	createUser(t, store, "metrics@example.com", "password123")
+
+	success := testutil.ToFloat64(metrics.AuthEvents.WithLabelValues("login", "success", "local", ""))
+	wrongPassword := testutil.ToFloat64(metrics.AuthEvents.WithLabelValues("login", "failure", "local", "wrong_password"))
+	verifications := histogramCount(metrics.PasswordHashDuration.WithLabelValues(config.Current.Password.HashAlgorithm, "verify"))
+
+	postJSON(srv.Login, "/api/login", map[string]string{"email": "metrics@example.com", "password": "password123"})
+	postJSON(srv.Login, "/api/login", map[string]string{"email": "metrics@example.com", "password": "wrong"})
+
+	if got := testutil.ToFloat64(metrics.AuthEvents.WithLabelValues("login", "success", "local", "")) - success; got != 1 {
+		t.Errorf("Expected one successful login, got %v", got)
+	}
+
+	if got := testutil.ToFloat64(metrics.AuthEvents.WithLabelValues("login", "failure", "local", "wrong_password")) - wrongPassword; got != 1 {
+		t.Errorf("Expected one wrong password, got %v", got)
+	}
+
+	if got := histogramCount(metrics.PasswordHashDuration.WithLabelValues(config.Current.Password.HashAlgorithm, "verify")) - verifications; got != 2 {
+		t.Errorf("Expected two timed verifications, got %v", got)
+	}
+}
+
+// tests the session gauge and pool stats are read on scrape
+func TestSessionAndDBMetrics(t *testing.T) {
+	store := newTestStore(t)
+	user := createUser(t, store, "gauge@example.com", "password123")
+	store.Sessions.Create(context.Background(), user.ID)
+	store.Sessions.Create(context.Background(), user.ID)
+
+	db, err := config.OpenDB("sqlite", config.SQLiteDSN(filepath.Join(t.TempDir(), "stats.db")))
+	if err != nil {
+		t.Fatalf("Failed to open database: %s", err)
+	}
+	defer db.Close()
+
+	reg := prometheus.NewRegistry()
+	metrics.RegisterActiveSessions(reg, store.Sessions.CountActive)
+	metrics.RegisterDBStats(reg, db)
+
+	body := scrape(metrics.Handler(reg, ""), "").Body.String()
+
+	for _, line := range []string{"auth_active_sessions 2\n", "db_max_open_connections 1\n", "# TYPE db_wait_count_total counter\n"} {
+		if !strings.Contains(body, line) {
+			t.Errorf("Expected %q in metrics:\n%s", line, body)
+		}
+	}
+}
@@ -0,0 +1,200 @@
+package tests
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"user-auth-go/constants"
+	"user-auth-go/internal/config"
+	"user-auth-go/internal/storage/sqlstore"
+)
+
+func openSQLite(t *testing.T, path string) *sql.DB {
+	t.Helper()
+
+	db, err := config.OpenDB("sqlite", config.SQLiteDSN(path))
+	if err != nil {
+		t.Fatalf("Failed to open sqlite: %s", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	return db
+}
+
+func tableExists(t *testing.T, db *sql.DB, table string) bool {
+	t.Helper()
+
+	var count int
+	db.QueryRow("SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = ?", table).Scan(&count)
+	return count == 1
+}
+
+// tests migrations apply once, report their status and revert
+func TestMigrateUpDownStatus(t *testing.T) {
+	ctx := context.Background()
+	db := openSQLite(t, filepath.Join(t.TempDir(), "migrate.db"))
+
+	migrator, err := sqlstore.NewMigrator(db, sqlstore.SQLite)
+	if err != nil {
+		t.Fatalf("Failed to load migrations: %s", err)
+	}
+
+	statuses, _ := migrator.Status(ctx)
+	if len(statuses) == 0 || statuses[0].AppliedAt != nil {
+		t.Fatalf("Expected pending migrations before up, got %+v", statuses)
+	}
+
+	applied, err := migrator.Up(ctx)
+	if err != nil || len(applied) != len(statuses) {
+		t.Fatalf("Expected %d migrations applied, got %d (%v)", len(statuses), len(applied), err)
+	}
+
+	if !tableExists(t, db, "users") || !tableExists(t, db, "outbox") {
+		t.Errorf("Expected tables to exist after up")
+	}
+
+	// nothing left to do the second time
+	if applied, err := migrator.Up(ctx); err != nil || len(applied) != 0 {
+		t.Errorf("Expected no migrations on second up, got %d (%v)", len(applied), err)
+	}
+
+	statuses, _ = migrator.Status(ctx)
+	for _, status := range statuses {
+		if status.AppliedAt == nil {
+			t.Errorf("Expected migration %d to be applied", status.Version)
+		}
+	}
+
+	reverted, err := migrator.Down(ctx, len(statuses))
+	if err != nil || len(reverted) != len(statuses) {
+		t.Fatalf("Expected %d migrations reverted, got %d (%v)", len(statuses), len(reverted), err)
+	}
+
+	if tableExists(t, db, "users") {
+		t.Errorf("Expected users table to be dropped after down")
+	}
+
+	if !tableExists(t, db, "schema_migrations") {
+		t.Errorf("Expected schema_migrations to be kept")
+	}
+}
+
+// tests concurrent instances wait for the lock instead of migrating twice
+func TestMigrateConcurrentInstances(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "concurrent.db")
+
+	var wg sync.WaitGroup
+	results := make([]int, 4)
+	errs := make([]error, 4)
+
+	for i := range results {
+		migrator, err := sqlstore.NewMigrator(openSQLite(t, path), sqlstore.SQLite)
+		if err != nil {
+			t.Fatalf("Failed to load migrations: %s", err)
+		}
+
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			applied, err := migrator.Up(context.Background())
+			results[i], errs[i] = len(applied), err
+		}(i)
+	}
+
+	wg.Wait()
+
+	total := 0
+	for i, n := range results {
+		if errs[i] != nil {
+			t.Errorf("Instance %d failed: %s", i, errs[i])
+		}
+		total += n
+	}
+
+	migrator, _ := sqlstore.NewMigrator(openSQLite(t, path), sqlstore.SQLite)
+	statuses, _ := migrator.Status(context.Background())
+	if total != len(statuses) {
+		t.Errorf("Expected every migration applied exactly once, got %d applications for %d migrations", total, len(statuses))
+	}
+}
+
+// users and sessions as the ddl.sql of the first release created them, before migrations
+var baselineDDL = []string{
+	`CREATE TABLE users (
+		id INT AUTO_INCREMENT PRIMARY KEY,
+		email VARCHAR(255) UNIQUE NOT NULL,
+		password VARCHAR(255),
+		full_name VARCHAR(255),
+		telephone VARCHAR(50),
+		auth_provider ENUM('local', 'google') DEFAULT 'local',
+		google_id VARCHAR(255),
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP
+	)`,
+	`CREATE TABLE sessions (
+		id INT AUTO_INCREMENT PRIMARY KEY,
+		user_id INT NOT NULL,
+		token VARCHAR(255) UNIQUE NOT NULL,
+		expires_at TIMESTAMP NOT NULL,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+	)`,
+}
+
+// tests a MySQL database created from the baseline ddl.sql migrates and keeps its users,
+// needs TEST_DB_DRIVER=mysql and a TEST_DB_DSN whose tables may be dropped
+func TestMigrateBaselineDatabase(t *testing.T) {
+	if os.Getenv("TEST_DB_DRIVER") != "mysql" {
+		t.Skip("needs TEST_DB_DRIVER=mysql")
+	}
+
+	ctx := context.Background()
+
+	db, err := config.OpenDB("mysql", os.Getenv("TEST_DB_DSN"))
+	if err != nil {
+		t.Fatalf("Failed to open database: %s", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	tables := []string{"webhook_delivery_attempts", "webhook_deliveries", "webhook_subscriptions", "outbox", "audit_events", "email_changes", "sessions", "users", "schema_migrations"}
+	for _, table := range tables {
+		if _, err := db.Exec("DROP TABLE IF EXISTS " + table); err != nil {
+			t.Fatalf("Failed to drop %s: %s", table, err)
+		}
+	}
+
+	for _, statement := range baselineDDL {
+		if _, err := db.Exec(statement); err != nil {
+			t.Fatalf("Failed to create baseline schema: %s", err)
+		}
+	}
+
+	if _, err := db.Exec("INSERT INTO users (email, password) VALUES ('baseline@example.com', 'hash')"); err != nil {
+		t.Fatalf("Failed to insert baseline user: %s", err)
+	}
+
+	migrator, err := sqlstore.NewMigrator(db, sqlstore.MySQL)
+	if err != nil {
+		t.Fatalf("Failed to load migrations: %s", err)
+	}
+	if _, err := migrator.Up(ctx); err != nil {
+		t.Fatalf("Failed to migrate baseline database: %s", err)
+	}
+
+	store := sqlstore.New(db, sqlstore.MySQL)
+
+	user, err := store.Users.GetByIdentifier(ctx, "baseline@example.com", "")
+	if err != nil || user == nil {
+		t.Fatalf("Expected the baseline user after migrating, got %v (%v)", user, err)
+	}
+	if user.Role != constants.RoleUser {
+		t.Errorf("Expected role %s, got %q", constants.RoleUser, user.Role)
+	}
+
+	if err := store.Users.UpdateRole(ctx, user.ID, constants.RoleAdmin); err != nil {
+		t.Errorf("Failed to update role: %s", err)
+	}
+}
@@ -0,0 +1,270 @@
+package tests
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+	"user-auth-go/constants"
+	"user-auth-go/internal/models"
+	"user-auth-go/internal/outbox"
+	"user-auth-go/internal/webhooks"
+)
+
+// sink failing the first n publishes
+type flakySink struct {
+	failures int
+	events   []models.OutboxEvent
+}
+
+func (s *flakySink) Name() string { return "flaky" }
+
+func (s *flakySink) Publish(ctx context.Context, event models.OutboxEvent) error {
+	if s.failures > 0 {
+		s.failures--
+		return errors.New("sink unavailable")
+	}
+	s.events = append(s.events, event)
+	return nil
+}
+
+func eventTypes(events []models.OutboxEvent) []string {
+	var types []string
+	for _, event := range events {
+		types = append(types, event.EventType)
+	}
+	return types
+}
+
+// tests user changes write their event in the same transaction
+func TestOutboxWrittenWithChange(t *testing.T) {
+	_, store := newTestServer(t)
+	ctx := context.Background()
+
+	user := createUser(t, store, "outbox_write@example.com", "password123")
+	store.Users.UpdateProfile(ctx, user.ID, "Outbox User", "0800", user.Email)
+
+	// a rejected change must not leave an event behind
+	other := createUser(t, store, "outbox_taken@example.com", "password123")
+
+	if err := store.Users.UpdateProfile(ctx, user.ID, "Outbox User", "0800", other.Email); !errors.Is(err, models.ErrEmailExists) {
+		t.Fatalf("Expected ErrEmailExists, got %v", err)
+	}
+
+	events, err := store.Outbox.ListForAggregate(ctx, user.ID)
+	if err != nil {
+		t.Fatalf("Failed to list outbox: %s", err)
+	}
+
+	expected := []string{constants.WebhookUserSignedUp, constants.WebhookUserUpdated}
+	if got := eventTypes(events); strings.Join(got, ",") != strings.Join(expected, ",") {
+		t.Fatalf("Expected events %v, got %v", expected, got)
+	}
+
+	var envelope webhooks.Event
+	if err := json.Unmarshal([]byte(events[1].Payload), &envelope); err != nil {
+		t.Fatalf("Failed to decode payload: %s", err)
+	}
+
+	data, _ := envelope.Data.(map[string]interface{})
+	if envelope.ID != events[1].EventID || data["full_name"] != "Outbox User" {
+		t.Errorf("Expected envelope with updated user, got %+v", envelope)
+	}
+}
+
+// tests failed events are retried and later events of the same user wait for them
+func TestOutboxDispatchOrderAndRetry(t *testing.T) {
+	_, store := newTestServer(t)
+	ctx := context.Background()
+
+	user := createUser(t, store, "outbox_order@example.com", "password123")
+	store.Users.UpdateProfile(ctx, user.ID, "First", "", user.Email)
+	store.Users.UpdateProfile(ctx, user.ID, "Second", "", user.Email)
+
+	sink := &flakySink{failures: 1}
+	dispatcher := outbox.NewDispatcher(store.Outbox, sink)
+	dispatcher.BaseBackoff = time.Second
+	dispatcher.MaxBackoff = time.Second
+
+	dispatcher.DispatchPending(ctx)
+
+	events, _ := store.Outbox.ListForAggregate(ctx, user.ID)
+	if events[0].Status != constants.OutboxPending || events[0].Attempts != 1 || events[0].LastError == "" {
+		t.Errorf("Expected first event pending after a failure, got %s attempts=%d", events[0].Status, events[0].Attempts)
+	}
+
+	// the first event is backing off, nothing behind it may go out
+	dispatcher.DispatchPending(ctx)
+	if len(sink.events) != 0 {
+		t.Fatalf("Expected later events to wait, got %v", eventTypes(sink.events))
+	}
+
+	// wait out the backoff, MySQL keeps whole seconds so allow some slack
+	deadline := time.Now().Add(3 * time.Second)
+	for len(sink.events) < 3 && time.Now().Before(deadline) {
+		dispatcher.DispatchPending(ctx)
+		time.Sleep(100 * time.Millisecond)
+	}
+
+	expected := []string{constants.WebhookUserSignedUp, constants.WebhookUserUpdated, constants.WebhookUserUpdated}
+	if got := eventTypes(sink.events); strings.Join(got, ",") != strings.Join(expected, ",") {
+		t.Fatalf("Expected events in order %v, got %v", expected, got)
+	}
+
+	events, _ = store.Outbox.ListForAggregate(ctx, user.ID)
+	for _, event := range events {
+		if event.Status != constants.OutboxSent || event.SentAt == nil {
+			t.Errorf("Expected event %s to be sent, got %s", event.EventID, event.Status)
+		}
+	}
+}
+
+// tests channel sink hands events to in-process consumers
+func TestOutboxChannelSink(t *testing.T) {
+	sink := outbox.NewChannelSink(1)
+	event := models.OutboxEvent{EventID: "evt_channel", EventType: constants.WebhookUserSignedUp}
+
+	if err := sink.Publish(context.Background(), event); err != nil {
+		t.Fatalf("Expected publish to succeed, got %v", err)
+	}
+
+	if got := <-sink.C; got.EventID != "evt_channel" {
+		t.Errorf("Expected evt_channel, got %s", got.EventID)
+	}
+
+	// nobody is reading and the buffer is full, publish gives up with the context
+	sink.Publish(context.Background(), event)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if err := sink.Publish(ctx, event); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context.Canceled, got %v", err)
+	}
+}
+
+// fake NATS server accepting one connection and recording published messages
+func fakeNATSServer(t *testing.T, reply func(subject string) string) (string, <-chan string) {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %s", err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	messages := make(chan string, 10)
+
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		reader := bufio.NewReader(conn)
+		conn.Write([]byte("INFO {\"server_id\":\"test\",\"max_payload\":1048576}\r\n"))
+
+		subject := ""
+		for {
+			line, err := reader.ReadString('\n')
+			if err != nil {
+				return
+			}
+			line = strings.TrimRight(line, "\r\n")
+
+			switch {
+			case strings.HasPrefix(line, "CONNECT "):
+				messages <- line
+			case strings.HasPrefix(line, "PUB "):
+				parts := strings.Fields(line)
+				size, _ := strconv.Atoi(parts[2])
+				payload := make([]byte, size+2)
+				io.ReadFull(reader, payload)
+
+				subject = parts[1]
+				messages <- subject + " " + string(payload[:size])
+			case line == "PING":
+				conn.Write([]byte(reply(subject)))
+			}
+		}
+	}()
+
+	return listener.Addr().String(), messages
+}
+
+// tests NATS sink sends URL credentials and waits for the server to accept every publish
+func TestOutboxNATSSink(t *testing.T) {
+	addr, messages := fakeNATSServer(t, func(subject string) string {
+		if strings.HasSuffix(subject, "user.deleted") {
+			return "-ERR 'Permissions Violation for Publish to \"" + subject + "\"'\r\nPONG\r\n"
+		}
+		return "PONG\r\n"
+	})
+
+	sink := outbox.NewNATSSink("nats://alice:secret@"+addr, "user-auth")
+
+	event := models.OutboxEvent{EventID: "evt_nats", EventType: constants.WebhookUserSignedUp, Payload: `{"id":"evt_nats"}`}
+
+	if err := sink.Publish(context.Background(), event); err != nil {
+		t.Fatalf("Expected publish to succeed, got %v", err)
+	}
+
+	connect := <-messages
+	if !strings.Contains(connect, `"user":"alice"`) || !strings.Contains(connect, `"pass":"secret"`) {
+		t.Errorf("Expected credentials in CONNECT, got %s", connect)
+	}
+
+	if msg := <-messages; msg != `user-auth.user.signed_up {"id":"evt_nats"}` {
+		t.Errorf("Unexpected message %q", msg)
+	}
+
+	event.EventType = constants.WebhookUserDeleted
+	if err := sink.Publish(context.Background(), event); err == nil || !strings.Contains(err.Error(), "Permissions Violation") {
+		t.Errorf("Expected server error to fail the publish, got %v", err)
+	}
+
+	// the earlier rejection must not fail the next accepted publish
+	event.EventType = constants.WebhookUserUpdated
+	if err := sink.Publish(context.Background(), event); err != nil {
+		t.Errorf("Expected publish to succeed after a rejection, got %v", err)
+	}
+
+	if err := sink.Close(); err != nil {
+		t.Errorf("Expected close to flush and close the connection, got %v", err)
+	}
+}
+
+// tests sent events past the retention are pruned while pending ones stay
+func TestOutboxPruneSent(t *testing.T) {
+	_, store := newTestServer(t)
+	ctx := context.Background()
+
+	user := createUser(t, store, "outbox_prune@example.com", "password123")
+
+	dispatcher := outbox.NewDispatcher(store.Outbox, &flakySink{})
+	dispatcher.DispatchPending(ctx)
+
+	store.Users.UpdateProfile(ctx, user.ID, "Pending", "", user.Email)
+
+	// events sent just now are still within the retention
+	if deleted, err := dispatcher.PruneSent(ctx); err != nil || deleted != 0 {
+		t.Fatalf("Expected nothing pruned, got %d, %v", deleted, err)
+	}
+
+	dispatcher.Retention = -time.Minute
+	if deleted, err := dispatcher.PruneSent(ctx); err != nil || deleted != 1 {
+		t.Fatalf("Expected the sent event pruned, got %d, %v", deleted, err)
+	}
+
+	events, _ := store.Outbox.ListForAggregate(ctx, user.ID)
+	if got := eventTypes(events); len(got) != 1 || got[0] != constants.WebhookUserUpdated || events[0].Status != constants.OutboxPending {
+		t.Errorf("Expected only the pending event to remain, got %v", got)
+	}
+}
@@ -0,0 +1,126 @@
+package tests
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+	"user-auth-go/internal/api"
+	"user-auth-go/internal/config"
+	"user-auth-go/internal/password"
+)
+
+func violationRules(violations []password.Violation) []string {
+	var rules []string
+	for _, v := range violations {
+		rules = append(rules, v.Rule)
+	}
+	return rules
+}
+
+// tests each policy rule reports its own violation
+func TestPasswordPolicyRules(t *testing.T) {
+	policy := &password.Policy{
+		MinLength:     10,
+		MaxLength:     20,
+		RequireUpper:  true,
+		RequireLower:  true,
+		RequireDigit:  true,
+		RequireSymbol: true,
+		MinStrength:   3,
+		Breached:      password.BundledBreachedList(),
+	}
+
+	cases := []struct {
+		password string
+		personal []string
+		expected []string
+	}{
+		{"short", nil, []string{password.RuleMinLength, password.RuleUppercase, password.RuleDigit, password.RuleSymbol, password.RuleStrength}},
+		{"ALLUPPERCASE12345!", nil, []string{password.RuleLowercase}},
+		{"nouppercase-here-99", nil, []string{password.RuleUppercase}},
+		{"Johnny-Rivers-2024!", []string{"johnny@example.com"}, []string{password.RulePersonalInfo}},
+		{"Vi0let-Harb0r-Sails-Far", nil, []string{password.RuleMaxLength}},
+		{"Mv9#tQ2!xLp7", nil, nil},
+	}
+
+	for _, tc := range cases {
+		got := strings.Join(violationRules(policy.Validate(tc.password, tc.personal...)), ",")
+		want := strings.Join(tc.expected, ",")
+
+		if got != want {
+			t.Errorf("%q: expected violations [%s], got [%s]", tc.password, want, got)
+		}
+	}
+}
+
+// tests breached passwords are rejected from the bundled list
+func TestPasswordPolicyBreached(t *testing.T) {
+	policy := &password.Policy{MinLength: 1, Breached: password.BundledBreachedList()}
+
+	violations := policy.Validate("p@ssw0rd")
+	if len(violations) != 1 || violations[0].Rule != password.RuleBreached {
+		t.Errorf("Expected breached violation, got %v", violations)
+	}
+
+	list := &password.BreachedList{}
+	if err := list.Load(strings.NewReader("not-a-hash\n")); err == nil || !strings.Contains(err.Error(), "line 1") {
+		t.Errorf("Expected line numbered parse error, got %v", err)
+	}
+}
+
+// tests strength estimate on obvious and random passwords
+func TestEstimateStrength(t *testing.T) {
+	weak := []string{"password", "qwerty123", "aaaaaaaa", "abcdefgh1234", "P@ssw0rd"}
+	for _, pw := range weak {
+		if score := password.EstimateStrength(pw); score > 1 {
+			t.Errorf("Expected %q to score at most 1, got %d", pw, score)
+		}
+	}
+
+	strong := []string{"Mv9#tQ2!xLp7", "correct-horse-battery-staple"}
+	for _, pw := range strong {
+		if score := password.EstimateStrength(pw); score < 3 {
+			t.Errorf("Expected %q to score at least 3, got %d", pw, score)
+		}
+	}
+
+	if password.EstimateStrength("marigold1987", "marigold@example.com") >= password.EstimateStrength("marigold1987") {
+		t.Errorf("Expected user inputs to lower the strength estimate")
+	}
+}
+
+// tests signup returns per-rule failures
+func TestSignupPasswordPolicyErrors(t *testing.T) {
+	srv, _ := newTestServer(t)
+
+	rr := postJSON(srv.Signup, "/api/signup", map[string]string{
+		"email":    "policy@example.com",
+		"password": "policy",
+	})
+
+	if rr.Code != http.StatusBadRequest {
+		t.Fatalf("Expected status 400, got %d", rr.Code)
+	}
+
+	var response api.APIResponse
+	json.Unmarshal(rr.Body.Bytes(), &response)
+
+	codes := map[string]bool{}
+	for _, fieldErr := range response.Errors {
+		if fieldErr.Field != "password" {
+			t.Errorf("Expected password field error, got %q", fieldErr.Field)
+		}
+		codes[fieldErr.Code] = true
+	}
+
+	for _, code := range []string{password.RuleMinLength, password.RulePersonalInfo} {
+		if !codes[code] {
+			t.Errorf("Expected %s error, got %+v", code, response.Errors)
+		}
+	}
+
+	if config.PasswordPolicy.MinLength != 8 {
+		t.Errorf("Expected default minimum length 8, got %d", config.PasswordPolicy.MinLength)
+	}
+}
@@ -0,0 +1,185 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"user-auth-go/internal/config"
+	"user-auth-go/internal/password"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// cheap argon2id params so tests stay fast
+func testArgon2id() *password.Argon2id {
+	return &password.Argon2id{Memory: 1024, Iterations: 1, Parallelism: 1, SaltLength: 16, KeyLength: 32}
+}
+
+// tests argon2id hash and verify roundtrip
+func TestArgon2idHashVerify(t *testing.T) {
+	manager, _ := password.NewManager(testArgon2id())
+
+	encoded, err := manager.Hash("password123")
+	if err != nil {
+		t.Fatalf("Failed to hash password: %s", err)
+	}
+
+	if !strings.HasPrefix(encoded, "$argon2id$v=19$m=1024,t=1,p=1$") {
+		t.Errorf("Unexpected argon2id encoding: %s", encoded)
+	}
+
+	if ok, _ := manager.Verify("password123", encoded); !ok {
+		t.Errorf("Expected password to verify")
+	}
+
+	if ok, _ := manager.Verify("wrongpassword", encoded); ok {
+		t.Errorf("Expected wrong password to fail")
+	}
+
+	if manager.NeedsRehash(encoded) {
+		t.Errorf("Expected fresh hash not to need rehash")
+	}
+}
+
+// tests outdated hashes are detected
+func TestPasswordNeedsRehash(t *testing.T) {
+	manager, _ := password.NewManager(testArgon2id())
+
+	bcryptHash, _ := bcrypt.GenerateFromPassword([]byte("password123"), bcrypt.MinCost)
+	if !manager.NeedsRehash(string(bcryptHash)) {
+		t.Errorf("Expected bcrypt hash to need rehash under argon2id")
+	}
+
+	if ok, _ := manager.Verify("password123", string(bcryptHash)); !ok {
+		t.Errorf("Expected bcrypt hash to still verify")
+	}
+
+	weaker := testArgon2id()
+	weaker.Memory = 512
+	weakHash, _ := weaker.Hash("password123")
+	if !manager.NeedsRehash(weakHash) {
+		t.Errorf("Expected argon2id hash with other params to need rehash")
+	}
+
+	bcryptManager, _ := password.NewManager(&password.Bcrypt{Cost: bcrypt.MinCost + 1})
+	if !bcryptManager.NeedsRehash(string(bcryptHash)) {
+		t.Errorf("Expected bcrypt hash with lower cost to need rehash")
+	}
+}
+
+// tests hashes imported from other systems
+func TestVerifyImportedHashes(t *testing.T) {
+	manager, _ := password.NewManager(testArgon2id())
+
+	hashes := []string{
+		"pbkdf2_sha256$10000$seasalt123$b5yWtMXiF50QDfpRrGe205KNBQCr/9OM1eguO3mHRyw=",
+		"$pbkdf2-sha512$5000$MDEyMzQ1Njc4OWFiY2RlZg$WKFoD/zBj1DaMjiry.T1pLUuQavMksVoklC5GYhA9FesQC0Geh8qALTJGwNQHBnPXewLp0WrTlZDyP.t7t6aBQ",
+		"$scrypt$ln=10,r=8,p=1$MDEyMzQ1Njc4OWFiY2RlZg$6g3umF.uVrJsObaTZhIbbTlgrvOEFcCItdwSjtPF67M",
+	}
+
+	for _, encoded := range hashes {
+		if ok, err := manager.Verify("correct horse", encoded); !ok || err != nil {
+			t.Errorf("Expected %s to verify, got %v (%v)", encoded, ok, err)
+		}
+
+		if ok, _ := manager.Verify("battery staple", encoded); ok {
+			t.Errorf("Expected wrong password to fail for %s", encoded)
+		}
+
+		if !manager.NeedsRehash(encoded) {
+			t.Errorf("Expected imported hash %s to need rehash", encoded)
+		}
+	}
+
+	if _, err := manager.Verify("correct horse", "md5$abc"); err != password.ErrUnknownHashFormat {
+		t.Errorf("Expected ErrUnknownHashFormat, got %v", err)
+	}
+}
+
+// tests truncated digests and broken params never verify, whatever the password
+func TestVerifyCorruptLegacyHashes(t *testing.T) {
+	manager, _ := password.NewManager(testArgon2id())
+
+	tests := []struct {
+		encoded string
+		err     error
+	}{
+		{"$scrypt$ln=4,r=8,p=1$c2FsdA$", password.ErrUnknownHashFormat},
+		{"$scrypt$ln=4,r=8,p=1$c2FsdA$6g3umF", password.ErrUnknownHashFormat},
+		{"$scrypt$ln=4,r=0,p=1$c2FsdA$6g3umF.uVrJsObaTZhIbbTlgrvOEFcCItdwSjtPF67M", nil},
+		{"$scrypt$ln=4,r=8,p=0$c2FsdA$6g3umF.uVrJsObaTZhIbbTlgrvOEFcCItdwSjtPF67M", nil},
+		{"$scrypt$ln=4,r=-1,p=1$c2FsdA$6g3umF.uVrJsObaTZhIbbTlgrvOEFcCItdwSjtPF67M", nil},
+		{"pbkdf2_sha256$10000$seasalt123$", password.ErrUnknownHashFormat},
+	}
+
+	for _, tt := range tests {
+		ok, err := manager.Verify("anything", tt.encoded)
+		if ok || err == nil {
+			t.Errorf("Expected %s to fail with an error, got %v (%v)", tt.encoded, ok, err)
+		}
+		if tt.err != nil && err != tt.err {
+			t.Errorf("Expected %v for %s, got %v", tt.err, tt.encoded, err)
+		}
+	}
+}
+
+// tests argon2id hashes the library would panic on are refused
+func TestVerifyCorruptArgon2id(t *testing.T) {
+	manager, _ := password.NewManager(testArgon2id())
+
+	tests := []struct {
+		name    string
+		encoded string
+	}{
+		{"empty hash", "$argon2id$v=19$m=1024,t=1,p=1$c2FsdHNhbHQ$"},
+		{"zero parallelism", "$argon2id$v=19$m=1024,t=1,p=0$c2FsdHNhbHQ$aGFzaGhhc2hoYXNoaGFzaA"},
+		{"zero iterations", "$argon2id$v=19$m=1024,t=0,p=1$c2FsdHNhbHQ$aGFzaGhhc2hoYXNoaGFzaA"},
+		{"memory below 8 per lane", "$argon2id$v=19$m=15,t=1,p=2$c2FsdHNhbHQ$aGFzaGhhc2hoYXNoaGFzaA"},
+	}
+
+	for _, tt := range tests {
+		ok, err := manager.Verify("anything", tt.encoded)
+		if ok || err == nil {
+			t.Errorf("%s: expected an error, got %v (%v)", tt.name, ok, err)
+		}
+
+		if !manager.NeedsRehash(tt.encoded) {
+			t.Errorf("%s: expected corrupt hash to need rehash", tt.name)
+		}
+	}
+}
+
+// tests login upgrades an outdated hash
+func TestLoginRehashesOutdatedPassword(t *testing.T) {
+	srv, store := newTestServer(t)
+
+	previous := config.PasswordHasher
+	config.PasswordHasher, _ = password.NewManager(testArgon2id())
+	defer func() { config.PasswordHasher = previous }()
+
+	bcryptHash, _ := bcrypt.GenerateFromPassword([]byte("password123"), bcrypt.MinCost)
+	store.Users.Create(context.Background(), "rehash@example.com", string(bcryptHash))
+
+	jsonBody, _ := json.Marshal(map[string]string{"email": "rehash@example.com", "password": "password123"})
+	req := httptest.NewRequest(http.MethodPost, "/api/login", bytes.NewBuffer(jsonBody))
+
+	rr := httptest.NewRecorder()
+	http.HandlerFunc(srv.Login).ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d. Body: %s", rr.Code, rr.Body.String())
+	}
+
+	user, _ := store.Users.GetByIdentifier(context.Background(), "rehash@example.com", "")
+	if !strings.HasPrefix(user.Password, "$argon2id$") {
+		t.Errorf("Expected password to be rehashed with argon2id, got %s", user.Password)
+	}
+
+	if !user.CheckPassword("password123") {
+		t.Errorf("Expected rehashed password to still verify")
+	}
+}
@@ -0,0 +1,87 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"user-auth-go/internal/config"
+)
+
+func putJSONWithSession(handler http.HandlerFunc, path, token string, body interface{}) *httptest.ResponseRecorder {
+	jsonBody, _ := json.Marshal(body)
+
+	req := httptest.NewRequest(http.MethodPut, path, bytes.NewBuffer(jsonBody))
+	req.Header.Set("Content-Type", "application/json")
+	req.AddCookie(&http.Cookie{Name: config.SessionCookie.Name(), Value: token})
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	return rr
+}
+
+// tests change password keeps the current session and revokes the others
+func TestChangePassword(t *testing.T) {
+	srv, store := newTestServer(t)
+
+	user := createUser(t, store, "change_pass@example.com", "password123")
+
+	current, _ := store.Sessions.Create(context.Background(), user.ID)
+	other, _ := store.Sessions.Create(context.Background(), user.ID)
+
+	// wrong current password
+	rr := putJSONWithSession(srv.AuthGuard(srv.ChangePassword), "/api/profile/password", current.Token, map[string]string{
+		"current_password": "not-my-password",
+		"new_password":     "violet-Harbor-42",
+	})
+	if rr.Code != http.StatusUnauthorized {
+		t.Errorf("Expected status 401 for wrong current password, got %d", rr.Code)
+	}
+
+	rr = putJSONWithSession(srv.AuthGuard(srv.ChangePassword), "/api/profile/password", current.Token, map[string]string{
+		"current_password": "password123",
+		"new_password":     "violet-Harbor-42",
+	})
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d. Body: %s", rr.Code, rr.Body.String())
+	}
+
+	updated, _ := store.Users.GetByID(context.Background(), user.ID)
+	if !updated.CheckPassword("violet-Harbor-42") {
+		t.Errorf("Expected new password to be stored")
+	}
+
+	if session, _ := store.Sessions.GetByToken(context.Background(), current.Token); session == nil {
+		t.Errorf("Expected current session to stay valid")
+	}
+
+	if session, _ := store.Sessions.GetByToken(context.Background(), other.Token); session != nil {
+		t.Errorf("Expected other sessions to be revoked")
+	}
+}
+
+// tests google accounts can set a first password without a current one
+func TestChangePasswordGoogleFirstPassword(t *testing.T) {
+	srv, store := newTestServer(t)
+
+	user, _ := store.Users.CreateWithGoogle(context.Background(), "google_pass@example.com", "google-pass-id", "Google Pass")
+
+	session, _ := store.Sessions.Create(context.Background(), user.ID)
+
+	rr := putJSONWithSession(srv.AuthGuard(srv.ChangePassword), "/api/profile/password", session.Token, map[string]string{
+		"new_password": "violet-Harbor-42",
+	})
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d. Body: %s", rr.Code, rr.Body.String())
+	}
+
+	rr = postJSON(srv.Login, "/api/login", map[string]string{
+		"email":    "google_pass@example.com",
+		"password": "violet-Harbor-42",
+	})
+	if rr.Code != http.StatusOK {
+		t.Errorf("Expected password login to work after setting a password, got %d", rr.Code)
+	}
+}
@@ -0,0 +1,120 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"user-auth-go/constants"
+	"user-auth-go/internal/api"
+	"user-auth-go/internal/config"
+)
+
+// tests endpoints are served under /api/v1 and at their deprecated unversioned paths
+func TestVersionedRoutes(t *testing.T) {
+	srv, store := newTestServer(t)
+	createUser(t, store, "routes@example.com", "password123")
+
+	mux := http.NewServeMux()
+	srv.Routes(mux)
+
+	credentials := map[string]string{"email": "routes@example.com", "password": "password123"}
+
+	rr := postJSON(mux.ServeHTTP, "/api/v1/login", credentials)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected 200 from /api/v1/login, got %d", rr.Code)
+	}
+	if rr.Header().Get("Deprecation") != "" {
+		t.Errorf("Expected no Deprecation header on the versioned route")
+	}
+
+	rr = postJSON(mux.ServeHTTP, "/api/login", credentials)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected 200 from the /api/login alias, got %d", rr.Code)
+	}
+	if rr.Header().Get("Deprecation") == "" || rr.Header().Get("Link") != `</api/v1/login>; rel="successor-version"` {
+		t.Errorf("Expected Deprecation and successor Link headers, got %q and %q", rr.Header().Get("Deprecation"), rr.Header().Get("Link"))
+	}
+}
+
+// tests known paths answer other methods with 405 and Allow, unknown API paths with 404, both as JSON
+func TestRouteMethodNotAllowed(t *testing.T) {
+	srv, _ := newTestServer(t)
+
+	mux := http.NewServeMux()
+	srv.Routes(mux)
+
+	tests := []struct {
+		method, path string
+		status       int
+		allow        string
+	}{
+		{http.MethodGet, "/api/v1/login", http.StatusMethodNotAllowed, "POST"},
+		{http.MethodPatch, "/api/v1/profile", http.StatusMethodNotAllowed, "GET, HEAD, PUT"},
+		{http.MethodDelete, "/api/signup", http.StatusMethodNotAllowed, "POST"},
+		{http.MethodGet, "/api/v1/nothing-here", http.StatusNotFound, ""},
+	}
+
+	for _, tt := range tests {
+		rr := httptest.NewRecorder()
+		mux.ServeHTTP(rr, httptest.NewRequest(tt.method, tt.path, nil))
+
+		if rr.Code != tt.status || rr.Header().Get("Allow") != tt.allow {
+			t.Errorf("%s %s: expected %d with Allow %q, got %d with %q", tt.method, tt.path, tt.status, tt.allow, rr.Code, rr.Header().Get("Allow"))
+		}
+
+		var response api.APIResponse
+		if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil || response.Success {
+			t.Errorf("%s %s: expected a JSON error body, got %s", tt.method, tt.path, rr.Body.String())
+		}
+	}
+}
+
+// tests `{id}` path parameters and the `?id=` form of their deprecated aliases
+func TestRoutePathParameters(t *testing.T) {
+	srv, store := newTestServer(t)
+	ctx := context.Background()
+
+	admin := createUser(t, store, "routes_admin@example.com", "password123")
+	store.Users.UpdateRole(ctx, admin.ID, constants.RoleAdmin)
+	session, _ := store.Sessions.Create(ctx, admin.ID)
+
+	mux := http.NewServeMux()
+	srv.Routes(mux)
+
+	deleteUser := func(path string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodDelete, path, nil)
+		req.AddCookie(&http.Cookie{Name: config.SessionCookie.Name(), Value: session.Token})
+
+		rr := httptest.NewRecorder()
+		mux.ServeHTTP(rr, req)
+		return rr
+	}
+
+	first := createUser(t, store, "routes_first@example.com", "password123")
+	if rr := deleteUser("/api/v1/admin/users/" + strconv.Itoa(first.ID)); rr.Code != http.StatusOK {
+		t.Errorf("Expected 200 deleting by path, got %d: %s", rr.Code, rr.Body.String())
+	}
+
+	second := createUser(t, store, "routes_second@example.com", "password123")
+	rr := deleteUser("/api/admin/users?id=" + strconv.Itoa(second.ID))
+	if rr.Code != http.StatusOK {
+		t.Errorf("Expected 200 deleting through the alias, got %d: %s", rr.Code, rr.Body.String())
+	}
+
+	if link := rr.Header().Get("Link"); link != "</api/v1/admin/users/"+strconv.Itoa(second.ID)+`>; rel="successor-version"` {
+		t.Errorf("Expected the successor link to carry the id, got %q", link)
+	}
+
+	for _, user := range []int{first.ID, second.ID} {
+		if found, _ := store.Users.GetByID(ctx, user); found != nil {
+			t.Errorf("Expected user %d to be deleted", user)
+		}
+	}
+
+	if rr := deleteUser("/api/v1/admin/users/not-a-number"); rr.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for a malformed id, got %d", rr.Code)
+	}
+}
@@ -0,0 +1,183 @@
+package tests
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"user-auth-go/internal/admin"
+	"user-auth-go/internal/config"
+	"user-auth-go/internal/secrets"
+)
+
+func newMasterKey(t *testing.T) (string, []byte) {
+	t.Helper()
+
+	encoded, err := secrets.GenerateKey()
+	if err != nil {
+		t.Fatalf("Failed to generate key: %s", err)
+	}
+
+	key, _ := secrets.ParseKey(encoded)
+	return encoded, key
+}
+
+// tests sealed secrets only open with the same key
+func TestSecretsSealOpen(t *testing.T) {
+	_, key := newMasterKey(t)
+	_, otherKey := newMasterKey(t)
+
+	content, err := secrets.Seal(key, map[string]string{"DB_PASS": "hunter2"})
+	if err != nil {
+		t.Fatalf("Failed to seal: %s", err)
+	}
+
+	if bytes.Contains(content, []byte("hunter2")) {
+		t.Errorf("Expected secret to be encrypted")
+	}
+
+	values, err := secrets.Open(key, content)
+	if err != nil || values["DB_PASS"] != "hunter2" {
+		t.Errorf("Expected secret back, got %v %v", values, err)
+	}
+
+	if _, err := secrets.Open(otherKey, content); !errors.Is(err, secrets.ErrDecrypt) {
+		t.Errorf("Expected decrypt error with another key, got %v", err)
+	}
+
+	if _, err := secrets.ParseKey("c2hvcnQ="); !errors.Is(err, secrets.ErrInvalidKey) {
+		t.Errorf("Expected short key to be rejected, got %v", err)
+	}
+}
+
+// tests secrets from `*_FILE` and the encrypted file, with env vars still winning
+func TestSecretProviders(t *testing.T) {
+	dir := t.TempDir()
+	encoded, key := newMasterKey(t)
+
+	secretsFile := filepath.Join(dir, "secrets.enc")
+	secrets.WriteFile(secretsFile, key, map[string]string{
+		"GOOGLE_CLIENT_SECRET": "from-encrypted-file",
+		"SMTP_PASS":            "smtp-from-encrypted-file",
+		"DB_PASS":              "db-from-encrypted-file",
+	})
+
+	dbPassFile := filepath.Join(dir, "db_pass")
+	os.WriteFile(dbPassFile, []byte("db-from-docker-secret\n"), 0o600)
+
+	t.Setenv("SECRETS_FILE", secretsFile)
+	t.Setenv("SECRETS_MASTER_KEY", encoded)
+	t.Setenv("DB_PASS_FILE", dbPassFile)
+	t.Setenv("SMTP_PASS", "smtp-from-env")
+
+	cfg, err := config.Resolve(nil)
+	if err != nil {
+		t.Fatalf("Expected valid config, got %s", err)
+	}
+
+	if cfg.Database.Pass != "db-from-docker-secret" {
+		t.Errorf("Expected *_FILE to win over the encrypted file, got %q", cfg.Database.Pass)
+	}
+
+	if cfg.Google.ClientSecret != "from-encrypted-file" {
+		t.Errorf("Expected secret from encrypted file, got %q", cfg.Google.ClientSecret)
+	}
+
+	if cfg.Mail.SMTPPass != "smtp-from-env" {
+		t.Errorf("Expected env var to win over providers, got %q", cfg.Mail.SMTPPass)
+	}
+
+	// both the variable and its file is ambiguous
+	t.Setenv("DB_PASS", "plain")
+	if _, err := config.Resolve(nil); err == nil || !strings.Contains(err.Error(), "DB_PASS_FILE") {
+		t.Errorf("Expected conflict between DB_PASS and DB_PASS_FILE, got %v", err)
+	}
+
+	t.Setenv("DB_PASS", "")
+	t.Setenv("SECRETS_MASTER_KEY", "")
+	if _, err := config.Resolve(nil); err == nil || !strings.Contains(err.Error(), "SECRETS_MASTER_KEY") {
+		t.Errorf("Expected missing master key to be reported, got %v", err)
+	}
+}
+
+// tests a rotated Google client secret applies without restart
+func TestReloadSecrets(t *testing.T) {
+	// registered first so it runs last, once the env below is restored
+	t.Cleanup(func() { config.Load() })
+
+	secretFile := filepath.Join(t.TempDir(), "google_secret")
+	os.WriteFile(secretFile, []byte("first-secret"), 0o600)
+
+	t.Setenv("GOOGLE_OAUTH_ENABLED", "true")
+	t.Setenv("GOOGLE_CLIENT_ID", "client-id")
+	t.Setenv("GOOGLE_CLIENT_SECRET_FILE", secretFile)
+
+	if err := config.Setup(nil); err != nil {
+		t.Fatalf("Failed to load config: %s", err)
+	}
+
+	if got := config.GoogleOAuth().ClientSecret; got != "first-secret" {
+		t.Fatalf("Expected first secret, got %q", got)
+	}
+
+	os.WriteFile(secretFile, []byte("rotated-secret"), 0o600)
+
+	applied, pending, err := config.ReloadSecrets()
+	if err != nil {
+		t.Fatalf("Failed to reload: %s", err)
+	}
+
+	if len(applied) != 1 || applied[0] != "GOOGLE_CLIENT_SECRET" || len(pending) != 0 {
+		t.Errorf("Expected only the Google secret to be applied, got %v and %v", applied, pending)
+	}
+
+	if got := config.GoogleOAuth().ClientSecret; got != "rotated-secret" {
+		t.Errorf("Expected rotated secret, got %q", got)
+	}
+
+	// a broken source keeps the running config
+	os.Remove(secretFile)
+	if _, _, err := config.ReloadSecrets(); err == nil {
+		t.Errorf("Expected reload to fail with a missing secret file")
+	}
+
+	if got := config.GoogleOAuth().ClientSecret; got != "rotated-secret" {
+		t.Errorf("Expected secret to be kept after a failed reload, got %q", got)
+	}
+}
+
+// tests the admin CLI edits the encrypted file without printing values
+func TestAdminSecrets(t *testing.T) {
+	encoded, key := newMasterKey(t)
+	secretsFile := filepath.Join(t.TempDir(), "secrets.enc")
+
+	t.Setenv("SECRETS_MASTER_KEY", encoded)
+	t.Setenv("SECRETS_FILE", secretsFile)
+
+	var stdout bytes.Buffer
+	cli := &admin.CLI{Stdout: &stdout, Stderr: &bytes.Buffer{}, Stdin: strings.NewReader("smtp-secret\n")}
+
+	if code := cli.Run([]string{"secrets", "set", "SMTP_PASS"}); code != admin.ExitOK {
+		t.Fatalf("Expected secrets set to succeed, got %d", code)
+	}
+
+	values, err := secrets.ReadFile(secretsFile, key)
+	if err != nil || values["SMTP_PASS"] != "smtp-secret" {
+		t.Errorf("Expected secret in encrypted file, got %v %v", values, err)
+	}
+
+	stdout.Reset()
+	if code := cli.Run([]string{"secrets", "list"}); code != admin.ExitOK {
+		t.Fatalf("Expected secrets list to succeed, got %d", code)
+	}
+
+	if stdout.String() != "SMTP_PASS\n" {
+		t.Errorf("Expected only names to be listed, got %q", stdout.String())
+	}
+
+	if code := cli.Run([]string{"secrets", "set", "PORT", "--value", "1"}); code != admin.ExitUsage {
+		t.Errorf("Expected non secret setting to be rejected, got %d", code)
+	}
+}
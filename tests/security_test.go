@@ -0,0 +1,159 @@
+package tests
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"user-auth-go/internal/middleware"
+)
+
+var okHandler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	w.Write([]byte(middleware.Nonce(r)))
+})
+
+// tests security headers and per-request CSP nonce
+func TestSecurityHeaders(t *testing.T) {
+	handler := middleware.SecurityHeaders(middleware.SecurityOptions{
+		ContentSecurityPolicy: middleware.DefaultContentSecurityPolicy,
+		HSTSMaxAge:            31536000,
+		HSTSIncludeSubdomains: true,
+		FrameOptions:          "DENY",
+		ReferrerPolicy:        "no-referrer",
+		PermissionsPolicy:     "camera=()",
+	})(okHandler)
+
+	req := httptest.NewRequest(http.MethodGet, "/login", nil)
+	req.Header.Set("X-Forwarded-Proto", "https")
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	nonce := rr.Body.String()
+	if nonce == "" {
+		t.Fatalf("Expected nonce to be available to the handler")
+	}
+
+	csp := rr.Header().Get("Content-Security-Policy")
+	if !strings.Contains(csp, "'nonce-"+nonce+"'") {
+		t.Errorf("Expected CSP to contain request nonce, got %q", csp)
+	}
+
+	expected := map[string]string{
+		"Strict-Transport-Security": "max-age=31536000; includeSubDomains",
+		"X-Frame-Options":           "DENY",
+		"Referrer-Policy":           "no-referrer",
+		"Permissions-Policy":        "camera=()",
+		"X-Content-Type-Options":    "nosniff",
+	}
+	for header, value := range expected {
+		if got := rr.Header().Get(header); got != value {
+			t.Errorf("Expected %s %q, got %q", header, value, got)
+		}
+	}
+
+	// nonce must change on every request
+	rr2 := httptest.NewRecorder()
+	handler.ServeHTTP(rr2, req)
+	if rr2.Body.String() == nonce {
+		t.Errorf("Expected a fresh nonce per request")
+	}
+}
+
+// tests HSTS is not sent over plain http
+func TestSecurityHeadersNoHSTSOverHTTP(t *testing.T) {
+	handler := middleware.SecurityHeaders(middleware.SecurityOptions{HSTSMaxAge: 600})(okHandler)
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if got := rr.Header().Get("Strict-Transport-Security"); got != "" {
+		t.Errorf("Expected no HSTS header over http, got %q", got)
+	}
+}
+
+// tests CORS preflight for an allowlisted origin
+func TestCORSPreflight(t *testing.T) {
+	handler := middleware.CORS(middleware.CORSOptions{
+		AllowedOrigins:   []string{"https://app.example.com"},
+		AllowedMethods:   []string{"GET", "POST"},
+		AllowedHeaders:   []string{"Content-Type"},
+		AllowCredentials: true,
+		MaxAge:           600,
+	})(okHandler)
+
+	req := httptest.NewRequest(http.MethodOptions, "/api/login", nil)
+	req.Header.Set("Origin", "https://app.example.com")
+	req.Header.Set("Access-Control-Request-Method", "POST")
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusNoContent {
+		t.Errorf("Expected status 204, got %d", rr.Code)
+	}
+
+	expected := map[string]string{
+		"Access-Control-Allow-Origin":      "https://app.example.com",
+		"Access-Control-Allow-Credentials": "true",
+		"Access-Control-Allow-Methods":     "GET, POST",
+		"Access-Control-Allow-Headers":     "Content-Type",
+		"Access-Control-Max-Age":           "600",
+	}
+	for header, value := range expected {
+		if got := rr.Header().Get(header); got != value {
+			t.Errorf("Expected %s %q, got %q", header, value, got)
+		}
+	}
+}
+
+// tests a wildcard never lets a foreign origin send credentials, even when they are enabled
+func TestCORSWildcardWithoutCredentials(t *testing.T) {
+	handler := middleware.CORS(middleware.CORSOptions{
+		AllowedOrigins:   []string{"*", "https://app.example.com"},
+		AllowCredentials: true,
+	})(okHandler)
+
+	request := func(origin string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/api/profile", nil)
+		req.Header.Set("Origin", origin)
+
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		return rr
+	}
+
+	rr := request("https://evil.example.com")
+	if got := rr.Header().Get("Access-Control-Allow-Origin"); got != "*" {
+		t.Errorf("Expected Access-Control-Allow-Origin *, got %q", got)
+	}
+	if got := rr.Header().Get("Access-Control-Allow-Credentials"); got != "" {
+		t.Errorf("Expected no Access-Control-Allow-Credentials for a foreign origin, got %q", got)
+	}
+
+	rr = request("https://app.example.com")
+	if rr.Header().Get("Access-Control-Allow-Origin") != "https://app.example.com" || rr.Header().Get("Access-Control-Allow-Credentials") != "true" {
+		t.Errorf("Expected the listed origin to keep credentials, got %v", rr.Header())
+	}
+}
+
+// tests origins outside the allowlist get no CORS headers
+func TestCORSDisallowedOrigin(t *testing.T) {
+	handler := middleware.CORS(middleware.CORSOptions{
+		AllowedOrigins: []string{"https://app.example.com"},
+	})(okHandler)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/profile", nil)
+	req.Header.Set("Origin", "https://evil.example.com")
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	if got := rr.Header().Get("Access-Control-Allow-Origin"); got != "" {
+		t.Errorf("Expected no Access-Control-Allow-Origin, got %q", got)
+	}
+
+	if rr.Code != http.StatusOK {
+		t.Errorf("Expected request to reach handler, got %d", rr.Code)
+	}
+}
@@ -0,0 +1,273 @@
+package tests
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+	"user-auth-go/internal/certs"
+	"user-auth-go/internal/config"
+)
+
+// testCert is a generated certificate with its key
+type testCert struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	der  []byte
+}
+
+// handle issue a certificate from template, self signed when parent is nil
+func issueCert(t *testing.T, template *x509.Certificate, parent *testCert) *testCert {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %s", err)
+	}
+
+	serial, _ := rand.Int(rand.Reader, big.NewInt(1<<62))
+	template.SerialNumber = serial
+	template.NotBefore = time.Now().Add(-time.Minute)
+	template.NotAfter = time.Now().Add(time.Hour)
+
+	signer, signerKey := template, key
+	if parent != nil {
+		signer, signerKey = parent.cert, parent.key
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, signer, &key.PublicKey, signerKey)
+	if err != nil {
+		t.Fatalf("Failed to create certificate: %s", err)
+	}
+
+	cert, _ := x509.ParseCertificate(der)
+	return &testCert{cert: cert, key: key, der: der}
+}
+
+func newTestCA(t *testing.T, name string) *testCert {
+	return issueCert(t, &x509.Certificate{
+		Subject:               pkix.Name{CommonName: name},
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}, nil)
+}
+
+func newServerCert(t *testing.T, ca *testCert, name string) *testCert {
+	return issueCert(t, &x509.Certificate{
+		Subject:     pkix.Name{CommonName: name},
+		IPAddresses: []net.IP{net.ParseIP("127.0.0.1")},
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}, ca)
+}
+
+func newClientCert(t *testing.T, ca *testCert, name string, uris ...string) *testCert {
+	template := &x509.Certificate{
+		Subject:     pkix.Name{CommonName: name},
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	for _, raw := range uris {
+		parsed, _ := url.Parse(raw)
+		template.URIs = append(template.URIs, parsed)
+	}
+	return issueCert(t, template, ca)
+}
+
+func (c *testCert) tlsCertificate() tls.Certificate {
+	return tls.Certificate{Certificate: [][]byte{c.der}, PrivateKey: c.key, Leaf: c.cert}
+}
+
+// handle write the certificate and key as PEM files, returns their paths
+func (c *testCert) writeFiles(t *testing.T, dir, name string) (string, string) {
+	t.Helper()
+
+	keyDER, _ := x509.MarshalECPrivateKey(c.key)
+	certFile := filepath.Join(dir, name+".crt")
+	keyFile := filepath.Join(dir, name+".key")
+
+	os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: c.der}), 0600)
+	os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600)
+
+	return certFile, keyFile
+}
+
+// tests a renewed certificate is served without restarting
+func TestCertificateReload(t *testing.T) {
+	dir := t.TempDir()
+	ca := newTestCA(t, "Test CA")
+
+	certFile, keyFile := newServerCert(t, ca, "first").writeFiles(t, dir, "server")
+
+	reloader, err := certs.NewReloader(certFile, keyFile)
+	if err != nil {
+		t.Fatalf("Failed to load certificate: %s", err)
+	}
+
+	served := func() string {
+		cert, _ := reloader.GetCertificate(nil)
+		leaf, _ := x509.ParseCertificate(cert.Certificate[0])
+		return leaf.Subject.CommonName
+	}
+
+	if name := served(); name != "first" {
+		t.Fatalf("Expected the first certificate, got %s", name)
+	}
+
+	// a broken pair keeps the previous certificate
+	os.WriteFile(keyFile, []byte("not a key"), 0600)
+	if err := reloader.Reload(); err == nil || served() != "first" {
+		t.Errorf("Expected a failed reload to keep the first certificate, got %v and %s", err, served())
+	}
+
+	newServerCert(t, ca, "renewed").writeFiles(t, dir, "server")
+	later := time.Now().Add(time.Second)
+	os.Chtimes(certFile, later, later)
+
+	reloader.Interval = 10 * time.Millisecond
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go reloader.Run(ctx)
+
+	deadline := time.Now().Add(2 * time.Second)
+	for served() != "renewed" && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if name := served(); name != "renewed" {
+		t.Errorf("Expected the renewed certificate after the files changed, got %s", name)
+	}
+}
+
+// tests identity entries are parsed and looked up by SAN before the common name
+func TestClientIdentities(t *testing.T) {
+	for _, entry := range []string{"billing=billing@example.com", "ip:10.0.0.1=ops@example.com", "cn:billing=not-an-email", "cn:=x@example.com"} {
+		if _, err := certs.ParseIdentities([]string{entry}); err == nil {
+			t.Errorf("Expected %q to be rejected", entry)
+		}
+	}
+
+	identities, err := certs.ParseIdentities([]string{
+		"cn:shared-name=cn@example.com",
+		"uri:spiffe://prod/billing?env=eu=billing@example.com",
+	})
+	if err != nil {
+		t.Fatalf("Failed to parse identities: %s", err)
+	}
+
+	ca := newTestCA(t, "Test CA")
+	cert := newClientCert(t, ca, "shared-name", "spiffe://prod/billing?env=eu").cert
+
+	if email, ok := identities.Lookup(cert); !ok || email != "billing@example.com" {
+		t.Errorf("Expected the URI SAN to win, got %q", email)
+	}
+
+	if email, ok := identities.Lookup(newClientCert(t, ca, "shared-name").cert); !ok || email != "cn@example.com" {
+		t.Errorf("Expected the common name to match, got %q", email)
+	}
+
+	if _, ok := identities.Lookup(newClientCert(t, ca, "stranger").cert); ok {
+		t.Errorf("Expected an unmapped certificate to be unknown")
+	}
+}
+
+// tests verified client certificates authenticate as their mapped user in AuthGuard
+func TestMutualTLSAuthGuard(t *testing.T) {
+	dir := t.TempDir()
+	ca := newTestCA(t, "Test CA")
+	clientCA := newTestCA(t, "Client CA")
+	otherCA := newTestCA(t, "Other CA")
+
+	certFile, keyFile := newServerCert(t, ca, "server").writeFiles(t, dir, "server")
+	clientCAFile, _ := clientCA.writeFiles(t, dir, "client-ca")
+
+	srv, store := newTestServer(t)
+	createUser(t, store, "billing@example.com", "password123")
+
+	identities, _ := certs.ParseIdentities([]string{
+		"uri:spiffe://prod/billing=billing@example.com",
+		"cn:retired-service=gone@example.com",
+	})
+	previous := config.ClientIdentities
+	config.ClientIdentities = identities
+	t.Cleanup(func() { config.ClientIdentities = previous })
+
+	reloader, err := certs.NewReloader(certFile, keyFile)
+	if err != nil {
+		t.Fatalf("Failed to load certificate: %s", err)
+	}
+	clientCAs, err := certs.LoadCertPool(clientCAFile)
+	if err != nil {
+		t.Fatalf("Failed to load client CA: %s", err)
+	}
+
+	mux := http.NewServeMux()
+	srv.Routes(mux)
+
+	server := httptest.NewUnstartedServer(mux)
+	server.Listener = tls.NewListener(server.Listener, certs.ServerConfig(reloader, clientCAs, certs.ClientAuthOptional))
+	server.Start()
+	defer server.Close()
+
+	profileURL := strings.Replace(server.URL, "http://", "https://", 1) + "/api/profile"
+
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.cert)
+
+	get := func(client *testCert) (int, string, error) {
+		tlsConfig := &tls.Config{RootCAs: roots}
+		if client != nil {
+			// sent even when the server does not list its CA as acceptable
+			tlsConfig.GetClientCertificate = func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+				cert := client.tlsCertificate()
+				return &cert, nil
+			}
+		}
+
+		httpClient := &http.Client{Transport: &http.Transport{TLSClientConfig: tlsConfig}}
+		resp, err := httpClient.Get(profileURL)
+		if err != nil {
+			return 0, "", err
+		}
+		defer resp.Body.Close()
+
+		body, _ := io.ReadAll(resp.Body)
+		return resp.StatusCode, string(body), nil
+	}
+
+	status, body, err := get(newClientCert(t, clientCA, "billing", "spiffe://prod/billing"))
+	if err != nil || status != http.StatusOK || !strings.Contains(body, "billing@example.com") {
+		t.Errorf("Expected the mapped certificate to reach the profile, got %d %s %v", status, body, err)
+	}
+
+	if status, _, _ := get(newClientCert(t, clientCA, "stranger")); status != http.StatusUnauthorized {
+		t.Errorf("Expected 401 for an unmapped certificate, got %d", status)
+	}
+
+	if status, _, _ := get(newClientCert(t, clientCA, "retired-service")); status != http.StatusUnauthorized {
+		t.Errorf("Expected 401 for a certificate mapped to a missing user, got %d", status)
+	}
+
+	if status, _, _ := get(nil); status != http.StatusUnauthorized {
+		t.Errorf("Expected 401 without a certificate, got %d", status)
+	}
+
+	// certificates from other CAs never reach the handler
+	if _, _, err := get(newClientCert(t, otherCA, "billing", "spiffe://prod/billing")); err == nil {
+		t.Errorf("Expected the handshake to reject a certificate from an unknown CA")
+	}
+}
@@ -0,0 +1,145 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"user-auth-go/internal/middleware"
+	"user-auth-go/internal/tracing"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// handle record spans in memory for the test, the previous provider is restored on cleanup
+func useInMemoryTracer(t *testing.T) *tracetest.InMemoryExporter {
+	t.Helper()
+
+	exporter := tracetest.NewInMemoryExporter()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+
+	previousProvider := otel.GetTracerProvider()
+	previousPropagator := otel.GetTextMapPropagator()
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+
+	t.Cleanup(func() {
+		provider.Shutdown(context.Background())
+		otel.SetTracerProvider(previousProvider)
+		otel.SetTextMapPropagator(previousPropagator)
+	})
+
+	return exporter
+}
+
+func findSpan(spans tracetest.SpanStubs, name string) *tracetest.SpanStub {
+	for i := range spans {
+		if spans[i].Name == name {
+			return &spans[i]
+		}
+	}
+	return nil
+}
+
+// tests a login continues the caller's trace with password and database spans under the route span
+func TestTracingLogin(t *testing.T) {
+	// database spans need a SQL store, keep the configured one when it already is
+	if driver := os.Getenv("TEST_DB_DRIVER"); driver == "" || driver == "memory" {
+		t.Setenv("TEST_DB_DRIVER", "sqlite")
+	}
+
+	srv, store := newTestServer(t)
+	createUser(t, store, "traced@example.com", "password123")
+
+	exporter := useInMemoryTracer(t)
+
+	mux := http.NewServeMux()
+	srv.Routes(mux)
+	handler := middleware.Chain(middleware.Route(mux), tracing.Middleware())
+
+	upstream := "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+	body, _ := json.Marshal(map[string]string{"email": "traced@example.com", "password": "password123"})
+	req := httptest.NewRequest(http.MethodPost, "/api/login", bytes.NewReader(body))
+	req.Header.Set("traceparent", upstream)
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d", rr.Code)
+	}
+
+	spans := exporter.GetSpans()
+
+	server := findSpan(spans, "POST /api/login")
+	if server == nil {
+		t.Fatalf("Expected a span named after the route, got %d spans", len(spans))
+	}
+
+	if server.SpanContext.TraceID().String() != "4bf92f3577b34da6a3ce929d0e0e4736" || server.Parent.SpanID().String() != "00f067aa0ba902b7" {
+		t.Errorf("Expected the upstream trace to continue, got trace %s parent %s", server.SpanContext.TraceID(), server.Parent.SpanID())
+	}
+
+	for _, name := range []string{"password.verify", "SELECT users", "INSERT sessions"} {
+		span := findSpan(spans, name)
+		if span == nil {
+			t.Errorf("Expected a %s span", name)
+			continue
+		}
+
+		if span.Parent.SpanID() != server.SpanContext.SpanID() {
+			t.Errorf("Expected %s to be a child of the request span", name)
+		}
+	}
+
+	if query := findSpan(spans, "SELECT users"); query != nil {
+		for _, attr := range query.Attributes {
+			if attr.Key == "db.query.text" && bytes.Contains([]byte(attr.Value.AsString()), []byte("traced@example.com")) {
+				t.Errorf("Expected query arguments to stay out of spans, got %s", attr.Value.AsString())
+			}
+		}
+	}
+}
+
+// tests the outgoing client used for Google sends the trace along with a client span
+func TestTracingHTTPClient(t *testing.T) {
+	exporter := useInMemoryTracer(t)
+
+	var received string
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = r.Header.Get("traceparent")
+	}))
+	defer upstream.Close()
+
+	ctx, parent := tracing.Start(context.Background(), "google.userinfo")
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, upstream.URL, nil)
+	resp, err := tracing.HTTPClient().Do(req)
+	if err != nil {
+		t.Fatalf("Request failed: %s", err)
+	}
+	resp.Body.Close()
+	parent.End()
+
+	traceID := parent.SpanContext().TraceID().String()
+	if len(received) < 36 || received[3:35] != traceID {
+		t.Errorf("Expected traceparent with trace %s, got %q", traceID, received)
+	}
+
+	var client *tracetest.SpanStub
+	for _, span := range exporter.GetSpans() {
+		if span.SpanKind == trace.SpanKindClient {
+			client = &span
+		}
+	}
+
+	if client == nil || client.Parent.SpanID() != parent.SpanContext().SpanID() {
+		t.Errorf("Expected a client span under the caller's span")
+	}
+}
@@ -0,0 +1,104 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"user-auth-go/internal/api"
+	"user-auth-go/internal/config"
+)
+
+// tests signup and profile bodies are answered with one error per broken field
+func TestRequestValidation(t *testing.T) {
+	srv, store := newTestServer(t)
+	user := createUser(t, store, "validation@example.com", "password123")
+	session, _ := store.Sessions.Create(context.Background(), user.ID)
+
+	tests := []struct {
+		name   string
+		do     func() *httptest.ResponseRecorder
+		errors map[string]string
+	}{
+		{
+			name: "signup missing fields",
+			do: func() *httptest.ResponseRecorder {
+				return postJSON(srv.Signup, "/api/v1/signup", map[string]string{})
+			},
+			errors: map[string]string{"email": "required", "password": "required"},
+		},
+		{
+			name: "signup invalid email",
+			do: func() *httptest.ResponseRecorder {
+				return postJSON(srv.Signup, "/api/v1/signup", map[string]string{"email": "Jane <jane@example.com>", "password": "violet-Harbor-42"})
+			},
+			errors: map[string]string{"email": "invalid_email"},
+		},
+		{
+			name: "signup unknown field",
+			do: func() *httptest.ResponseRecorder {
+				return postJSON(srv.Signup, "/api/v1/signup", map[string]string{"email": "new@example.com", "password": "password123", "role": "admin"})
+			},
+			errors: map[string]string{"role": "unknown_field"},
+		},
+		{
+			name: "signup wrong type",
+			do: func() *httptest.ResponseRecorder {
+				return postJSON(srv.Signup, "/api/v1/signup", map[string]interface{}{"email": 42, "password": "password123"})
+			},
+			errors: map[string]string{"email": "invalid_type"},
+		},
+		{
+			name: "profile too long and invalid email",
+			do: func() *httptest.ResponseRecorder {
+				return putJSONWithSession(srv.AuthGuard(srv.UpdateProfile), "/api/v1/profile", session.Token, map[string]string{
+					"full_name": strings.Repeat("a", 101),
+					"telephone": "0800",
+					"email":     "not-an-email",
+				})
+			},
+			errors: map[string]string{"full_name": "too_long", "email": "invalid_email"},
+		},
+	}
+
+	for _, tt := range tests {
+		rr := tt.do()
+		if rr.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d: %s", tt.name, rr.Code, rr.Body.String())
+			continue
+		}
+
+		var response api.APIResponse
+		json.Unmarshal(rr.Body.Bytes(), &response)
+
+		got := map[string]string{}
+		for _, fieldError := range response.Errors {
+			got[fieldError.Field] = fieldError.Code
+		}
+
+		if len(got) != len(tt.errors) {
+			t.Errorf("%s: expected errors %v, got %v", tt.name, tt.errors, got)
+		}
+		for field, code := range tt.errors {
+			if got[field] != code {
+				t.Errorf("%s: expected %s on %s, got %q", tt.name, code, field, got[field])
+			}
+		}
+	}
+}
+
+// tests bodies over the configured limit are refused with 413
+func TestRequestBodyLimit(t *testing.T) {
+	srv, _ := newTestServer(t)
+
+	limit := config.MaxBodyBytes
+	config.MaxBodyBytes = 128
+	t.Cleanup(func() { config.MaxBodyBytes = limit })
+
+	rr := postJSON(srv.Signup, "/api/v1/signup", map[string]string{"email": "big@example.com", "password": strings.Repeat("x", 200)})
+	if rr.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("Expected 413, got %d: %s", rr.Code, rr.Body.String())
+	}
+}
@@ -0,0 +1,227 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+	"user-auth-go/constants"
+	"user-auth-go/internal/config"
+	"user-auth-go/internal/models"
+	"user-auth-go/internal/webhooks"
+)
+
+// receiver verifying signatures and answering with queued status codes
+type webhookReceiver struct {
+	mu       sync.Mutex
+	secret   string
+	statuses []int
+	received []webhooks.Event
+	errors   []error
+}
+
+func (rcv *webhookReceiver) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	rcv.mu.Lock()
+	defer rcv.mu.Unlock()
+
+	body, _ := io.ReadAll(r.Body)
+
+	err := webhooks.Verify(rcv.secret, r.Header.Get(webhooks.HeaderTimestamp), r.Header.Get(webhooks.HeaderSignature), body, 5*time.Minute, time.Now())
+	if err != nil {
+		rcv.errors = append(rcv.errors, err)
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	var event webhooks.Event
+	json.Unmarshal(body, &event)
+	rcv.received = append(rcv.received, event)
+
+	status := http.StatusOK
+	if len(rcv.statuses) > 0 {
+		status, rcv.statuses = rcv.statuses[0], rcv.statuses[1:]
+	}
+	w.WriteHeader(status)
+}
+
+// tests deliveries are signed so the receiver can verify them
+func TestWebhookDeliverSigned(t *testing.T) {
+	receiver := &webhookReceiver{secret: "whsec_test"}
+	server := httptest.NewServer(receiver)
+	defer server.Close()
+
+	body := []byte(`{"id":"evt_1","type":"user.signed_up","data":{"id":1}}`)
+
+	status, err := webhooks.Deliver(context.Background(), server.Client(), server.URL, "whsec_test", "evt_1", constants.WebhookUserSignedUp, body, time.Now())
+	if err != nil || status != http.StatusOK {
+		t.Fatalf("Expected delivery to succeed, got %d (%v)", status, err)
+	}
+
+	if len(receiver.errors) > 0 {
+		t.Errorf("Expected signature to verify, got %v", receiver.errors)
+	}
+
+	// wrong secret must be rejected by the receiver
+	status, err = webhooks.Deliver(context.Background(), server.Client(), server.URL, "whsec_other", "evt_2", constants.WebhookUserSignedUp, body, time.Now())
+	if err == nil || status != http.StatusUnauthorized {
+		t.Errorf("Expected delivery with wrong secret to fail with 401, got %d (%v)", status, err)
+	}
+}
+
+// tests signature verification rejects tampered and replayed payloads
+func TestWebhookVerify(t *testing.T) {
+	now := time.Now()
+	body := []byte(`{"id":"evt_1"}`)
+	signature := webhooks.Sign("secret", now.Unix(), body)
+	timestamp := strconv.FormatInt(now.Unix(), 10)
+
+	if err := webhooks.Verify("secret", timestamp, signature, body, time.Minute, now); err != nil {
+		t.Errorf("Expected valid signature, got %v", err)
+	}
+
+	if err := webhooks.Verify("secret", timestamp, signature, []byte(`{"id":"evt_2"}`), time.Minute, now); err != webhooks.ErrInvalidSignature {
+		t.Errorf("Expected ErrInvalidSignature for tampered body, got %v", err)
+	}
+
+	if err := webhooks.Verify("secret", timestamp, signature, body, time.Minute, now.Add(10*time.Minute)); err != webhooks.ErrStaleTimestamp {
+		t.Errorf("Expected ErrStaleTimestamp for replayed delivery, got %v", err)
+	}
+
+	if err := webhooks.Verify("secret", timestamp, "v1=deadbeef, "+signature, body, time.Minute, now); err != nil {
+		t.Errorf("Expected one matching signature out of several to pass, got %v", err)
+	}
+}
+
+// tests exponential backoff is capped
+func TestWebhookBackoff(t *testing.T) {
+	cases := map[int]time.Duration{
+		1:  30 * time.Second,
+		2:  time.Minute,
+		3:  2 * time.Minute,
+		10: time.Hour,
+		64: time.Hour,
+	}
+
+	for attempt, expected := range cases {
+		if got := webhooks.Backoff(attempt, 30*time.Second, time.Hour); got != expected {
+			t.Errorf("Attempt %d: expected %s, got %s", attempt, expected, got)
+		}
+	}
+}
+
+// tests worker retries a failed delivery from the queue and logs every attempt
+func TestWebhookWorkerRetry(t *testing.T) {
+	receiver := &webhookReceiver{statuses: []int{http.StatusInternalServerError, http.StatusOK}}
+	server := httptest.NewServer(receiver)
+	defer server.Close()
+
+	_, store := newTestServer(t)
+	ctx := context.Background()
+
+	sub, err := store.Webhooks.CreateSubscription(ctx, server.URL, "whsec_worker", []string{constants.WebhookUserSignedUp}, "test receiver")
+	if err != nil {
+		t.Fatalf("Failed to create subscription: %s", err)
+	}
+	receiver.secret = sub.Secret
+
+	payload := `{"id":"evt_worker","type":"user.signed_up","data":{"id":42,"email":"hook@example.com"}}`
+	if err := store.Webhooks.Enqueue(ctx, "evt_worker", constants.WebhookUserSignedUp, payload); err != nil {
+		t.Fatalf("Failed to enqueue event: %s", err)
+	}
+
+	// the outbox may hand over the same event again, it must not be queued twice
+	store.Webhooks.Enqueue(ctx, "evt_worker", constants.WebhookUserSignedUp, payload)
+
+	// events the subscription didn't ask for are not queued
+	store.Webhooks.Enqueue(ctx, "evt_deleted", constants.WebhookUserDeleted, `{"id":"evt_deleted"}`)
+
+	worker := webhooks.NewWorker(store.Webhooks)
+	worker.Client = server.Client()
+
+	if n, err := worker.ProcessDue(ctx); n != 1 || err != nil {
+		t.Fatalf("Expected 1 delivery attempted, got %d (%v)", n, err)
+	}
+
+	deliveries, _ := store.Webhooks.ListDeliveries(ctx, models.WebhookDeliveryFilter{SubscriptionID: sub.ID})
+	if len(deliveries) != 1 {
+		t.Fatalf("Expected 1 delivery, got %d", len(deliveries))
+	}
+
+	delivery := deliveries[0]
+	if delivery.Status != constants.DeliveryPending || delivery.Attempts != 1 || !delivery.NextAttemptAt.After(time.Now()) {
+		t.Errorf("Expected pending delivery scheduled for retry, got %s attempts=%d next=%s", delivery.Status, delivery.Attempts, delivery.NextAttemptAt)
+	}
+
+	// manual redelivery skips the backoff and starts a fresh retry budget
+	store.Webhooks.Redeliver(ctx, delivery.ID)
+	worker.ProcessDue(ctx)
+
+	updated, _ := store.Webhooks.GetDelivery(ctx, delivery.ID)
+	if updated.Status != constants.DeliverySucceeded || updated.Attempts != 1 {
+		t.Errorf("Expected delivery to succeed on retry, got %s attempts=%d", updated.Status, updated.Attempts)
+	}
+
+	attempts, _ := store.Webhooks.ListAttempts(ctx, delivery.ID)
+	if len(attempts) != 2 || *attempts[0].StatusCode != http.StatusInternalServerError || *attempts[1].StatusCode != http.StatusOK {
+		t.Errorf("Expected attempt log [500, 200], got %+v", attempts)
+	}
+
+	if len(receiver.received) != 2 || receiver.received[0].Type != constants.WebhookUserSignedUp {
+		t.Errorf("Expected receiver to get the signed up event twice, got %+v", receiver.received)
+	}
+}
+
+// tests a delivery that used up its retries gets a fresh budget when an admin redelivers it
+func TestAdminRedeliverResetsAttempts(t *testing.T) {
+	receiver := &webhookReceiver{statuses: []int{http.StatusInternalServerError}}
+	server := httptest.NewServer(receiver)
+	defer server.Close()
+
+	srv, store := newTestServer(t)
+	ctx := context.Background()
+
+	admin := createUser(t, store, "redeliver_admin@example.com", "password123")
+	store.Users.UpdateRole(ctx, admin.ID, constants.RoleAdmin)
+	session, _ := store.Sessions.Create(ctx, admin.ID)
+
+	sub, _ := store.Webhooks.CreateSubscription(ctx, server.URL, "whsec_redeliver", []string{constants.WebhookUserSignedUp}, "")
+	receiver.secret = sub.Secret
+	store.Webhooks.Enqueue(ctx, "evt_redeliver", constants.WebhookUserSignedUp, `{"id":"evt_redeliver"}`)
+
+	worker := webhooks.NewWorker(store.Webhooks)
+	worker.Client = server.Client()
+	worker.MaxAttempts = 1
+	worker.ProcessDue(ctx)
+
+	deliveries, _ := store.Webhooks.ListDeliveries(ctx, models.WebhookDeliveryFilter{SubscriptionID: sub.ID})
+	if len(deliveries) != 1 || deliveries[0].Status != constants.DeliveryFailed {
+		t.Fatalf("Expected one failed delivery, got %+v", deliveries)
+	}
+
+	mux := http.NewServeMux()
+	srv.Routes(mux)
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/admin/webhooks/deliveries/"+strconv.FormatInt(deliveries[0].ID, 10)+"/redeliver", nil)
+	req.AddCookie(&http.Cookie{Name: config.SessionCookie.Name(), Value: session.Token})
+	rr := httptest.NewRecorder()
+	mux.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+
+	updated, _ := store.Webhooks.GetDelivery(ctx, deliveries[0].ID)
+	if updated.Status != constants.DeliveryPending || updated.Attempts != 0 {
+		t.Errorf("Expected a pending delivery with no attempts, got %s attempts=%d", updated.Status, updated.Attempts)
+	}
+
+	events, _ := store.Audit.Query(ctx, models.AuditFilter{EventType: constants.EventWebhookChange})
+	if len(events) != 1 || events[0].Details["action"] != "redeliver" || events[0].ActorUserID == nil || *events[0].ActorUserID != admin.ID {
+		t.Errorf("Expected the redelivery to be audited, got %+v", events)
+	}
+}
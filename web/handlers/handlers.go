@@ -4,6 +4,10 @@ import (
 	"html/template"
 	"net/http"
 	"path/filepath"
+	"user-auth-go/constants"
+	"user-auth-go/internal/config"
+	"user-auth-go/internal/logging"
+	"user-auth-go/internal/middleware"
 	"user-auth-go/internal/models"
 )
 
@@ -12,7 +16,7 @@ var templates map[string]*template.Template
 func Init() {
 	templates = make(map[string]*template.Template)
 
-	pages := []string{"login", "signup", "profile", "profile_edit"}
+	pages := []string{"login", "signup", "profile", "profile_edit", "profile_password"}
 	
 	for _, page := range pages {
 		templates[page] = template.Must(template.ParseFiles(
@@ -22,10 +26,34 @@ func Init() {
 	}
 }
 
+// Server renders the web pages, page handlers are its methods
+type Server struct {
+	*models.Store
+}
+
+func NewServer(store *models.Store) *Server {
+	return &Server{Store: store}
+}
+
+// handle register web page routes
+func (s *Server) Routes(mux *http.ServeMux) {
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+	})
+	mux.HandleFunc("/login", s.LoginPage)
+	mux.HandleFunc("/signup", s.SignupPage)
+	mux.HandleFunc("/profile", s.ProfilePage)
+	mux.HandleFunc("/profile/edit", s.ProfileEditPage)
+	mux.HandleFunc("/profile/password", s.ProfilePasswordPage)
+}
+
 type PageData struct {
-	Title string
-	Error string
-	User  *models.User
+	Title        string
+	Error        string
+	Notice       string
+	User         *models.User
+	PendingEmail string
+	Nonce        string
 }
 
 func setNoCacheHeaders(w http.ResponseWriter) {
@@ -35,52 +63,67 @@ func setNoCacheHeaders(w http.ResponseWriter) {
 }
 
 // handle render templates
-func render(w http.ResponseWriter, page string, data PageData) {
+func render(w http.ResponseWriter, r *http.Request, page string, data PageData) {
 	tmpl, ok := templates[page]
 	if !ok {
 		http.Error(w, "Template not found", http.StatusInternalServerError)
 		return
 	}
 
+	// inline scripts are only allowed by CSP with the request nonce
+	data.Nonce = middleware.Nonce(r)
+
 	err := tmpl.ExecuteTemplate(w, "base", data)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		logging.FromContext(r.Context()).Error("Failed to render page", "page", page, "error", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
 	}
 }
 
 // GET /login
-func LoginPage(w http.ResponseWriter, r *http.Request) {
-	if isAuthenticated(r) {
+func (s *Server) LoginPage(w http.ResponseWriter, r *http.Request) {
+	if s.isAuthenticated(r) {
 		http.Redirect(w, r, "/profile", http.StatusSeeOther)
 		return
 	}
 
 	setNoCacheHeaders(w)
 
-	error := r.URL.Query().Get("error")
-	render(w, "login", PageData{
-		Title: "Login",
-		Error: error,
+	render(w, r, "login", PageData{
+		Title:  "Login",
+		Error:  catalogMessage(constants.ErrorMessages, r.URL.Query().Get("error"), constants.ErrorMessages[constants.ErrorInternal]),
+		Notice: catalogMessage(constants.NoticeMessages, r.URL.Query().Get("notice"), ""),
 	})
 }
 
+// handle text of a `?error=` or `?notice=` code, links never carry free text to show
+func catalogMessage(messages map[string]string, code, fallback string) string {
+	if code == "" {
+		return ""
+	}
+	if message, ok := messages[code]; ok {
+		return message
+	}
+	return fallback
+}
+
 // GET /signup
-func SignupPage(w http.ResponseWriter, r *http.Request) {
-	if isAuthenticated(r) {
+func (s *Server) SignupPage(w http.ResponseWriter, r *http.Request) {
+	if s.isAuthenticated(r) {
 		http.Redirect(w, r, "/profile", http.StatusSeeOther)
 		return
 	}
 
 	setNoCacheHeaders(w)
 
-	render(w, "signup", PageData{
+	render(w, r, "signup", PageData{
 		Title: "Sign Up",
 	})
 }
 
 // GET /profile
-func ProfilePage(w http.ResponseWriter, r *http.Request) {
-	user := getAuthenticatedUser(r)
+func (s *Server) ProfilePage(w http.ResponseWriter, r *http.Request) {
+	user := s.getAuthenticatedUser(r)
 	if user == nil {
 		http.Redirect(w, r, "/login", http.StatusSeeOther)
 		return
@@ -88,15 +131,21 @@ func ProfilePage(w http.ResponseWriter, r *http.Request) {
 
 	setNoCacheHeaders(w)
 
-	render(w, "profile", PageData{
+	data := PageData{
 		Title: "Profile",
 		User:  user,
-	})
+	}
+
+	if change, err := s.EmailChanges.GetPending(r.Context(), user.ID); err == nil && change != nil {
+		data.PendingEmail = change.NewEmail
+	}
+
+	render(w, r, "profile", data)
 }
 
 // GET /profile/edit
-func ProfileEditPage(w http.ResponseWriter, r *http.Request) {
-	user := getAuthenticatedUser(r)
+func (s *Server) ProfileEditPage(w http.ResponseWriter, r *http.Request) {
+	user := s.getAuthenticatedUser(r)
 	if user == nil {
 		http.Redirect(w, r, "/login", http.StatusSeeOther)
 		return
@@ -104,34 +153,55 @@ func ProfileEditPage(w http.ResponseWriter, r *http.Request) {
 
 	setNoCacheHeaders(w)
 
-	render(w, "profile_edit", PageData{
+	render(w, r, "profile_edit", PageData{
 		Title: "Edit Profile",
 		User:  user,
 	})
 }
 
+// GET /profile/password
+func (s *Server) ProfilePasswordPage(w http.ResponseWriter, r *http.Request) {
+	user := s.getAuthenticatedUser(r)
+	if user == nil {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	}
+
+	setNoCacheHeaders(w)
+
+	render(w, r, "profile_password", PageData{
+		Title: "Change Password",
+		User:  user,
+	})
+}
 
 // simple helper to check state user authenticated
-func isAuthenticated(r *http.Request) bool {
-	return getAuthenticatedUser(r) != nil
+func (s *Server) isAuthenticated(r *http.Request) bool {
+	return s.getAuthenticatedUser(r) != nil
 }
 
 // handle to check current authenticated user
-func getAuthenticatedUser(r *http.Request) *models.User {
-	cookie, err := r.Cookie("session_token")
+func (s *Server) getAuthenticatedUser(r *http.Request) *models.User {
+	token, err := config.SessionCookie.Read(r)
 	if err != nil {
 		return nil
 	}
 
-	session, err := models.GetSessionByToken(cookie.Value)
-	if err != nil || session == nil {
+	session, err := s.Sessions.GetByToken(r.Context(), token)
+	if err != nil {
+		logging.FromContext(r.Context()).Error("Failed to validate session", "error", err)
+		return nil
+	}
+	if session == nil {
 		return nil
 	}
 
-	user, err := models.GetUserByID(session.UserID)
-	if err != nil {
+	user, err := s.Users.GetByID(r.Context(), session.UserID)
+	if err != nil || user == nil || user.IsDisabled() {
 		return nil
 	}
 
+	logging.SetUserID(r.Context(), user.ID)
+
 	return user
 }